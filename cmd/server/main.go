@@ -6,15 +6,56 @@ import (
 	"path/filepath"
 	"time"
 
+	"docker-dashboard/internal/alerts"
 	"docker-dashboard/internal/api"
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/history"
+	"docker-dashboard/internal/storage"
 
 	"github.com/labstack/echo/v4"
 )
 
 func main() {
 	staticDir := filepath.Join("web", "public")
+
+	// Согласуем версию Docker API при старте; при ошибке повторим при первом запросе
+	if _, err := containers.NegotiateAPIVersion(); err != nil {
+		log.Printf("Docker API version negotiation: %v", err)
+	}
+
+	// События контейнеров сохраняются на диск, если задан DATA_DIR
+	if store := storage.Default(); store != nil {
+		store.RecordContainerEvents()
+	}
+
+	authenticator, err := auth.FromEnv()
+	if err != nil {
+		log.Fatalf("Auth configuration error: %v", err)
+	}
+
+	ipExtractor, err := api.IPExtractorFromEnv()
+	if err != nil {
+		log.Fatalf("Proxy configuration error: %v", err)
+	}
+
+	// Журнал аудита открывается при старте, чтобы ошибки конфигурации были видны сразу
+	audit.Default()
+
+	// История метрик пишется независимо от подключенных клиентов
+	history.StartRecorder()
+
+	// Правила алертов проверяются в фоне, без открытых вкладок браузера
+	alerts.Start()
+
 	for {
 		e := echo.New()
+		// IP клиента для журнала аудита: без TRUSTED_PROXIES заголовкам X-Forwarded-For не доверяем
+		e.IPExtractor = ipExtractor
+		// Аутентификация применяется ко всем маршрутам: REST, WebSocket и статике
+		e.Use(authenticator.Middleware())
+		authenticator.RegisterRoutes(e)
 		api.RegisterRoutes(e)
 
 		// Serve static files from "web/public"; SPA fallback: unknown paths serve index.html
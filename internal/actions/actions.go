@@ -0,0 +1,234 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-dashboard/internal/containers"
+)
+
+// Действия над контейнером
+const (
+	Start   = "start"
+	Stop    = "stop"
+	Restart = "restart"
+	Kill    = "kill"
+	Pause   = "pause"
+	Unpause = "unpause"
+	Remove  = "remove"
+)
+
+// All — все поддерживаемые действия
+var All = []string{Start, Stop, Restart, Kill, Pause, Unpause, Remove}
+
+// Максимальное время ожидания остановки контейнера, которое можно запросить
+const maxStopTimeout = 10 * time.Minute
+
+var signalPattern = regexp.MustCompile(`^(SIG)?[A-Z][A-Z0-9+-]*$|^[0-9]{1,2}$`)
+
+// Options — параметры действия
+type Options struct {
+	// Timeout — сколько секунд ждать остановки перед SIGKILL (stop, restart).
+	// nil — значение Docker по умолчанию (StopTimeout контейнера, обычно 10 секунд)
+	Timeout *int
+	// Signal — сигнал для kill (по умолчанию SIGKILL)
+	Signal string
+	// RemoveVolumes — удалить анонимные тома вместе с контейнером (remove)
+	RemoveVolumes bool
+	// Force — удалить запущенный контейнер (remove)
+	Force bool
+}
+
+// Validate проверяет параметры для действия
+func (o Options) Validate(action string) error {
+	if o.Timeout != nil {
+		if action != Stop && action != Restart {
+			return fmt.Errorf("timeout is only supported for stop and restart")
+		}
+		if *o.Timeout < 0 || time.Duration(*o.Timeout)*time.Second > maxStopTimeout {
+			return fmt.Errorf("timeout must be between 0 and %d seconds", int(maxStopTimeout/time.Second))
+		}
+	}
+	if o.Signal != "" {
+		if action != Kill {
+			return fmt.Errorf("signal is only supported for kill")
+		}
+		if !signalPattern.MatchString(o.Signal) {
+			return fmt.Errorf("invalid signal %q", o.Signal)
+		}
+	}
+	if (o.RemoveVolumes || o.Force) && action != Remove {
+		return fmt.Errorf("volumes and force are only supported for remove")
+	}
+	return nil
+}
+
+// Result — результат успешного действия
+type Result struct {
+	Action    string `json:"action"`
+	Container string `json:"container"`
+	// Unchanged — контейнер уже был в нужном состоянии (Docker вернул 304)
+	Unchanged bool   `json:"unchanged,omitempty"`
+	Message   string `json:"message"`
+}
+
+// Error — ошибка выполнения действия с деталями ответа Docker
+type Error struct {
+	Action    string `json:"action"`
+	Container string `json:"container"`
+	// StatusCode — HTTP статус ответа Docker API; 0, если Docker недоступен
+	StatusCode int    `json:"status_code"`
+	Message    string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("failed to %s container %s: %s", e.Action, e.Container, e.Message)
+	}
+	return fmt.Sprintf("failed to %s container %s: %s (status %d)", e.Action, e.Container, e.Message, e.StatusCode)
+}
+
+// IsSupported сообщает, поддерживается ли действие
+func IsSupported(action string) bool {
+	for _, a := range All {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// Deadline возвращает разумный таймаут запроса для действия с учетом таймаута остановки
+func Deadline(action string, opts Options) time.Duration {
+	timeout := 30 * time.Second
+	if opts.Timeout != nil && (action == Stop || action == Restart) {
+		timeout += time.Duration(*opts.Timeout) * time.Second
+	}
+	return timeout
+}
+
+// request возвращает метод и путь запроса Docker API для действия
+func request(action, id string, opts Options) (string, string) {
+	query := url.Values{}
+	method, path := http.MethodPost, "/containers/"+id+"/"+action
+	switch action {
+	case Stop, Restart:
+		if opts.Timeout != nil {
+			query.Set("t", strconv.Itoa(*opts.Timeout))
+		}
+	case Kill:
+		if opts.Signal != "" {
+			query.Set("signal", opts.Signal)
+		}
+	case Remove:
+		method, path = http.MethodDelete, "/containers/"+id
+		if opts.RemoveVolumes {
+			query.Set("v", "true")
+		}
+		if opts.Force {
+			query.Set("force", "true")
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return method, path
+}
+
+// Do выполняет действие над контейнером и сразу обновляет его в модели контейнеров
+func Do(ctx context.Context, action string, c *containers.Container, opts Options) (*Result, error) {
+	if !IsSupported(action) {
+		return nil, &Error{Action: action, Container: c.Name, StatusCode: http.StatusBadRequest, Message: "unsupported action"}
+	}
+	if err := opts.Validate(action); err != nil {
+		return nil, &Error{Action: action, Container: c.Name, StatusCode: http.StatusBadRequest, Message: err.Error()}
+	}
+	id := c.FullID
+	if id == "" {
+		id = c.ID
+	}
+
+	method, path := request(action, id, opts)
+	actionURL, err := containers.DockerURL(path)
+	if err != nil {
+		return nil, &Error{Action: action, Container: c.Name, Message: err.Error()}
+	}
+	log.Printf("[docker-dashboard] %s %s", method, actionURL)
+
+	req, err := http.NewRequestWithContext(ctx, method, actionURL, nil)
+	if err != nil {
+		return nil, &Error{Action: action, Container: c.Name, Message: err.Error()}
+	}
+	resp, err := containers.DockerStreamClient().Do(req)
+	if err != nil {
+		return nil, &Error{Action: action, Container: c.Name, Message: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	// Модель обновляем в любом случае: даже неудачное действие могло изменить состояние
+	defer containers.Refresh(id)
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusOK:
+		return &Result{Action: action, Container: c.Name, Message: successMessage(action)}, nil
+	case http.StatusNotModified:
+		return &Result{Action: action, Container: c.Name, Unchanged: true, Message: unchangedMessage(action)}, nil
+	}
+	return nil, &Error{Action: action, Container: c.Name, StatusCode: resp.StatusCode, Message: dockerErrorMessage(resp)}
+}
+
+// dockerErrorMessage извлекает текст ошибки из ответа Docker ({"message": "..."})
+func dockerErrorMessage(resp *http.Response) string {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil || len(body) == 0 {
+		return http.StatusText(resp.StatusCode)
+	}
+	var dockerErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &dockerErr) == nil && dockerErr.Message != "" {
+		return dockerErr.Message
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func pastTense(action string) string {
+	switch action {
+	case Start:
+		return "started"
+	case Stop:
+		return "stopped"
+	case Restart:
+		return "restarted"
+	case Kill:
+		return "killed"
+	case Pause:
+		return "paused"
+	case Unpause:
+		return "unpaused"
+	case Remove:
+		return "removed"
+	}
+	return action
+}
+
+func successMessage(action string) string {
+	return "Container " + pastTense(action) + " successfully"
+}
+
+// unchangedMessage — сообщение, когда контейнер уже в нужном состоянии (Docker отвечает 304 на start и stop)
+func unchangedMessage(action string) string {
+	if action == Start {
+		return "Container is already running"
+	}
+	return "Container is already " + pastTense(action)
+}
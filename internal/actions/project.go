@@ -0,0 +1,183 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"docker-dashboard/internal/containers"
+)
+
+const (
+	composeServiceLabel   = "com.docker.compose.service"
+	composeDependsOnLabel = "com.docker.compose.depends_on"
+)
+
+// ProjectActions — действия, которые можно выполнить над всем compose проектом
+var ProjectActions = []string{Start, Stop, Restart}
+
+// Состояния шага проектного действия
+const (
+	StepPending = "pending"
+	StepRunning = "running"
+	StepSuccess = "success"
+	StepError   = "error"
+	StepSkipped = "skipped"
+)
+
+// Step — действие над одним контейнером в составе проектного действия
+type Step struct {
+	Container string `json:"container"`
+	Service   string `json:"service,omitempty"`
+	Status    string `json:"status"`
+	Message   string `json:"message,omitempty"`
+	Error     *Error `json:"error,omitempty"`
+}
+
+// IsProjectAction сообщает, поддерживается ли действие для проекта
+func IsProjectAction(action string) bool {
+	for _, a := range ProjectActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// serviceDependencies разбирает метку com.docker.compose.depends_on:
+// "db:service_healthy:false,cache:service_started:true" -> [db cache]
+func serviceDependencies(c containers.Container) []string {
+	value := c.AllLabels[composeDependsOnLabel]
+	if value == "" {
+		return nil
+	}
+	var deps []string
+	for _, item := range strings.Split(value, ",") {
+		service, _, _ := strings.Cut(strings.TrimSpace(item), ":")
+		if service != "" {
+			deps = append(deps, service)
+		}
+	}
+	return deps
+}
+
+// ProjectOrder упорядочивает контейнеры проекта по зависимостям compose:
+// для start и restart зависимости идут раньше зависящих от них сервисов, для stop — наоборот.
+// Реплики одного сервиса идут подряд, внутри уровня порядок по имени
+func ProjectOrder(list []containers.Container, action string) ([]containers.Container, error) {
+	byService := make(map[string][]containers.Container)
+	deps := make(map[string]map[string]bool)
+	for _, c := range list {
+		service := c.AllLabels[composeServiceLabel]
+		if service == "" {
+			service = c.Name
+		}
+		byService[service] = append(byService[service], c)
+		if deps[service] == nil {
+			deps[service] = make(map[string]bool)
+		}
+		for _, dep := range serviceDependencies(c) {
+			deps[service][dep] = true
+		}
+	}
+
+	// Топологическая сортировка по уровням (алгоритм Кана).
+	// Зависимости от сервисов вне списка (например, удаленных) игнорируются
+	remaining := make(map[string]int, len(byService))
+	for service, ds := range deps {
+		for dep := range ds {
+			if _, ok := byService[dep]; ok && dep != service {
+				remaining[service]++
+			}
+		}
+	}
+	var order []string
+	done := make(map[string]bool)
+	for len(order) < len(byService) {
+		var level []string
+		for service := range byService {
+			if !done[service] && remaining[service] == 0 {
+				level = append(level, service)
+			}
+		}
+		if len(level) == 0 {
+			var cycle []string
+			for service := range byService {
+				if !done[service] {
+					cycle = append(cycle, service)
+				}
+			}
+			sort.Strings(cycle)
+			return nil, fmt.Errorf("dependency cycle between services: %s", strings.Join(cycle, ", "))
+		}
+		sort.Strings(level)
+		for _, service := range level {
+			done[service] = true
+			order = append(order, service)
+		}
+		for service, ds := range deps {
+			for _, finished := range level {
+				if ds[finished] && service != finished {
+					remaining[service]--
+				}
+			}
+		}
+	}
+
+	if action == Stop {
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+
+	result := make([]containers.Container, 0, len(list))
+	for _, service := range order {
+		replicas := byService[service]
+		sort.Slice(replicas, func(i, j int) bool { return replicas[i].Name < replicas[j].Name })
+		result = append(result, replicas...)
+	}
+	return result, nil
+}
+
+// NewSteps создает шаги проектного действия в порядке выполнения
+func NewSteps(ordered []containers.Container) []Step {
+	steps := make([]Step, len(ordered))
+	for i, c := range ordered {
+		steps[i] = Step{Container: c.Name, Service: c.AllLabels[composeServiceLabel], Status: StepPending}
+	}
+	return steps
+}
+
+// RunProject выполняет действие над контейнерами по очереди.
+// При continueOnError=false после первой ошибки оставшиеся шаги пропускаются.
+// onStep вызывается при каждом изменении шага; возвращается число неудачных шагов
+func RunProject(ctx context.Context, action string, ordered []containers.Container, opts Options, continueOnError bool,
+	onStep func(i int, step Step, err error)) int {
+	steps := NewSteps(ordered)
+	failed := 0
+	for i := range ordered {
+		if ctx.Err() != nil || (failed > 0 && !continueOnError) {
+			steps[i].Status = StepSkipped
+			onStep(i, steps[i], nil)
+			continue
+		}
+		steps[i].Status = StepRunning
+		onStep(i, steps[i], nil)
+
+		result, err := Do(ctx, action, &ordered[i], opts)
+		if err != nil {
+			failed++
+			steps[i].Status = StepError
+			steps[i].Message = err.Error()
+			if actionErr, ok := err.(*Error); ok {
+				steps[i].Error = actionErr
+			}
+		} else {
+			steps[i].Status = StepSuccess
+			steps[i].Message = result.Message
+		}
+		onStep(i, steps[i], err)
+	}
+	return failed
+}
@@ -0,0 +1,337 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Состояния алерта
+const (
+	StateFiring   = "firing"
+	StateResolved = "resolved"
+)
+
+// Типы алертов, кроме типов правил из rules.go
+const (
+	TypeLogPattern = "log_pattern"
+	TypeTest       = "test" // тестовое уведомление
+)
+
+// Важность алерта
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+const (
+	// Размер очереди уведомлений; при переполнении уведомления отбрасываются
+	notifyQueueSize = 256
+	// Время на отправку одного уведомления одним каналом
+	notifyTimeout = 30 * time.Second
+	// По умолчанию не больше стольких уведомлений в минуту (ALERTS_RATE_LIMIT)
+	defaultRateLimit = 20
+	// Сколько разрешенных алертов хранится для GET /api/alerts
+	maxResolved = 500
+	// Сколько хранятся разрешенные алерты
+	resolvedRetention = 24 * time.Hour
+)
+
+// Alert — алерт по правилу для контейнера (или для хоста, если контейнер не указан)
+type Alert struct {
+	ID          string            `json:"id"` // правило и контейнер: одинаковый для повторных срабатываний
+	Rule        string            `json:"rule"`
+	Type        string            `json:"type"` // log_pattern, ...
+	Severity    string            `json:"severity"`
+	State       string            `json:"state"`
+	Container   string            `json:"container,omitempty"`
+	ContainerID string            `json:"container_id,omitempty"` // полный ID
+	Project     string            `json:"project,omitempty"`
+	Summary     string            `json:"summary"`
+	Details     map[string]string `json:"details,omitempty"`
+	StartsAt    time.Time         `json:"starts_at"`
+	EndsAt      *time.Time        `json:"ends_at,omitempty"`
+}
+
+// Notifier — канал уведомлений об изменении состояния алертов.
+// Notify возвращает ошибку, если уведомление не доставлено; временные ошибки повторяются
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Manager хранит активные алерты и рассылает уведомления о срабатывании и разрешении.
+// Уведомления отправляются в фоне и ограничиваются по частоте, чтобы поток
+// срабатываний не превратился в поток сообщений
+type Manager struct {
+	mu        sync.Mutex
+	active    map[string]*Alert
+	resolved  []Alert // последние разрешенные алерты, новые в конце
+	notifiers []*notifierWorker
+
+	queue     chan Alert
+	rateLimit int // уведомлений в минуту, 0 — без ограничения
+}
+
+var (
+	defaultManager     *Manager
+	defaultManagerOnce sync.Once
+	startOnce          sync.Once
+)
+
+// Default возвращает общий менеджер алертов
+func Default() *Manager {
+	defaultManagerOnce.Do(func() {
+		defaultManager = NewManager(envInt("ALERTS_RATE_LIMIT", defaultRateLimit))
+		for _, n := range DefaultConfig().notifiers {
+			defaultManager.AddNotifier(n.notifier, n.filter)
+		}
+	})
+	return defaultManager
+}
+
+// Start запускает фоновую проверку правил из ALERTS_CONFIG_FILE (один раз).
+// Проверка идет независимо от подключенных клиентов
+func Start() {
+	startOnce.Do(func() {
+		cfg := DefaultConfig()
+		manager := Default()
+		if len(cfg.Rules) > 0 {
+			go newRuleEngine(cfg.Rules, manager).run()
+		}
+		if len(cfg.LogRules) > 0 {
+			go newLogWatcher(cfg.LogRules, manager).run()
+		}
+	})
+}
+
+func envInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("[docker-dashboard] Invalid %s=%q, using %d", name, value, def)
+		return def
+	}
+	return n
+}
+
+// NewManager создает менеджер и запускает отправку уведомлений
+func NewManager(rateLimit int) *Manager {
+	m := &Manager{
+		active:    make(map[string]*Alert),
+		queue:     make(chan Alert, notifyQueueSize),
+		rateLimit: rateLimit,
+	}
+	go m.dispatch()
+	return m
+}
+
+// AddNotifier добавляет канал уведомлений. Каждый канал отправляет уведомления в своей горутине,
+// поэтому повторы после ошибок одного канала не задерживают остальные
+func (m *Manager) AddNotifier(n Notifier, filter Filter) {
+	w := &notifierWorker{notifier: n, filter: filter, queue: make(chan Alert, notifierQueueSize)}
+	go w.run()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notifiers = append(m.notifiers, w)
+}
+
+// TestResult — результат тестовой отправки в канал
+type TestResult struct {
+	Notifier string `json:"notifier"`
+	Status   string `json:"status"` // sent или error
+	Error    string `json:"error,omitempty"`
+}
+
+// ErrUnknownNotifier — канал с таким именем не настроен
+var ErrUnknownNotifier = errors.New("unknown notifier")
+
+// Test отправляет тестовое уведомление в канал name (или во все каналы, если name пустой)
+// одной попыткой, без фильтров и ограничения частоты
+func (m *Manager) Test(ctx context.Context, name, user string) ([]TestResult, error) {
+	m.mu.Lock()
+	var workers []*notifierWorker
+	for _, w := range m.notifiers {
+		if name == "" || w.notifier.Name() == name {
+			workers = append(workers, w)
+		}
+	}
+	m.mu.Unlock()
+	if name != "" && len(workers) == 0 {
+		return nil, ErrUnknownNotifier
+	}
+	alert := Alert{
+		ID:       "test",
+		Rule:     "test",
+		Type:     TypeTest,
+		Severity: SeverityInfo,
+		State:    StateFiring,
+		Summary:  "Test notification from docker-dashboard",
+		StartsAt: time.Now(),
+	}
+	if user != "" {
+		alert.Details = map[string]string{"requested_by": user}
+	}
+	results := make([]TestResult, len(workers))
+	var wg sync.WaitGroup
+	for i, w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sendCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+			defer cancel()
+			results[i] = TestResult{Notifier: w.notifier.Name(), Status: "sent"}
+			if err := w.notifier.Notify(sendCtx, alert); err != nil {
+				results[i].Status, results[i].Error = "error", err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// Fire отмечает алерт сработавшим. Для нового алерта отправляется уведомление и возвращается true;
+// у уже активного обновляются описание и детали без повторного уведомления
+func (m *Manager) Fire(alert Alert) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if current, ok := m.active[alert.ID]; ok {
+		current.Summary = alert.Summary
+		current.Details = alert.Details
+		return false
+	}
+	alert.State = StateFiring
+	alert.EndsAt = nil
+	if alert.StartsAt.IsZero() {
+		alert.StartsAt = time.Now()
+	}
+	m.active[alert.ID] = &alert
+	m.enqueue(alert)
+	return true
+}
+
+// Resolve отмечает активный алерт разрешенным и отправляет уведомление
+func (m *Manager) Resolve(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	alert, ok := m.active[id]
+	if !ok {
+		return
+	}
+	delete(m.active, id)
+	now := time.Now()
+	alert.State = StateResolved
+	alert.EndsAt = &now
+	m.resolved = append(m.resolved, *alert)
+	m.pruneResolved(now)
+	m.enqueue(*alert)
+}
+
+// pruneResolved удаляет старые разрешенные алерты (вызывается под m.mu)
+func (m *Manager) pruneResolved(now time.Time) {
+	i := 0
+	for i < len(m.resolved) && (len(m.resolved)-i > maxResolved || now.Sub(*m.resolved[i].EndsAt) > resolvedRetention) {
+		i++
+	}
+	if i > 0 {
+		m.resolved = append(m.resolved[:0], m.resolved[i:]...)
+	}
+}
+
+// Firing сообщает, активен ли алерт
+func (m *Manager) Firing(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.active[id]
+	return ok
+}
+
+// Active возвращает активные алерты, начиная с самых новых
+func (m *Manager) Active() []Alert {
+	m.mu.Lock()
+	result := make([]Alert, 0, len(m.active))
+	for _, alert := range m.active {
+		result = append(result, *alert)
+	}
+	m.mu.Unlock()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].StartsAt.After(result[j].StartsAt)
+	})
+	return result
+}
+
+// Resolved возвращает алерты, разрешенные за последние сутки, начиная с самых новых
+func (m *Manager) Resolved() []Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneResolved(time.Now())
+	result := make([]Alert, len(m.resolved))
+	for i, alert := range m.resolved {
+		result[len(m.resolved)-1-i] = alert
+	}
+	return result
+}
+
+// enqueue ставит уведомление в очередь, не блокируя вызывающего (вызывается под m.mu)
+func (m *Manager) enqueue(alert Alert) {
+	if alert.Details != nil {
+		details := make(map[string]string, len(alert.Details))
+		for key, value := range alert.Details {
+			details[key] = value
+		}
+		alert.Details = details
+	}
+	select {
+	case m.queue <- alert:
+	default:
+		log.Printf("[docker-dashboard] Alerts: notification queue is full, dropping %s %s", alert.State, alert.ID)
+	}
+}
+
+// dispatch отправляет уведомления из очереди во все каналы. В лог процесса пишутся все
+// изменения состояния; в каналы сверх rateLimit уведомлений в минуту отправка пропускается
+func (m *Manager) dispatch() {
+	var (
+		windowStart time.Time
+		sent        int
+		suppressed  int
+	)
+	for alert := range m.queue {
+		log.Printf("[docker-dashboard] Alert %s: [%s] %s: %s", alert.State, alert.Severity, alert.ID, alert.Summary)
+		now := time.Now()
+		if now.Sub(windowStart) >= time.Minute {
+			if suppressed > 0 {
+				log.Printf("[docker-dashboard] Alerts: %d notifications suppressed by rate limit (%d per minute)", suppressed, m.rateLimit)
+			}
+			windowStart, sent, suppressed = now, 0, 0
+		}
+		if m.rateLimit > 0 && sent >= m.rateLimit {
+			suppressed++
+			continue
+		}
+		sent++
+
+		m.mu.Lock()
+		workers := append([]*notifierWorker(nil), m.notifiers...)
+		m.mu.Unlock()
+		for _, w := range workers {
+			if !w.filter.Accept(alert) {
+				continue
+			}
+			select {
+			case w.queue <- alert:
+			default:
+				log.Printf("[docker-dashboard] Alerts: %s queue is full, dropping %s %s", w.notifier.Name(), alert.State, alert.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/logstream"
+)
+
+// Config — правила алертов из ALERTS_CONFIG_FILE (JSON)
+type Config struct {
+	Rules     []*Rule          `json:"rules,omitempty"`
+	LogRules  []*LogRule       `json:"log_rules,omitempty"`
+	Notifiers []NotifierConfig `json:"notifiers,omitempty"`
+
+	notifiers []configuredNotifier
+}
+
+// configuredNotifier — канал, созданный по NotifierConfig
+type configuredNotifier struct {
+	notifier Notifier
+	filter   Filter
+}
+
+// Duration — длительность в JSON: строка "5m" или число секунд
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return err
+		}
+		d.Duration = parsed
+	case float64:
+		d.Duration = time.Duration(v * float64(time.Second))
+	default:
+		return fmt.Errorf("invalid duration %s", data)
+	}
+	if d.Duration < 0 {
+		return fmt.Errorf("negative duration %s", data)
+	}
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Selector выбирает контейнеры, к которым относится правило. Все заданные условия
+// должны выполняться; пустой Selector выбирает все контейнеры
+type Selector struct {
+	Containers []string          `json:"containers,omitempty"` // имена, допускаются шаблоны "api-*"
+	Projects   []string          `json:"projects,omitempty"`   // com.docker.compose.project
+	Labels     map[string]string `json:"labels,omitempty"`     // все метки должны совпасть
+}
+
+// Match проверяет, что контейнер подходит под Selector
+func (s Selector) Match(c *containers.Container) bool {
+	if len(s.Projects) > 0 && !slices.Contains(s.Projects, c.ComposeProject) {
+		return false
+	}
+	if len(s.Containers) > 0 {
+		matched := false
+		for _, pattern := range s.Containers {
+			if ok, _ := path.Match(pattern, c.Name); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for key, value := range s.Labels {
+		if c.AllLabels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) validate() error {
+	for _, pattern := range s.Containers {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid container pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// Значения по умолчанию для правил по логам
+const (
+	defaultLogWindow   = time.Minute
+	defaultLogCooldown = 5 * time.Minute
+)
+
+// LogRule — правило по логам: срабатывает, когда за окно Window в логах контейнера
+// набирается не меньше Threshold строк, совпадающих с регулярным выражением Pattern
+type LogRule struct {
+	Name string `json:"name"`
+	Selector
+	Pattern    string   `json:"pattern"` // регулярное выражение (синтаксис RE2)
+	IgnoreCase bool     `json:"ignore_case,omitempty"`
+	Stream     string   `json:"stream,omitempty"`    // stdout, stderr или пусто — оба потока
+	Threshold  int      `json:"threshold,omitempty"` // по умолчанию 1
+	Window     Duration `json:"window,omitempty"`    // по умолчанию 1m
+	// Cooldown — после срабатывания алерт по тому же контейнеру не отправляется повторно
+	// раньше этого времени, даже если успел разрешиться (по умолчанию 5m)
+	Cooldown Duration `json:"cooldown,omitempty"`
+	Severity string   `json:"severity,omitempty"` // info, warning (по умолчанию), critical
+
+	matcher *logstream.Matcher
+	stream  int // logstream.Stdout, logstream.Stderr или 0 — оба потока
+}
+
+// Match проверяет строку лога по потоку и шаблону правила
+func (r *LogRule) Match(line logstream.Line) bool {
+	if r.stream != 0 && r.stream != line.Stream {
+		return false
+	}
+	return r.matcher.Match(line.Text)
+}
+
+func (r *LogRule) prepare() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Pattern == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	matcher, err := logstream.NewMatcher(r.Pattern, true, r.IgnoreCase, false)
+	if err != nil {
+		return err
+	}
+	r.matcher = matcher
+	if err := r.Selector.validate(); err != nil {
+		return err
+	}
+	switch r.Stream {
+	case "":
+	case "stdout":
+		r.stream = logstream.Stdout
+	case "stderr":
+		r.stream = logstream.Stderr
+	default:
+		return fmt.Errorf("unknown stream %q, expected stdout or stderr", r.Stream)
+	}
+	if r.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative")
+	}
+	if r.Threshold == 0 {
+		r.Threshold = 1
+	}
+	if r.Window.Duration == 0 {
+		r.Window.Duration = defaultLogWindow
+	}
+	if r.Window.Duration < time.Second {
+		return fmt.Errorf("window must be at least 1s")
+	}
+	if r.Cooldown.Duration == 0 {
+		r.Cooldown.Duration = defaultLogCooldown
+	}
+	return validateSeverity(&r.Severity)
+}
+
+func validateSeverity(severity *string) error {
+	switch *severity {
+	case "":
+		*severity = SeverityWarning
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+	default:
+		return fmt.Errorf("unknown severity %q, expected info, warning or critical", *severity)
+	}
+	return nil
+}
+
+// LoadConfig читает и проверяет правила из JSON файла
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alerts config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse alerts config: %w", err)
+	}
+	// Имена уникальны среди всех правил: из них составляются ID алертов
+	names := make(map[string]bool)
+	for i, rule := range cfg.Rules {
+		if err := rule.prepare(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("rule %d: duplicate name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	for i, rule := range cfg.LogRules {
+		if err := rule.prepare(); err != nil {
+			return nil, fmt.Errorf("log rule %d: %w", i, err)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("log rule %d: duplicate name %q", i, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	notifierNames := make(map[string]bool)
+	for i, nc := range cfg.Notifiers {
+		n, filter, err := NewNotifier(nc)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %d: %w", i, err)
+		}
+		if notifierNames[nc.Name] {
+			return nil, fmt.Errorf("notifier %d: duplicate name %q", i, nc.Name)
+		}
+		notifierNames[nc.Name] = true
+		cfg.notifiers = append(cfg.notifiers, configuredNotifier{notifier: n, filter: filter})
+	}
+	return &cfg, nil
+}
+
+var (
+	defaultConfig     *Config
+	defaultConfigOnce sync.Once
+)
+
+// DefaultConfig возвращает правила из ALERTS_CONFIG_FILE; без файла или при ошибке в нем — пустые
+func DefaultConfig() *Config {
+	defaultConfigOnce.Do(func() {
+		defaultConfig = &Config{}
+		path := os.Getenv("ALERTS_CONFIG_FILE")
+		if path == "" {
+			return
+		}
+		cfg, err := LoadConfig(path)
+		if err != nil {
+			log.Printf("[docker-dashboard] Alerts: %v; alert rules are disabled", err)
+			return
+		}
+		log.Printf("[docker-dashboard] Alerts: loaded %d rules, %d log rules and %d notifiers from %s",
+			len(cfg.Rules), len(cfg.LogRules), len(cfg.Notifiers), path)
+		defaultConfig = cfg
+	})
+	return defaultConfig
+}
+
+// ruleAlertID — ID алерта правила для контейнера
+func ruleAlertID(rule string, c *containers.Container) string {
+	if c == nil {
+		return rule
+	}
+	return rule + "/" + c.Name
+}
@@ -0,0 +1,192 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Режимы TLS для SMTP
+const (
+	smtpStartTLS = "starttls" // STARTTLS обязателен
+	smtpTLS      = "tls"      // TLS с самого подключения (обычно порт 465)
+	smtpNoTLS    = "none"     // без шифрования, только для локальных релеев
+)
+
+// emailNotifier отправляет уведомления письмом через SMTP
+type emailNotifier struct {
+	name     string
+	host     string
+	addr     string
+	tlsMode  string
+	username string
+	password string
+	from     *mail.Address
+	to       []*mail.Address
+	msg      message
+}
+
+func newEmailNotifier(cfg NotifierConfig, msg message) (*emailNotifier, error) {
+	if cfg.SMTPHost == "" {
+		return nil, fmt.Errorf("smtp_host is required")
+	}
+	n := &emailNotifier{
+		name:     cfg.Name,
+		host:     cfg.SMTPHost,
+		tlsMode:  cfg.SMTPTLS,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		msg:      msg,
+	}
+	port := cfg.SMTPPort
+	switch n.tlsMode {
+	case "":
+		n.tlsMode = smtpStartTLS
+	case smtpStartTLS, smtpNoTLS:
+	case smtpTLS:
+		if port == 0 {
+			port = 465
+		}
+	default:
+		return nil, fmt.Errorf("unknown smtp_tls %q, expected starttls, tls or none", cfg.SMTPTLS)
+	}
+	if port == 0 {
+		port = 587
+	}
+	n.addr = net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(port))
+
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address %q", cfg.From)
+	}
+	n.from = from
+	if len(cfg.To) == 0 {
+		return nil, fmt.Errorf("to is required")
+	}
+	for _, value := range cfg.To {
+		to, err := mail.ParseAddress(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to address %q", value)
+		}
+		n.to = append(n.to, to)
+	}
+	return n, nil
+}
+
+func (n *emailNotifier) Name() string { return n.name }
+
+func (n *emailNotifier) Notify(ctx context.Context, alert Alert) error {
+	title, text, err := n.msg.render(alert)
+	if err != nil {
+		return err
+	}
+	data, err := n.compose(title, text, time.Now())
+	if err != nil {
+		return permanent(err)
+	}
+	err = n.send(ctx, data)
+	// Ответы 5xx (неверный адрес, отказ в авторизации) не исправятся при повторе
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) && smtpErr.Code >= 500 {
+		return permanent(err)
+	}
+	return err
+}
+
+// compose формирует письмо: тема — заголовок уведомления, текст в quoted-printable
+func (n *emailNotifier) compose(title, text string, now time.Time) ([]byte, error) {
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	to := make([]string, len(n.to))
+	for i, addr := range n.to {
+		to[i] = addr.String()
+	}
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(title)
+	domain := n.from.Address[strings.LastIndexByte(n.from.Address, '@')+1:]
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", n.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&buf)
+	qp.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n")))
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	buf.WriteString("\r\n")
+	return buf.Bytes(), nil
+}
+
+// send передает письмо SMTP серверу. Время всего обмена ограничено ctx
+func (n *emailNotifier) send(ctx context.Context, data []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	tlsConfig := &tls.Config{ServerName: n.host}
+	if n.tlsMode == smtpTLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if n.tlsMode == smtpStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return permanent(errors.New("SMTP server does not support STARTTLS; set smtp_tls to none to send without encryption"))
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if n.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, n.host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.from.Address); err != nil {
+		return err
+	}
+	for _, to := range n.to {
+		if err := client.Rcpt(to.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
@@ -0,0 +1,301 @@
+package alerts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpStub — минимальный SMTP сервер: принимает письма и запоминает конверт и текст
+type smtpStub struct {
+	ln         net.Listener
+	startTLS   bool // объявлять STARTTLS (сам TLS не поддерживается)
+	rejectRcpt bool // отвечать 550 на RCPT TO
+
+	mu   sync.Mutex
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpStub{ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpStub) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	reply("220 stub ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		s.mu.Lock()
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250-stub")
+			if s.startTLS {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			s.auth = strings.TrimPrefix(line, "AUTH PLAIN ")
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			s.from = strings.TrimPrefix(line, "MAIL FROM:")
+			reply("250 OK")
+		case "RCPT":
+			if s.rejectRcpt {
+				reply("550 5.1.1 No such user")
+				break
+			}
+			s.to = append(s.to, strings.TrimPrefix(line, "RCPT TO:"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					s.mu.Unlock()
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.data = data.String()
+			reply("250 OK queued")
+		case "QUIT":
+			reply("221 Bye")
+			s.mu.Unlock()
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+		s.mu.Unlock()
+	}
+}
+
+func newTestEmailNotifier(t *testing.T, server *smtpStub, cfg NotifierConfig) Notifier {
+	t.Helper()
+	cfg.Type = NotifierEmail
+	cfg.SMTPHost = "127.0.0.1"
+	cfg.SMTPPort = server.port()
+	if cfg.SMTPTLS == "" {
+		cfg.SMTPTLS = smtpNoTLS
+	}
+	if cfg.From == "" {
+		cfg.From = "Docker Dashboard <dashboard@example.com>"
+	}
+	if cfg.To == nil {
+		cfg.To = []string{"ops@example.com", "Дежурный <oncall@example.com>"}
+	}
+	return newTestNotifier(t, cfg)
+}
+
+func TestEmailSend(t *testing.T) {
+	server := newSMTPStub(t)
+	n := newTestEmailNotifier(t, server, NotifierConfig{SMTPUsername: "user", SMTPPassword: "pass"})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := n.Notify(ctx, testAlert); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.from != "<dashboard@example.com>" {
+		t.Errorf("MAIL FROM %s", server.from)
+	}
+	if strings.Join(server.to, ",") != "<ops@example.com>,<oncall@example.com>" {
+		t.Errorf("RCPT TO %v", server.to)
+	}
+	auth, _ := base64.StdEncoding.DecodeString(server.auth)
+	if string(auth) != "\x00user\x00pass" {
+		t.Errorf("AUTH PLAIN %q", auth)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(server.data))
+	if err != nil {
+		t.Fatalf("invalid message: %v\n%s", err, server.data)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "[FIRING] errors: api" {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID = %s", msg.Header.Get("Message-ID"))
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if !strings.HasPrefix(string(body), testAlert.Summary+"\r\nSeverity: critical\r\n") {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestEmailCompose(t *testing.T) {
+	n, err := newEmailNotifier(NotifierConfig{
+		Name:     "mail",
+		SMTPHost: "smtp.example.com",
+		From:     "dashboard@alerts.example.com",
+		To:       []string{"Дежурный <oncall@example.com>"},
+	}, message{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.addr != "smtp.example.com:587" || n.tlsMode != smtpStartTLS {
+		t.Errorf("addr %s, tls %s; want port 587 with STARTTLS by default", n.addr, n.tlsMode)
+	}
+
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	text := "Контейнер api\nупал " + strings.Repeat("долго ", 20)
+	data, err := n.compose("[FIRING] ошибки\r\nBcc: evil@example.com", text, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := msg.Header["Bcc"]; ok {
+		t.Error("line breaks in the title must not add headers")
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "[FIRING] ошибки  Bcc: evil@example.com" {
+		t.Errorf("subject = %q", subject)
+	}
+	to, err := msg.Header.AddressList("To")
+	if err != nil || len(to) != 1 || to[0].Name != "Дежурный" || to[0].Address != "oncall@example.com" {
+		t.Errorf("To = %v, %v", to, err)
+	}
+	if date, _ := msg.Header.Date(); !date.Equal(now) {
+		t.Errorf("Date = %v", date)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@alerts.example.com>") {
+		t.Errorf("Message-ID = %s", msg.Header.Get("Message-ID"))
+	}
+	if msg.Header.Get("Content-Transfer-Encoding") != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %s", msg.Header.Get("Content-Transfer-Encoding"))
+	}
+	// строки текста в quoted-printable не длиннее 76 символов
+	_, rawBody, _ := strings.Cut(string(data), "\r\n\r\n")
+	for _, line := range strings.Split(rawBody, "\r\n") {
+		if len(line) > 78 {
+			t.Errorf("line is too long: %q", line)
+		}
+	}
+	body, _ := io.ReadAll(quotedprintable.NewReader(msg.Body))
+	if want := strings.ReplaceAll(text, "\n", "\r\n") + "\r\n"; string(body) != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestEmailRejectedRecipientIsPermanent(t *testing.T) {
+	server := newSMTPStub(t)
+	server.rejectRcpt = true
+	n := newTestEmailNotifier(t, server, NotifierConfig{})
+	err := n.Notify(context.Background(), testAlert)
+	var perm *permanentError
+	if !errors.As(err, &perm) || !strings.Contains(err.Error(), "No such user") {
+		t.Errorf("err = %v, want permanent 550", err)
+	}
+}
+
+func TestEmailRequiresStartTLS(t *testing.T) {
+	server := newSMTPStub(t)
+	n := newTestEmailNotifier(t, server, NotifierConfig{SMTPTLS: smtpStartTLS})
+	err := n.Notify(context.Background(), testAlert)
+	var perm *permanentError
+	if !errors.As(err, &perm) || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("err = %v, want permanent STARTTLS error", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.from != "" {
+		t.Error("message was sent without TLS")
+	}
+}
+
+func TestEmailConnectionErrorIsTemporary(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	n := newTestNotifier(t, NotifierConfig{
+		Type: NotifierEmail, SMTPHost: "127.0.0.1", SMTPPort: port, SMTPTLS: smtpNoTLS,
+		From: "dashboard@example.com", To: []string{"ops@example.com"},
+	})
+	err = n.Notify(context.Background(), testAlert)
+	var perm *permanentError
+	if err == nil || errors.As(err, &perm) {
+		t.Errorf("err = %v, want temporary error", err)
+	}
+}
+
+func TestEmailConfigErrors(t *testing.T) {
+	base := NotifierConfig{Name: "mail", Type: NotifierEmail, SMTPHost: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}}
+	tests := map[string]func(*NotifierConfig){
+		"no host":  func(c *NotifierConfig) { c.SMTPHost = "" },
+		"bad tls":  func(c *NotifierConfig) { c.SMTPTLS = "ssl" },
+		"bad from": func(c *NotifierConfig) { c.From = "not an address" },
+		"no to":    func(c *NotifierConfig) { c.To = nil },
+		"bad to":   func(c *NotifierConfig) { c.To = []string{"b@example.com", "@"} },
+	}
+	for name, modify := range tests {
+		cfg := base
+		modify(&cfg)
+		if _, _, err := NewNotifier(cfg); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	cfg := base
+	cfg.SMTPTLS = smtpTLS
+	n, _, err := NewNotifier(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr := n.(*emailNotifier).addr; addr != "smtp.example.com:"+strconv.Itoa(465) {
+		t.Errorf("implicit TLS addr = %s, want port 465", addr)
+	}
+}
@@ -0,0 +1,302 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/logstream"
+)
+
+const (
+	// Как часто искать новые контейнеры и проверять пороги правил по логам
+	logWatchInterval = 5 * time.Second
+	// Пауза перед повторной попыткой читать логи контейнера после ошибки Docker
+	logWatchRetryDelay = time.Minute
+	// Максимальная длина строки лога в деталях алерта
+	maxAlertLineLength = 512
+)
+
+// hitBucket — число совпадений за одну секунду
+type hitBucket struct {
+	second int64
+	count  int
+}
+
+// logHits — совпадения правила в логах одного контейнера за последнее окно
+type logHits struct {
+	rule      *LogRule
+	container containers.Container
+	buckets   []hitBucket
+	lastLine  string
+	lastFired time.Time
+}
+
+func (h *logHits) add(t time.Time, text string) {
+	second := t.Unix()
+	if n := len(h.buckets); n > 0 && h.buckets[n-1].second >= second {
+		// Строки приходят по порядку; более ранние метки считаем в последнюю секунду
+		h.buckets[n-1].count++
+	} else {
+		h.buckets = append(h.buckets, hitBucket{second: second, count: 1})
+	}
+	h.lastLine = text
+}
+
+// count удаляет совпадения старше окна и возвращает число оставшихся
+func (h *logHits) count(now time.Time) int {
+	from := now.Add(-h.rule.Window.Duration).Unix()
+	i := 0
+	for i < len(h.buckets) && h.buckets[i].second <= from {
+		i++
+	}
+	h.buckets = append(h.buckets[:0], h.buckets[i:]...)
+	total := 0
+	for _, b := range h.buckets {
+		total += b.count
+	}
+	return total
+}
+
+func (h *logHits) alert(count int) Alert {
+	rule := h.rule
+	return Alert{
+		ID:          ruleAlertID(rule.Name, &h.container),
+		Rule:        rule.Name,
+		Type:        TypeLogPattern,
+		Severity:    rule.Severity,
+		Container:   h.container.Name,
+		ContainerID: h.container.FullID,
+		Project:     h.container.ComposeProject,
+		// Шаблон не включается в описание: оно попадает в лог процесса, который тоже может читаться правилом
+		Summary: fmt.Sprintf("%d matching log lines in %s in the last %s", count, h.container.Name, rule.Window.Duration),
+		Details: map[string]string{
+			"count":     strconv.Itoa(count),
+			"threshold": strconv.Itoa(rule.Threshold),
+			"window":    rule.Window.Duration.String(),
+			"pattern":   rule.Pattern,
+			"last_line": truncate(h.lastLine, maxAlertLineLength),
+		},
+	}
+}
+
+// truncate обрезает строку до max байт, не разрывая символы UTF-8
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// logWatcher читает логи запущенных контейнеров, подходящих под правила, и считает совпадения
+type logWatcher struct {
+	rules   []*LogRule
+	manager *Manager
+	start   time.Time
+	self    string // hostname: ID собственного контейнера, если дашборд запущен в Docker
+
+	mu       sync.Mutex
+	active   map[string]bool      // полный ID -> идет чтение логов
+	lastSeen map[string]time.Time // полный ID -> метка времени последней строки
+	retryAt  map[string]time.Time // полный ID -> когда повторить чтение после ошибки
+	hits     map[string]*logHits  // ID алерта -> совпадения
+}
+
+func newLogWatcher(rules []*LogRule, manager *Manager) *logWatcher {
+	hostname, _ := os.Hostname()
+	return &logWatcher{
+		self:     hostname,
+		rules:    rules,
+		manager:  manager,
+		start:    time.Now(),
+		active:   make(map[string]bool),
+		lastSeen: make(map[string]time.Time),
+		retryAt:  make(map[string]time.Time),
+		hits:     make(map[string]*logHits),
+	}
+}
+
+func (w *logWatcher) run() {
+	log.Printf("[docker-dashboard] Alerts: watching logs for %d rules", len(w.rules))
+	ticker := time.NewTicker(logWatchInterval)
+	defer ticker.Stop()
+	for {
+		w.follow()
+		w.evaluate(time.Now())
+		<-ticker.C
+	}
+}
+
+// follow запускает чтение логов запущенных контейнеров, для которых оно еще не идет.
+// После перезапуска контейнера чтение продолжается с последней прочитанной строки
+func (w *logWatcher) follow() {
+	list, err := containers.GetContainers()
+	if err != nil {
+		log.Printf("[docker-dashboard] Alerts: failed to list containers: %v", err)
+		return
+	}
+	now := time.Now()
+	present := make(map[string]bool, len(list))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i := range list {
+		container := list[i]
+		present[container.FullID] = true
+		if container.State != "running" || w.active[container.FullID] || now.Before(w.retryAt[container.FullID]) {
+			continue
+		}
+		// Свои логи не читаем: в них пишутся сами алерты
+		if len(w.self) == 12 && strings.HasPrefix(container.FullID, w.self) {
+			continue
+		}
+		var rules []*LogRule
+		for _, rule := range w.rules {
+			if rule.Selector.Match(&container) {
+				rules = append(rules, rule)
+			}
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		since := w.start
+		if last, ok := w.lastSeen[container.FullID]; ok && !last.Before(since) {
+			since = last.Add(time.Nanosecond)
+		}
+		w.active[container.FullID] = true
+		go w.read(container, rules, since)
+	}
+	// Удаленные контейнеры больше не появятся
+	for id := range w.lastSeen {
+		if !present[id] {
+			delete(w.lastSeen, id)
+			delete(w.retryAt, id)
+		}
+	}
+}
+
+// read читает логи контейнера начиная с since до остановки контейнера
+func (w *logWatcher) read(container containers.Container, rules []*LogRule, since time.Time) {
+	defer func() {
+		w.mu.Lock()
+		delete(w.active, container.FullID)
+		w.mu.Unlock()
+	}()
+	body, err := openLogStream(context.Background(), container.FullID, since)
+	if err != nil {
+		log.Printf("[docker-dashboard] Alerts: failed to read logs of %s: %v", container.Name, err)
+		w.mu.Lock()
+		w.retryAt[container.FullID] = time.Now().Add(logWatchRetryDelay)
+		w.mu.Unlock()
+		return
+	}
+	defer body.Close()
+	reader := logstream.NewReader(body, container.Tty, true)
+	for {
+		line, err := reader.Next()
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("[docker-dashboard] Alerts: error reading logs of %s: %v", container.Name, err)
+			}
+			return
+		}
+		if line.Timestamp.IsZero() {
+			line.Timestamp = time.Now()
+		}
+		var matched []*LogRule
+		for _, rule := range rules {
+			if rule.Match(line) {
+				matched = append(matched, rule)
+			}
+		}
+		w.mu.Lock()
+		if line.Timestamp.After(w.lastSeen[container.FullID]) {
+			w.lastSeen[container.FullID] = line.Timestamp
+		}
+		for _, rule := range matched {
+			id := ruleAlertID(rule.Name, &container)
+			h, ok := w.hits[id]
+			if !ok {
+				h = &logHits{rule: rule}
+				w.hits[id] = h
+			}
+			h.container = container
+			h.add(line.Timestamp, line.Text)
+		}
+		w.mu.Unlock()
+	}
+}
+
+// evaluate сравнивает число совпадений за окно с порогом: алерт срабатывает при достижении
+// порога (не чаще раза за Cooldown) и разрешается, когда совпадений за окно становится меньше
+func (w *logWatcher) evaluate(now time.Time) {
+	var fire []Alert
+	var resolve []string
+	w.mu.Lock()
+	for id, h := range w.hits {
+		count := h.count(now)
+		firing := w.manager.Firing(id)
+		switch {
+		case count >= h.rule.Threshold && firing:
+			// Обновляем число совпадений в активном алерте
+			fire = append(fire, h.alert(count))
+		case count >= h.rule.Threshold:
+			if !h.lastFired.IsZero() && now.Sub(h.lastFired) < h.rule.Cooldown.Duration {
+				continue
+			}
+			h.lastFired = now
+			fire = append(fire, h.alert(count))
+		case firing:
+			resolve = append(resolve, id)
+		case count == 0 && now.Sub(h.lastFired) >= h.rule.Cooldown.Duration:
+			delete(w.hits, id)
+		}
+	}
+	w.mu.Unlock()
+	for _, alert := range fire {
+		w.manager.Fire(alert)
+	}
+	for _, id := range resolve {
+		w.manager.Resolve(id)
+	}
+}
+
+// openLogStream открывает поток новых строк логов контейнера с метками времени
+func openLogStream(ctx context.Context, id string, since time.Time) (io.ReadCloser, error) {
+	query := url.Values{}
+	query.Set("follow", "true")
+	query.Set("stdout", "true")
+	query.Set("stderr", "true")
+	query.Set("timestamps", "true")
+	query.Set("since", fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()))
+	logsURL, err := containers.DockerURL("/containers/" + id + "/logs?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := containers.DockerStreamClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("docker API status %d", resp.StatusCode)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,279 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Типы каналов уведомлений
+const (
+	NotifierWebhook  = "webhook"
+	NotifierSlack    = "slack" // Slack и Mattermost incoming webhooks
+	NotifierTelegram = "telegram"
+	NotifierEmail    = "email"
+)
+
+const (
+	// Число попыток отправки уведомления, включая первую
+	notifyAttempts = 4
+	// Размер очереди одного канала
+	notifierQueueSize = 64
+)
+
+// Паузы между попытками (переменные, чтобы тесты не ждали)
+var (
+	// Пауза перед первой повторной попыткой; дальше удваивается
+	notifyBackoff = 2 * time.Second
+	// Максимальная пауза между попытками
+	maxNotifyBackoff = time.Minute
+)
+
+// Шаблоны по умолчанию (text/template, данные — Alert)
+const (
+	defaultTitleTemplate = `[{{upper .State}}] {{.Rule}}{{if .Container}}: {{.Container}}{{end}}`
+	defaultBodyTemplate  = `{{.Summary}}
+Severity: {{.Severity}}
+{{if .Project}}Project: {{.Project}}
+{{end}}Started: {{.StartsAt.Format "2006-01-02 15:04:05 MST"}}
+{{if .EndsAt}}Resolved: {{.EndsAt.Format "2006-01-02 15:04:05 MST"}}
+{{end}}{{range $key, $value := .Details}}{{$key}}: {{$value}}
+{{end}}`
+)
+
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// NotifierConfig — канал уведомлений в ALERTS_CONFIG_FILE
+type NotifierConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // webhook, slack, telegram или email
+
+	// webhook и slack: адрес; telegram: адрес Bot API (по умолчанию https://api.telegram.org)
+	URL     string            `json:"url,omitempty"`
+	Secret  string            `json:"secret,omitempty"`  // webhook: ключ HMAC-SHA256 подписи тела
+	Headers map[string]string `json:"headers,omitempty"` // webhook: дополнительные заголовки
+
+	Channel  string `json:"channel,omitempty"`  // slack: канал вместо канала по умолчанию
+	Username string `json:"username,omitempty"` // slack: имя отправителя
+
+	BotToken string `json:"bot_token,omitempty"` // telegram
+	ChatID   string `json:"chat_id,omitempty"`   // telegram
+
+	SMTPHost     string   `json:"smtp_host,omitempty"` // email
+	SMTPPort     int      `json:"smtp_port,omitempty"` // по умолчанию 587, для tls — 465
+	SMTPUsername string   `json:"smtp_username,omitempty"`
+	SMTPPassword string   `json:"smtp_password,omitempty"`
+	SMTPTLS      string   `json:"smtp_tls,omitempty"` // starttls (по умолчанию), tls или none
+	From         string   `json:"from,omitempty"`
+	To           []string `json:"to,omitempty"`
+
+	Title    string `json:"title,omitempty"`    // шаблон заголовка (тема письма)
+	Template string `json:"template,omitempty"` // шаблон текста
+
+	MinSeverity  string `json:"min_severity,omitempty"`  // не отправлять алерты ниже этой важности
+	SendResolved *bool  `json:"send_resolved,omitempty"` // уведомлять о разрешении (по умолчанию true)
+}
+
+// Filter определяет, какие алерты отправляются в канал
+type Filter struct {
+	MinSeverity  string
+	SkipResolved bool
+}
+
+var severityRank = map[string]int{SeverityInfo: 0, SeverityWarning: 1, SeverityCritical: 2}
+
+// Accept сообщает, нужно ли отправлять алерт в канал
+func (f Filter) Accept(alert Alert) bool {
+	if f.SkipResolved && alert.State == StateResolved {
+		return false
+	}
+	return severityRank[alert.Severity] >= severityRank[f.MinSeverity]
+}
+
+// message — заголовок и текст уведомления по шаблонам канала
+type message struct {
+	title *template.Template
+	body  *template.Template
+}
+
+func newMessage(cfg NotifierConfig) (message, error) {
+	titleText, bodyText := cfg.Title, cfg.Template
+	if titleText == "" {
+		titleText = defaultTitleTemplate
+	}
+	if bodyText == "" {
+		bodyText = defaultBodyTemplate
+	}
+	title, err := template.New("title").Funcs(templateFuncs).Option("missingkey=zero").Parse(titleText)
+	if err != nil {
+		return message{}, fmt.Errorf("invalid title template: %w", err)
+	}
+	body, err := template.New("template").Funcs(templateFuncs).Option("missingkey=zero").Parse(bodyText)
+	if err != nil {
+		return message{}, fmt.Errorf("invalid template: %w", err)
+	}
+	return message{title: title, body: body}, nil
+}
+
+// render возвращает заголовок и текст уведомления. Ошибка шаблона не исправится при повторе
+func (m message) render(alert Alert) (string, string, error) {
+	var title, body bytes.Buffer
+	if err := m.title.Execute(&title, alert); err != nil {
+		return "", "", permanent(fmt.Errorf("title template: %w", err))
+	}
+	if err := m.body.Execute(&body, alert); err != nil {
+		return "", "", permanent(fmt.Errorf("template: %w", err))
+	}
+	return strings.TrimSpace(title.String()), strings.TrimSpace(body.String()), nil
+}
+
+// NewNotifier создает канал уведомлений по конфигурации
+func NewNotifier(cfg NotifierConfig) (Notifier, Filter, error) {
+	if cfg.Name == "" {
+		return nil, Filter{}, fmt.Errorf("name is required")
+	}
+	filter := Filter{MinSeverity: cfg.MinSeverity, SkipResolved: cfg.SendResolved != nil && !*cfg.SendResolved}
+	if filter.MinSeverity != "" {
+		if _, ok := severityRank[filter.MinSeverity]; !ok {
+			return nil, Filter{}, fmt.Errorf("unknown min_severity %q, expected info, warning or critical", filter.MinSeverity)
+		}
+	}
+	msg, err := newMessage(cfg)
+	if err != nil {
+		return nil, Filter{}, err
+	}
+	var n Notifier
+	switch cfg.Type {
+	case NotifierWebhook:
+		n, err = newWebhookNotifier(cfg, msg)
+	case NotifierSlack:
+		n, err = newSlackNotifier(cfg, msg)
+	case NotifierTelegram:
+		n, err = newTelegramNotifier(cfg, msg)
+	case NotifierEmail:
+		n, err = newEmailNotifier(cfg, msg)
+	case "":
+		err = fmt.Errorf("type is required")
+	default:
+		err = fmt.Errorf("unknown type %q, expected webhook, slack, telegram or email", cfg.Type)
+	}
+	if err != nil {
+		return nil, Filter{}, err
+	}
+	return n, filter, nil
+}
+
+// permanentError — ошибка, при которой повторная отправка бессмысленна (неверный адрес, 4xx)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// retryAfterError — сервис попросил повторить не раньше указанного времени (429)
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// notifierWorker отправляет уведомления одного канала по очереди, с повторами
+type notifierWorker struct {
+	notifier Notifier
+	filter   Filter
+	queue    chan Alert
+}
+
+func (w *notifierWorker) run() {
+	for alert := range w.queue {
+		deliver(w.notifier, alert)
+	}
+}
+
+// deliver отправляет уведомление, повторяя временные ошибки с растущей паузой
+func deliver(n Notifier, alert Alert) {
+	delay := notifyBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+		err := n.Notify(ctx, alert)
+		cancel()
+		if err == nil {
+			return
+		}
+		var perm *permanentError
+		if errors.As(err, &perm) || attempt == notifyAttempts {
+			log.Printf("[docker-dashboard] Alerts: %s notification for %s failed after %d attempts: %v", n.Name(), alert.ID, attempt, err)
+			return
+		}
+		wait := delay
+		var retryAfter *retryAfterError
+		if errors.As(err, &retryAfter) && retryAfter.after > wait {
+			wait = min(retryAfter.after, maxNotifyBackoff)
+		}
+		log.Printf("[docker-dashboard] Alerts: %s notification for %s failed: %v; retrying in %s", n.Name(), alert.ID, err, wait)
+		time.Sleep(wait)
+		delay = min(delay*2, maxNotifyBackoff)
+	}
+}
+
+// notifyHTTPClient — клиент для webhook каналов; время запроса ограничивается через context
+var notifyHTTPClient = &http.Client{}
+
+// postJSON отправляет JSON и проверяет статус ответа. 429 и 5xx считаются временными ошибками
+func postJSON(ctx context.Context, target string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "docker-dashboard")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := notifyHTTPClient.Do(req)
+	if err != nil {
+		// Адрес не попадает в текст ошибки: в нем бывают секреты (токен бота, путь webhook)
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("request failed: %w", urlErr.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("status %d", resp.StatusCode)
+	if text := strings.TrimSpace(string(respBody)); text != "" {
+		err = fmt.Errorf("status %d: %s", resp.StatusCode, text)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		after, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return &retryAfterError{err: err, after: time.Duration(after) * time.Second}
+	case resp.StatusCode >= 500:
+		return err
+	default:
+		return permanent(err)
+	}
+}
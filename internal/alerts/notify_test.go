@@ -0,0 +1,352 @@
+package alerts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// request — запрос, полученный тестовым HTTP сервером
+type request struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// stubServer отвечает статусами из statuses по очереди (последний повторяется) и запоминает запросы
+type stubServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []request
+	statuses []int
+	header   http.Header // дополнительные заголовки ответа
+}
+
+func newStubServer(t *testing.T, statuses ...int) *stubServer {
+	t.Helper()
+	if len(statuses) == 0 {
+		statuses = []int{http.StatusOK}
+	}
+	s := &stubServer{statuses: statuses, header: http.Header{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, request{path: r.URL.Path, header: r.Header.Clone(), body: body})
+		status := s.statuses[min(len(s.requests), len(s.statuses))-1]
+		for key, values := range s.header {
+			w.Header()[key] = values
+		}
+		s.mu.Unlock()
+		w.WriteHeader(status)
+		if status >= 300 {
+			io.WriteString(w, http.StatusText(status))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubServer) received() []request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]request(nil), s.requests...)
+}
+
+// fastRetries сокращает паузы между попытками на время теста
+func fastRetries(t *testing.T) {
+	backoff, maxBackoff := notifyBackoff, maxNotifyBackoff
+	notifyBackoff, maxNotifyBackoff = time.Millisecond, 10*time.Millisecond
+	t.Cleanup(func() { notifyBackoff, maxNotifyBackoff = backoff, maxBackoff })
+}
+
+func newTestNotifier(t *testing.T, cfg NotifierConfig) Notifier {
+	t.Helper()
+	if cfg.Name == "" {
+		cfg.Name = "test"
+	}
+	n, _, err := NewNotifier(cfg)
+	if err != nil {
+		t.Fatalf("NewNotifier: %v", err)
+	}
+	return n
+}
+
+var testAlert = Alert{
+	ID:        "errors/api",
+	Rule:      "errors",
+	Type:      TypeLogPattern,
+	Severity:  SeverityCritical,
+	State:     StateFiring,
+	Container: "api",
+	Project:   "shop",
+	Summary:   "api logged 12 matching lines",
+	Details:   map[string]string{"matches": "12"},
+	StartsAt:  time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+}
+
+func TestWebhookSignature(t *testing.T) {
+	server := newStubServer(t)
+	n := newTestNotifier(t, NotifierConfig{
+		Type:    NotifierWebhook,
+		URL:     server.URL + "/hook",
+		Secret:  "s3cret",
+		Headers: map[string]string{"X-Team": "ops"},
+	})
+	if err := n.Notify(context.Background(), testAlert); err != nil {
+		t.Fatal(err)
+	}
+
+	requests := server.received()
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	req := requests[0]
+	if req.path != "/hook" || req.header.Get("Content-Type") != "application/json" || req.header.Get("X-Team") != "ops" {
+		t.Errorf("path %s, headers %v", req.path, req.header)
+	}
+	timestamp := req.header.Get("X-Dashboard-Timestamp")
+	if timestamp == "" {
+		t.Fatal("X-Dashboard-Timestamp is missing")
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "." + string(req.body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := req.header.Get("X-Dashboard-Signature"); got != want {
+		t.Errorf("signature = %s, want %s", got, want)
+	}
+
+	var payload struct {
+		Title   string `json:"title"`
+		Message string `json:"message"`
+		Alert   Alert  `json:"alert"`
+	}
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Title != "[FIRING] errors: api" {
+		t.Errorf("title = %q", payload.Title)
+	}
+	if !strings.HasPrefix(payload.Message, testAlert.Summary) || !strings.Contains(payload.Message, "Project: shop") {
+		t.Errorf("message = %q", payload.Message)
+	}
+	if payload.Alert.ID != testAlert.ID || payload.Alert.Details["matches"] != "12" {
+		t.Errorf("alert = %+v", payload.Alert)
+	}
+}
+
+func TestWebhookWithoutSecret(t *testing.T) {
+	server := newStubServer(t)
+	n := newTestNotifier(t, NotifierConfig{Type: NotifierWebhook, URL: server.URL})
+	if err := n.Notify(context.Background(), testAlert); err != nil {
+		t.Fatal(err)
+	}
+	header := server.received()[0].header
+	if header.Get("X-Dashboard-Signature") != "" || header.Get("X-Dashboard-Timestamp") != "" {
+		t.Errorf("unsigned webhook has signature headers: %v", header)
+	}
+}
+
+func TestSlackPayload(t *testing.T) {
+	server := newStubServer(t)
+	n := newTestNotifier(t, NotifierConfig{
+		Type:     NotifierSlack,
+		URL:      server.URL + "/services/T000/B000/XXXX",
+		Channel:  "#alerts",
+		Username: "dashboard",
+		Title:    "{{.Rule}} {{lower .State}}",
+		Template: "{{.Summary}}",
+	})
+	resolved := testAlert
+	resolved.State = StateResolved
+	for _, alert := range []Alert{testAlert, resolved} {
+		if err := n.Notify(context.Background(), alert); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requests := server.received()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	wantColors := []string{"#d00000", "#2eb886"}
+	wantTitles := []string{"errors firing", "errors resolved"}
+	for i, req := range requests {
+		var payload map[string]interface{}
+		if err := json.Unmarshal(req.body, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := payload["text"]; ok {
+			t.Errorf("payload has top-level text: %s", req.body)
+		}
+		if payload["channel"] != "#alerts" || payload["username"] != "dashboard" {
+			t.Errorf("channel and username: %s", req.body)
+		}
+		attachments, _ := payload["attachments"].([]interface{})
+		if len(attachments) != 1 {
+			t.Fatalf("attachments: %s", req.body)
+		}
+		attachment := attachments[0].(map[string]interface{})
+		want := map[string]interface{}{
+			"color":    wantColors[i],
+			"title":    wantTitles[i],
+			"text":     testAlert.Summary,
+			"fallback": wantTitles[i] + "\n" + testAlert.Summary,
+		}
+		for key, value := range want {
+			if attachment[key] != value {
+				t.Errorf("request %d: attachment %s = %v, want %v", i, key, attachment[key], value)
+			}
+		}
+	}
+}
+
+func TestTelegramPayload(t *testing.T) {
+	server := newStubServer(t)
+	n := newTestNotifier(t, NotifierConfig{
+		Type:     NotifierTelegram,
+		URL:      server.URL + "/",
+		BotToken: "123:ABC",
+		ChatID:   "-100500",
+		Template: strings.Repeat("x", 5000),
+	})
+	if err := n.Notify(context.Background(), testAlert); err != nil {
+		t.Fatal(err)
+	}
+
+	req := server.received()[0]
+	if req.path != "/bot123:ABC/sendMessage" {
+		t.Errorf("path = %s", req.path)
+	}
+	var payload struct {
+		ChatID                string `json:"chat_id"`
+		Text                  string `json:"text"`
+		DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+	}
+	if err := json.Unmarshal(req.body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.ChatID != "-100500" || !payload.DisableWebPagePreview {
+		t.Errorf("payload = %s", req.body)
+	}
+	// Telegram ограничивает текст 4096 символами
+	if !strings.HasPrefix(payload.Text, "[FIRING] errors: api\n\nxxx") || !strings.HasSuffix(payload.Text, "x…") ||
+		utf8.RuneCountInString(payload.Text) > 4096 {
+		t.Errorf("text has %d characters: %.40q", utf8.RuneCountInString(payload.Text), payload.Text)
+	}
+}
+
+func TestNotifierConfigErrors(t *testing.T) {
+	tests := []NotifierConfig{
+		{Name: "a", Type: NotifierWebhook},
+		{Name: "a", Type: NotifierSlack, URL: "ftp://hooks.slack.com/services/T000/B000/XXXX"},
+		{Name: "a", Type: NotifierTelegram, BotToken: "123:ABC"},
+		{Name: "a", Type: "pager"},
+		{Name: "a", Type: NotifierWebhook, URL: "http://localhost", MinSeverity: "loud"},
+		{Name: "a", Type: NotifierWebhook, URL: "http://localhost", Template: "{{.Summary"},
+		{Type: NotifierWebhook, URL: "http://localhost"},
+	}
+	for _, cfg := range tests {
+		if _, _, err := NewNotifier(cfg); err == nil {
+			t.Errorf("NewNotifier(%+v): expected error", cfg)
+		}
+	}
+
+	// Секрет из адреса webhook не попадает в текст ошибки
+	_, _, err := NewNotifier(NotifierConfig{Name: "a", Type: NotifierSlack, URL: "hooks.slack.com/services/T000/B000/XXXX"})
+	if err == nil || strings.Contains(err.Error(), "XXXX") {
+		t.Errorf("error = %v, must not contain the url", err)
+	}
+}
+
+// countingNotifier считает попытки отправки
+type countingNotifier struct {
+	Notifier
+	mu       sync.Mutex
+	attempts int
+	last     error
+}
+
+func (n *countingNotifier) Notify(ctx context.Context, alert Alert) error {
+	err := n.Notifier.Notify(ctx, alert)
+	n.mu.Lock()
+	n.attempts++
+	n.last = err
+	n.mu.Unlock()
+	return err
+}
+
+func TestDeliverRetries(t *testing.T) {
+	fastRetries(t)
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		attempts   int
+		delivered  bool
+		permanent  bool
+	}{
+		{"success", []int{200}, "", 1, true, false},
+		{"5xx is retried", []int{503, 502, 200}, "", 3, true, false},
+		{"429 is retried", []int{429, 204}, "1", 2, true, false},
+		{"5xx until attempts run out", []int{500}, "", notifyAttempts, false, false},
+		{"4xx is not retried", []int{400}, "", 1, false, true},
+		{"404 is not retried", []int{503, 404}, "", 2, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStubServer(t, tt.statuses...)
+			if tt.retryAfter != "" {
+				server.header.Set("Retry-After", tt.retryAfter)
+			}
+			n := &countingNotifier{Notifier: newTestNotifier(t, NotifierConfig{Type: NotifierWebhook, URL: server.URL})}
+			deliver(n, testAlert)
+
+			if n.attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", n.attempts, tt.attempts)
+			}
+			if got := n.last == nil; got != tt.delivered {
+				t.Errorf("last error = %v, delivered want %v", n.last, tt.delivered)
+			}
+			var perm *permanentError
+			if got := errors.As(n.last, &perm); got != tt.permanent {
+				t.Errorf("last error = %v, permanent want %v", n.last, tt.permanent)
+			}
+		})
+	}
+}
+
+func TestPostJSONErrors(t *testing.T) {
+	server := newStubServer(t, http.StatusTooManyRequests)
+	server.header.Set("Retry-After", "30")
+	err := postJSON(context.Background(), server.URL, []byte("{}"), nil)
+	var retryAfter *retryAfterError
+	if !errors.As(err, &retryAfter) || retryAfter.after != 30*time.Second {
+		t.Errorf("429: err = %#v, want retry after 30s", err)
+	}
+	if !strings.Contains(err.Error(), "status 429: Too Many Requests") {
+		t.Errorf("429: err = %v", err)
+	}
+
+	// Ошибка соединения — временная, адрес с токеном в нее не попадает
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	err = postJSON(context.Background(), closed.URL+"/bot123:SECRET/sendMessage", []byte("{}"), nil)
+	var perm *permanentError
+	if err == nil || errors.As(err, &perm) {
+		t.Fatalf("connection error: err = %v, want temporary error", err)
+	}
+	if strings.Contains(err.Error(), "SECRET") {
+		t.Errorf("error contains the url: %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package alerts
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/hostinfo"
+)
+
+// Типы правил состояния контейнеров и ресурсов
+const (
+	TypeUnhealthy = "unhealthy" // контейнер unhealthy
+	TypeRestarts  = "restarts"  // счетчик перезапусков вырос
+	TypeExited    = "exited"    // контейнер завершился с ненулевым кодом
+	TypeCPU       = "cpu"       // CPU выше threshold% лимита DeployResources
+	TypeMemory    = "memory"    // память выше threshold% лимита DeployResources
+	TypeHostDisk  = "host_disk" // раздел диска хоста заполнен больше threshold%
+)
+
+const (
+	// Как часто проверяются правила состояния и ресурсов
+	ruleEvalInterval = 10 * time.Second
+	// Окно подсчета перезапусков по умолчанию
+	defaultRestartWindow = 10 * time.Minute
+)
+
+// Rule — правило состояния контейнера или ресурсов. Алерт срабатывает, когда условие
+// выполняется непрерывно в течение For, и разрешается, как только условие перестает выполняться
+type Rule struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Selector
+	For Duration `json:"for,omitempty"`
+	// Threshold — процент для cpu, memory и host_disk; для restarts — число перезапусков за Window (по умолчанию 1)
+	Threshold float64 `json:"threshold,omitempty"`
+	// Window — окно подсчета перезапусков для restarts (по умолчанию 10m);
+	// алерт разрешается, когда за окно перезапусков становится меньше Threshold
+	Window      Duration `json:"window,omitempty"`
+	Mountpoints []string `json:"mountpoints,omitempty"` // разделы для host_disk, по умолчанию все
+	Severity    string   `json:"severity,omitempty"`    // info, warning (по умолчанию), critical
+}
+
+func (r *Rule) prepare() error {
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch r.Type {
+	case TypeUnhealthy, TypeExited:
+	case TypeRestarts:
+		if r.Threshold == 0 {
+			r.Threshold = 1
+		}
+		if r.Window.Duration == 0 {
+			r.Window.Duration = defaultRestartWindow
+		}
+	case TypeCPU, TypeMemory, TypeHostDisk:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("threshold (percent) is required for %s", r.Type)
+		}
+	case "":
+		return fmt.Errorf("type is required")
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+	if r.Threshold < 0 {
+		return fmt.Errorf("threshold must not be negative")
+	}
+	if r.Type == TypeHostDisk {
+		if len(r.Containers) > 0 || len(r.Projects) > 0 || len(r.Labels) > 0 {
+			return fmt.Errorf("selector is not supported for %s", r.Type)
+		}
+	} else if len(r.Mountpoints) > 0 {
+		return fmt.Errorf("mountpoints are only supported for %s", TypeHostDisk)
+	}
+	if err := r.Selector.validate(); err != nil {
+		return err
+	}
+	return validateSeverity(&r.Severity)
+}
+
+// condition — условие правила, выполняющееся в момент проверки
+type condition struct {
+	rule  *Rule
+	alert Alert
+}
+
+// ruleEngine периодически проверяет правила по списку контейнеров, их статистике и дискам хоста
+type ruleEngine struct {
+	rules   []*Rule
+	manager *Manager
+
+	pending       map[string]time.Time   // ID алерта -> с какого момента выполняется условие
+	restartCounts map[string]int         // полный ID -> последний RestartCount
+	restarts      map[string][]time.Time // ID алерта -> моменты перезапусков в окне
+}
+
+func newRuleEngine(rules []*Rule, manager *Manager) *ruleEngine {
+	return &ruleEngine{
+		rules:         rules,
+		manager:       manager,
+		pending:       make(map[string]time.Time),
+		restartCounts: make(map[string]int),
+		restarts:      make(map[string][]time.Time),
+	}
+}
+
+func (e *ruleEngine) run() {
+	log.Printf("[docker-dashboard] Alerts: evaluating %d rules every %s", len(e.rules), ruleEvalInterval)
+	ticker := time.NewTicker(ruleEvalInterval)
+	defer ticker.Stop()
+	for {
+		e.evaluate(time.Now())
+		<-ticker.C
+	}
+}
+
+func (e *ruleEngine) needs(types ...string) bool {
+	for _, rule := range e.rules {
+		if slices.Contains(types, rule.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate проверяет все правила. Если данные получить не удалось, состояние алертов не меняется
+func (e *ruleEngine) evaluate(now time.Time) {
+	list, err := containers.GetContainers()
+	if err != nil {
+		log.Printf("[docker-dashboard] Alerts: failed to list containers: %v", err)
+		return
+	}
+	stats := make(map[string]containers.ContainerStats)
+	if e.needs(TypeCPU, TypeMemory) {
+		all, err := containers.GetContainersStats()
+		if err != nil {
+			log.Printf("[docker-dashboard] Alerts: failed to get container stats: %v", err)
+			return
+		}
+		for _, s := range all {
+			stats[s.ID] = s
+		}
+	}
+	var host *hostinfo.SystemMetrics
+	if e.needs(TypeHostDisk) {
+		if host, err = hostinfo.GetSystemMetrics(); err != nil {
+			log.Printf("[docker-dashboard] Alerts: failed to get host metrics: %v", err)
+			return
+		}
+	}
+	e.apply(now, list, stats, host)
+}
+
+// apply проверяет правила по полученным данным и переводит алерты между firing и resolved
+func (e *ruleEngine) apply(now time.Time, list []containers.Container, stats map[string]containers.ContainerStats,
+	host *hostinfo.SystemMetrics) {
+	// Прирост счетчиков перезапусков с прошлой проверки
+	restarted := make(map[string]int)
+	present := make(map[string]bool, len(list))
+	for _, c := range list {
+		present[c.FullID] = true
+		if prev, ok := e.restartCounts[c.FullID]; ok && c.RestartCount > prev {
+			restarted[c.FullID] = c.RestartCount - prev
+		}
+		e.restartCounts[c.FullID] = c.RestartCount
+	}
+	for id := range e.restartCounts {
+		if !present[id] {
+			delete(e.restartCounts, id)
+		}
+	}
+
+	active := make(map[string]condition)
+	for _, rule := range e.rules {
+		if rule.Type == TypeHostDisk {
+			e.checkDisk(rule, host, active)
+			continue
+		}
+		for i := range list {
+			c := &list[i]
+			if !rule.Selector.Match(c) {
+				continue
+			}
+			alert, ok := e.checkContainer(rule, c, stats, restarted[c.FullID], now)
+			if !ok {
+				continue
+			}
+			alert.ID = ruleAlertID(rule.Name, c)
+			alert.Rule = rule.Name
+			alert.Type = rule.Type
+			alert.Severity = rule.Severity
+			alert.Container = c.Name
+			alert.ContainerID = c.FullID
+			alert.Project = c.ComposeProject
+			active[alert.ID] = condition{rule: rule, alert: alert}
+		}
+	}
+
+	for id, cond := range active {
+		since, ok := e.pending[id]
+		if !ok {
+			since = now
+			e.pending[id] = now
+		}
+		if now.Sub(since) >= cond.rule.For.Duration {
+			e.manager.Fire(cond.alert)
+		}
+	}
+	for id := range e.pending {
+		if _, ok := active[id]; !ok {
+			delete(e.pending, id)
+			e.manager.Resolve(id)
+		}
+	}
+}
+
+// checkContainer проверяет правило для контейнера; false — условие не выполняется
+func (e *ruleEngine) checkContainer(rule *Rule, c *containers.Container, stats map[string]containers.ContainerStats,
+	restarted int, now time.Time) (Alert, bool) {
+	switch rule.Type {
+	case TypeUnhealthy:
+		if c.State != "running" || c.Health != "unhealthy" {
+			return Alert{}, false
+		}
+		return Alert{Summary: c.Name + " is unhealthy"}, true
+
+	case TypeExited:
+		if (c.State != "exited" && c.State != "dead") || c.ExitCode == 0 {
+			return Alert{}, false
+		}
+		return Alert{
+			Summary: fmt.Sprintf("%s exited with code %d", c.Name, c.ExitCode),
+			Details: map[string]string{"exit_code": strconv.Itoa(c.ExitCode)},
+		}, true
+
+	case TypeRestarts:
+		id := ruleAlertID(rule.Name, c)
+		times := e.restarts[id]
+		for i := 0; i < restarted; i++ {
+			times = append(times, now)
+		}
+		from := now.Add(-rule.Window.Duration)
+		for len(times) > 0 && !times[0].After(from) {
+			times = times[1:]
+		}
+		if len(times) == 0 {
+			delete(e.restarts, id)
+		} else {
+			e.restarts[id] = times
+		}
+		if float64(len(times)) < rule.Threshold {
+			return Alert{}, false
+		}
+		return Alert{
+			Summary: fmt.Sprintf("%s restarted %d times in the last %s", c.Name, len(times), rule.Window.Duration),
+			Details: map[string]string{
+				"restarts":      strconv.Itoa(len(times)),
+				"restart_count": strconv.Itoa(c.RestartCount),
+				"window":        rule.Window.Duration.String(),
+			},
+		}, true
+
+	case TypeCPU, TypeMemory:
+		s, ok := stats[c.ID]
+		if !ok || c.State != "running" || c.DeployResources == nil {
+			return Alert{}, false
+		}
+		var percent float64
+		var limit string
+		if rule.Type == TypeCPU {
+			if c.DeployResources.CPULimitCores <= 0 {
+				return Alert{}, false
+			}
+			percent = s.CPUUsage / c.DeployResources.CPULimitCores * 100
+			limit = c.DeployResources.CPULimit
+		} else {
+			if c.DeployResources.MemoryLimitBytes <= 0 {
+				return Alert{}, false
+			}
+			percent = float64(s.MemoryUsage) / float64(c.DeployResources.MemoryLimitBytes) * 100
+			limit = c.DeployResources.MemoryLimit
+		}
+		if percent <= rule.Threshold {
+			return Alert{}, false
+		}
+		return Alert{
+			Summary: fmt.Sprintf("%s %s usage is %.0f%% of the limit %s", c.Name, rule.Type, percent, limit),
+			Details: map[string]string{
+				"percent":   strconv.FormatFloat(percent, 'f', 1, 64),
+				"threshold": strconv.FormatFloat(rule.Threshold, 'f', -1, 64),
+				"limit":     limit,
+			},
+		}, true
+	}
+	return Alert{}, false
+}
+
+// checkDisk добавляет условия для разделов хоста, заполненных больше порога
+func (e *ruleEngine) checkDisk(rule *Rule, host *hostinfo.SystemMetrics, active map[string]condition) {
+	for mountpoint, usage := range host.DiskUsage {
+		if len(rule.Mountpoints) > 0 && !slices.Contains(rule.Mountpoints, mountpoint) {
+			continue
+		}
+		if usage == nil || usage.UsedPercent <= rule.Threshold {
+			continue
+		}
+		id := rule.Name + ":" + mountpoint
+		active[id] = condition{rule: rule, alert: Alert{
+			ID:       id,
+			Rule:     rule.Name,
+			Type:     rule.Type,
+			Severity: rule.Severity,
+			Summary:  fmt.Sprintf("disk %s is %.0f%% full", mountpoint, usage.UsedPercent),
+			Details: map[string]string{
+				"mountpoint": mountpoint,
+				"percent":    strconv.FormatFloat(usage.UsedPercent, 'f', 1, 64),
+				"threshold":  strconv.FormatFloat(rule.Threshold, 'f', -1, 64),
+				"free":       strconv.FormatUint(usage.Free, 10),
+			},
+		}}
+	}
+}
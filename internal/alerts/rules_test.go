@@ -0,0 +1,250 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/disk"
+
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/hostinfo"
+)
+
+var testStart = time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+
+func newTestEngine(t *testing.T, rules ...*Rule) (*ruleEngine, *Manager) {
+	t.Helper()
+	for _, rule := range rules {
+		if err := rule.prepare(); err != nil {
+			t.Fatalf("rule %s: %v", rule.Name, err)
+		}
+	}
+	manager := NewManager(0)
+	return newRuleEngine(rules, manager), manager
+}
+
+func container(name, state string) containers.Container {
+	return containers.Container{ID: name + "-id", FullID: name + "-full-id", Name: name, State: state}
+}
+
+// step — одна проверка правил: данные в момент at и ожидаемое состояние алерта после нее
+type step struct {
+	at     time.Duration
+	list   []containers.Container
+	stats  map[string]containers.ContainerStats
+	firing bool
+}
+
+func runSteps(t *testing.T, engine *ruleEngine, manager *Manager, id string, steps []step) {
+	t.Helper()
+	for i, s := range steps {
+		engine.apply(testStart.Add(s.at), s.list, s.stats, nil)
+		if got := manager.Firing(id); got != s.firing {
+			t.Fatalf("step %d (%s): firing = %v, want %v", i, s.at, got, s.firing)
+		}
+	}
+}
+
+func TestRuleForDelay(t *testing.T) {
+	engine, manager := newTestEngine(t, &Rule{Name: "unhealthy", Type: TypeUnhealthy, For: Duration{30 * time.Second}})
+	healthy := container("web", "running")
+	healthy.Health = "healthy"
+	unhealthy := healthy
+	unhealthy.Health = "unhealthy"
+
+	runSteps(t, engine, manager, "unhealthy/web", []step{
+		{at: 0, list: []containers.Container{unhealthy}},
+		{at: 20 * time.Second, list: []containers.Container{unhealthy}},
+		// условие прервалось до For: отсчет начинается заново
+		{at: 25 * time.Second, list: []containers.Container{healthy}},
+		{at: 30 * time.Second, list: []containers.Container{unhealthy}},
+		{at: 50 * time.Second, list: []containers.Container{unhealthy}},
+		{at: 60 * time.Second, list: []containers.Container{unhealthy}, firing: true},
+		{at: 70 * time.Second, list: []containers.Container{unhealthy}, firing: true},
+		{at: 80 * time.Second, list: []containers.Container{healthy}},
+	})
+
+	resolved := manager.Resolved()
+	if len(resolved) != 1 || resolved[0].ID != "unhealthy/web" || resolved[0].State != StateResolved {
+		t.Fatalf("resolved = %+v, want one resolved unhealthy/web", resolved)
+	}
+	if resolved[0].Container != "web" || resolved[0].ContainerID != "web-full-id" || resolved[0].Severity != SeverityWarning {
+		t.Errorf("resolved alert = %+v", resolved[0])
+	}
+}
+
+func TestRuleWithoutForFiresImmediately(t *testing.T) {
+	engine, manager := newTestEngine(t, &Rule{Name: "exited", Type: TypeExited})
+	ok := container("job", "exited")
+	failed := container("job", "exited")
+	failed.ExitCode = 137
+
+	runSteps(t, engine, manager, "exited/job", []step{
+		{at: 0, list: []containers.Container{ok}},
+		{at: 10 * time.Second, list: []containers.Container{failed}, firing: true},
+		{at: 20 * time.Second, list: []containers.Container{container("job", "running")}},
+	})
+}
+
+func TestRuleResolvesWhenContainerRemoved(t *testing.T) {
+	engine, manager := newTestEngine(t, &Rule{Name: "exited", Type: TypeExited})
+	failed := container("job", "dead")
+	failed.ExitCode = 1
+	other := container("other", "running")
+
+	runSteps(t, engine, manager, "exited/job", []step{
+		{at: 0, list: []containers.Container{failed, other}, firing: true},
+		{at: 10 * time.Second, list: []containers.Container{other}},
+	})
+	if len(manager.Active()) != 0 {
+		t.Errorf("active = %+v, want none", manager.Active())
+	}
+}
+
+func TestRuleSelector(t *testing.T) {
+	engine, manager := newTestEngine(t, &Rule{Name: "exited", Type: TypeExited, Selector: Selector{Containers: []string{"api-*"}}})
+	api := container("api-1", "exited")
+	api.ExitCode = 1
+	worker := container("worker", "exited")
+	worker.ExitCode = 1
+
+	engine.apply(testStart, []containers.Container{api, worker}, nil, nil)
+	if !manager.Firing("exited/api-1") {
+		t.Error("exited/api-1 is not firing")
+	}
+	if manager.Firing("exited/worker") {
+		t.Error("exited/worker is firing, but does not match the selector")
+	}
+}
+
+func TestRuleRestartWindow(t *testing.T) {
+	engine, manager := newTestEngine(t, &Rule{Name: "restarts", Type: TypeRestarts, Threshold: 3, Window: Duration{10 * time.Minute}})
+	withCount := func(n int) []containers.Container {
+		c := container("api", "running")
+		c.RestartCount = n
+		return []containers.Container{c}
+	}
+
+	runSteps(t, engine, manager, "restarts/api", []step{
+		// первая проверка только запоминает счетчик: перезапуски до старта не считаются
+		{at: 0, list: withCount(5)},
+		{at: 1 * time.Minute, list: withCount(6)},
+		{at: 2 * time.Minute, list: withCount(7)},
+		{at: 3 * time.Minute, list: withCount(8), firing: true},
+		{at: 9 * time.Minute, list: withCount(8), firing: true},
+		// перезапуск в 1m вышел из окна: осталось два
+		{at: 11*time.Minute + 30*time.Second, list: withCount(8)},
+		// два перезапуска между проверками считаются оба
+		{at: 12 * time.Minute, list: withCount(10), firing: true},
+		{at: 12*time.Minute + 30*time.Second, list: withCount(10), firing: true},
+		// все перезапуски вышли из окна
+		{at: 23 * time.Minute, list: withCount(10)},
+	})
+}
+
+func TestRuleRestartsOfRecreatedContainer(t *testing.T) {
+	engine, manager := newTestEngine(t, &Rule{Name: "restarts", Type: TypeRestarts})
+	old := container("api", "running")
+	old.RestartCount = 3
+	recreated := container("api", "running")
+	recreated.FullID = "api-new-full-id"
+	recreated.RestartCount = 5
+
+	runSteps(t, engine, manager, "restarts/api", []step{
+		{at: 0, list: []containers.Container{old}},
+		// новый контейнер с тем же именем: его счетчик не сравнивается со старым
+		{at: time.Minute, list: []containers.Container{recreated}},
+		{at: 2 * time.Minute, list: []containers.Container{recreated}},
+	})
+	recreated.RestartCount = 6
+	runSteps(t, engine, manager, "restarts/api", []step{
+		{at: 3 * time.Minute, list: []containers.Container{recreated}, firing: true},
+	})
+}
+
+func TestRuleResourcePercentOfLimit(t *testing.T) {
+	limited := container("api", "running")
+	limited.DeployResources = &containers.DeployResources{
+		CPULimit: "0.5", CPULimitCores: 0.5,
+		MemoryLimit: "1000B", MemoryLimitBytes: 1000,
+	}
+	unlimited := container("api", "running")
+	stopped := limited
+	stopped.State = "exited"
+	usage := func(cpu float64, memory int64) map[string]containers.ContainerStats {
+		return map[string]containers.ContainerStats{"api-id": {ID: "api-id", CPUUsage: cpu, MemoryUsage: memory}}
+	}
+
+	tests := []struct {
+		name   string
+		rule   *Rule
+		list   []containers.Container
+		stats  map[string]containers.ContainerStats
+		firing bool
+	}{
+		{"cpu over threshold", &Rule{Name: "cpu", Type: TypeCPU, Threshold: 80}, []containers.Container{limited}, usage(0.45, 0), true},
+		{"cpu at threshold", &Rule{Name: "cpu", Type: TypeCPU, Threshold: 80}, []containers.Container{limited}, usage(0.4, 0), false},
+		{"cpu below threshold", &Rule{Name: "cpu", Type: TypeCPU, Threshold: 80}, []containers.Container{limited}, usage(0.3, 0), false},
+		{"cpu without limit", &Rule{Name: "cpu", Type: TypeCPU, Threshold: 80}, []containers.Container{unlimited}, usage(4, 0), false},
+		{"cpu without stats", &Rule{Name: "cpu", Type: TypeCPU, Threshold: 80}, []containers.Container{limited}, nil, false},
+		{"cpu of stopped container", &Rule{Name: "cpu", Type: TypeCPU, Threshold: 80}, []containers.Container{stopped}, usage(0.45, 0), false},
+		{"memory over threshold", &Rule{Name: "memory", Type: TypeMemory, Threshold: 90}, []containers.Container{limited}, usage(0, 950), true},
+		{"memory below threshold", &Rule{Name: "memory", Type: TypeMemory, Threshold: 90}, []containers.Container{limited}, usage(0, 850), false},
+		{"memory without limit", &Rule{Name: "memory", Type: TypeMemory, Threshold: 90}, []containers.Container{unlimited}, usage(0, 1<<30), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, manager := newTestEngine(t, tt.rule)
+			engine.apply(testStart, tt.list, tt.stats, nil)
+			id := tt.rule.Name + "/api"
+			if got := manager.Firing(id); got != tt.firing {
+				t.Fatalf("firing = %v, want %v", got, tt.firing)
+			}
+			if !tt.firing {
+				return
+			}
+			alert := manager.Active()[0]
+			if alert.Type != tt.rule.Type || alert.Details["threshold"] == "" || alert.Details["percent"] == "" {
+				t.Errorf("alert = %+v", alert)
+			}
+		})
+	}
+}
+
+func TestRuleResourceResolves(t *testing.T) {
+	engine, manager := newTestEngine(t, &Rule{Name: "cpu", Type: TypeCPU, Threshold: 80, For: Duration{time.Minute}})
+	c := container("api", "running")
+	c.DeployResources = &containers.DeployResources{CPULimitCores: 2}
+	busy := map[string]containers.ContainerStats{"api-id": {CPUUsage: 1.9}}
+	idle := map[string]containers.ContainerStats{"api-id": {CPUUsage: 0.2}}
+	list := []containers.Container{c}
+
+	runSteps(t, engine, manager, "cpu/api", []step{
+		{at: 0, list: list, stats: busy},
+		{at: time.Minute, list: list, stats: busy, firing: true},
+		{at: 2 * time.Minute, list: list, stats: idle},
+	})
+	if resolved := manager.Resolved(); len(resolved) != 1 || resolved[0].ID != "cpu/api" {
+		t.Errorf("resolved = %+v, want cpu/api", resolved)
+	}
+}
+
+func TestRuleHostDisk(t *testing.T) {
+	engine, manager := newTestEngine(t, &Rule{Name: "disk", Type: TypeHostDisk, Threshold: 90, Mountpoints: []string{"/", "/data"}})
+	host := func(root, data, other float64) *hostinfo.SystemMetrics {
+		return &hostinfo.SystemMetrics{DiskUsage: map[string]*disk.UsageStat{
+			"/":     {UsedPercent: root},
+			"/data": {UsedPercent: data},
+			"/boot": {UsedPercent: other},
+		}}
+	}
+
+	engine.apply(testStart, nil, nil, host(95, 50, 99))
+	if !manager.Firing("disk:/") || manager.Firing("disk:/data") || manager.Firing("disk:/boot") {
+		t.Fatalf("active = %+v, want only disk:/", manager.Active())
+	}
+	engine.apply(testStart.Add(time.Minute), nil, nil, host(80, 91, 99))
+	if manager.Firing("disk:/") || !manager.Firing("disk:/data") {
+		t.Fatalf("active = %+v, want only disk:/data", manager.Active())
+	}
+}
@@ -0,0 +1,203 @@
+package alerts
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultTelegramURL = "https://api.telegram.org"
+
+// Цвета вложений Slack по состоянию и важности
+var slackColors = map[string]string{
+	SeverityCritical: "#d00000",
+	SeverityWarning:  "#e8a317",
+	SeverityInfo:     "#439fe0",
+	StateResolved:    "#2eb886",
+}
+
+// validateURL проверяет адрес канала. Адрес не попадает в текст ошибки: в адресах
+// webhook Slack и Mattermost секрет — часть пути
+func validateURL(value string) error {
+	if value == "" {
+		return fmt.Errorf("url is required")
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url: expected http:// or https:// address")
+	}
+	return nil
+}
+
+// webhookNotifier отправляет алерт в виде JSON. С secret тело подписывается HMAC-SHA256:
+//
+//	X-Dashboard-Timestamp: <unix time>
+//	X-Dashboard-Signature: sha256=<hex(HMAC(secret, timestamp + "." + body))>
+type webhookNotifier struct {
+	name    string
+	url     string
+	secret  []byte
+	headers map[string]string
+	msg     message
+}
+
+// webhookPayload — тело запроса webhook
+type webhookPayload struct {
+	Title   string `json:"title"`
+	Message string `json:"message"`
+	Alert   Alert  `json:"alert"`
+}
+
+func newWebhookNotifier(cfg NotifierConfig, msg message) (*webhookNotifier, error) {
+	if err := validateURL(cfg.URL); err != nil {
+		return nil, err
+	}
+	return &webhookNotifier{name: cfg.Name, url: cfg.URL, secret: []byte(cfg.Secret), headers: cfg.Headers, msg: msg}, nil
+}
+
+func (n *webhookNotifier) Name() string { return n.name }
+
+func (n *webhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	title, text, err := n.msg.render(alert)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(webhookPayload{Title: title, Message: text, Alert: alert})
+	if err != nil {
+		return permanent(err)
+	}
+	headers := make(map[string]string, len(n.headers)+2)
+	for key, value := range n.headers {
+		headers[key] = value
+	}
+	if len(n.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers["X-Dashboard-Timestamp"] = timestamp
+		headers["X-Dashboard-Signature"] = "sha256=" + signPayload(n.secret, timestamp, body)
+	}
+	return postJSON(ctx, n.url, body, headers)
+}
+
+// signPayload вычисляет HMAC-SHA256 от "timestamp.body": метка времени защищает от повторной отправки
+func signPayload(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// slackNotifier отправляет сообщение в incoming webhook Slack или Mattermost
+type slackNotifier struct {
+	name     string
+	url      string
+	channel  string
+	username string
+	msg      message
+}
+
+type slackAttachment struct {
+	Color    string `json:"color"`
+	Title    string `json:"title"`
+	Text     string `json:"text"`
+	Fallback string `json:"fallback"`
+}
+
+type slackPayload struct {
+	Text        string            `json:"text,omitempty"`
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	Attachments []slackAttachment `json:"attachments"`
+}
+
+func newSlackNotifier(cfg NotifierConfig, msg message) (*slackNotifier, error) {
+	if err := validateURL(cfg.URL); err != nil {
+		return nil, err
+	}
+	return &slackNotifier{name: cfg.Name, url: cfg.URL, channel: cfg.Channel, username: cfg.Username, msg: msg}, nil
+}
+
+func (n *slackNotifier) Name() string { return n.name }
+
+func (n *slackNotifier) Notify(ctx context.Context, alert Alert) error {
+	title, text, err := n.msg.render(alert)
+	if err != nil {
+		return err
+	}
+	color := slackColors[alert.Severity]
+	if alert.State == StateResolved {
+		color = slackColors[StateResolved]
+	}
+	body, err := json.Marshal(slackPayload{
+		Channel:  n.channel,
+		Username: n.username,
+		Attachments: []slackAttachment{{
+			Color:    color,
+			Title:    title,
+			Text:     text,
+			Fallback: title + "\n" + text,
+		}},
+	})
+	if err != nil {
+		return permanent(err)
+	}
+	return postJSON(ctx, n.url, body, nil)
+}
+
+// telegramNotifier отправляет сообщение через Telegram Bot API (sendMessage)
+type telegramNotifier struct {
+	name   string
+	url    string // полный адрес метода sendMessage
+	chatID string
+	msg    message
+}
+
+type telegramPayload struct {
+	ChatID                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+func newTelegramNotifier(cfg NotifierConfig, msg message) (*telegramNotifier, error) {
+	if cfg.BotToken == "" || cfg.ChatID == "" {
+		return nil, fmt.Errorf("bot_token and chat_id are required")
+	}
+	apiURL := cfg.URL
+	if apiURL == "" {
+		apiURL = defaultTelegramURL
+	}
+	if err := validateURL(apiURL); err != nil {
+		return nil, err
+	}
+	return &telegramNotifier{
+		name:   cfg.Name,
+		url:    strings.TrimRight(apiURL, "/") + "/bot" + cfg.BotToken + "/sendMessage",
+		chatID: cfg.ChatID,
+		msg:    msg,
+	}, nil
+}
+
+func (n *telegramNotifier) Name() string { return n.name }
+
+func (n *telegramNotifier) Notify(ctx context.Context, alert Alert) error {
+	title, text, err := n.msg.render(alert)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(telegramPayload{
+		ChatID:                n.chatID,
+		Text:                  truncate(title+"\n\n"+text, 4000), // лимит Telegram — 4096 символов
+		DisableWebPagePreview: true,
+	})
+	if err != nil {
+		return permanent(err)
+	}
+	return postJSON(ctx, n.url, body, nil)
+}
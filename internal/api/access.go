@@ -0,0 +1,123 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/labstack/echo/v4"
+)
+
+// containerView — контейнер с правами текущего пользователя
+type containerView struct {
+	containers.Container
+	Permissions map[rbac.Action]bool `json:"permissions"`
+}
+
+// visibleContainers оставляет контейнеры, которые пользователь может видеть, и добавляет права на действия
+func visibleContainers(user *auth.User, list []containers.Container) []containerView {
+	result := make([]containerView, 0, len(list))
+	for i := range list {
+		if !rbac.Can(user, &list[i], rbac.ActionView) {
+			continue
+		}
+		result = append(result, containerView{
+			Container:   list[i],
+			Permissions: rbac.Permissions(user, &list[i]),
+		})
+	}
+	return result
+}
+
+// authorizeContainer находит контейнер и проверяет право пользователя на действие.
+// Контейнеры, которые пользователь не может видеть, выглядят как несуществующие
+func authorizeContainer(c echo.Context, idOrName string, action rbac.Action) (*containers.Container, error) {
+	if idOrName == "" {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Container ID is required")
+	}
+	user := auth.UserFromContext(c)
+	container, err := containers.GetContainer(idOrName)
+	if err != nil {
+		if containers.IsNotFound(err) {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "Container not found")
+		}
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Failed to get container: "+err.Error())
+	}
+	var denied *echo.HTTPError
+	switch {
+	case !rbac.Can(user, container, rbac.ActionView):
+		denied = echo.NewHTTPError(http.StatusNotFound, "Container not found")
+	case !rbac.FeatureEnabled(action):
+		denied = echo.NewHTTPError(http.StatusForbidden, "Container "+string(action)+" is disabled")
+	case !rbac.Can(user, container, action):
+		denied = echo.NewHTTPError(http.StatusForbidden, "Not allowed to "+string(action)+" this container")
+	}
+	if denied != nil {
+		// Отказы тоже попадают в журнал аудита
+		entry := newAuditEntry(c, string(action), container)
+		entry.Result = audit.ResultDenied
+		entry.Error = fmt.Sprint(denied.Message)
+		audit.Record(entry)
+		return nil, denied
+	}
+	return container, nil
+}
+
+// recordAccess проверяет право просмотра данных контейнеров, которые могли быть уже удалены
+// (история метрик и событий, алерты). Список контейнеров загружается один раз на запрос.
+// Для удаленных контейнеров метки неизвестны, поэтому применяются только правила без selector
+type recordAccess struct {
+	user   *auth.User
+	byID   map[string]*containers.Container
+	byName map[string]*containers.Container
+}
+
+func newRecordAccess(user *auth.User) *recordAccess {
+	a := &recordAccess{
+		user:   user,
+		byID:   make(map[string]*containers.Container),
+		byName: make(map[string]*containers.Container),
+	}
+	if list, err := containers.GetContainers(); err == nil {
+		for i := range list {
+			a.byID[list[i].ID] = &list[i]
+			a.byName[list[i].Name] = &list[i]
+		}
+	}
+	return a
+}
+
+// canView проверяет доступ к данным контейнера по короткому ID или, если ID не известен, по имени
+func (a *recordAccess) canView(id, name, project string) bool {
+	container := a.byName[name]
+	if id != "" {
+		container = a.byID[id]
+	}
+	if container == nil {
+		container = &containers.Container{Name: name, ComposeProject: project}
+	}
+	return rbac.Can(a.user, container, rbac.ActionView)
+}
+
+// visibleStats оставляет метрики только тех контейнеров, которые пользователь может видеть
+func visibleStats(user *auth.User, stats []containers.ContainerStats) []containers.ContainerStats {
+	list, err := containers.GetContainers()
+	if err != nil {
+		return []containers.ContainerStats{}
+	}
+	allowed := make(map[string]bool, len(list))
+	for i := range list {
+		allowed[list[i].ID] = rbac.Can(user, &list[i], rbac.ActionView)
+	}
+	result := make([]containers.ContainerStats, 0, len(stats))
+	for _, s := range stats {
+		if allowed[s.ID] {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -0,0 +1,351 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-dashboard/internal/actions"
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/jobs"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+// actionResponse — результат действия над контейнером
+type actionResponse struct {
+	Status    string         `json:"status"` // success или error
+	Message   string         `json:"message"`
+	Action    string         `json:"action"`
+	Container string         `json:"container,omitempty"`
+	Unchanged bool           `json:"unchanged,omitempty"`
+	Error     *actions.Error `json:"error,omitempty"`
+	JobID     string         `json:"job_id,omitempty"`
+}
+
+// actionRequest — параметры действия в теле запроса (JSON). Те же параметры
+// можно передать в query: timeout, signal, volumes, force, async, on_failure
+type actionRequest struct {
+	Timeout   *int   `json:"timeout,omitempty"`
+	Signal    string `json:"signal,omitempty"`
+	Volumes   *bool  `json:"volumes,omitempty"`
+	Force     *bool  `json:"force,omitempty"`
+	Async     *bool  `json:"async,omitempty"`
+	OnFailure string `json:"on_failure,omitempty"` // abort или continue (для действий над проектом)
+}
+
+// actionParams — разобранные параметры запроса действия
+type actionParams struct {
+	opts            actions.Options
+	async           bool
+	continueOnError bool
+}
+
+// parseActionRequest читает параметры действия из тела и query
+func parseActionRequest(c echo.Context) (actionParams, error) {
+	var body actionRequest
+	if c.Request().ContentLength != 0 && strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		if err := json.NewDecoder(c.Request().Body).Decode(&body); err != nil {
+			return actionParams{}, errors.New("invalid JSON body")
+		}
+	}
+
+	if v := c.QueryParam("timeout"); v != "" {
+		timeout, err := strconv.Atoi(v)
+		if err != nil {
+			return actionParams{}, errors.New("invalid timeout")
+		}
+		body.Timeout = &timeout
+	}
+	if v := c.QueryParam("signal"); v != "" {
+		body.Signal = v
+	}
+	if v := c.QueryParam("on_failure"); v != "" {
+		body.OnFailure = v
+	}
+	for name, target := range map[string]**bool{"volumes": &body.Volumes, "force": &body.Force, "async": &body.Async} {
+		if v := c.QueryParam(name); v != "" {
+			value, err := strconv.ParseBool(v)
+			if err != nil {
+				return actionParams{}, errors.New("invalid " + name)
+			}
+			*target = &value
+		}
+	}
+
+	params := actionParams{opts: actions.Options{Timeout: body.Timeout, Signal: body.Signal}}
+	if body.Volumes != nil {
+		params.opts.RemoveVolumes = *body.Volumes
+	}
+	if body.Force != nil {
+		params.opts.Force = *body.Force
+	}
+	switch body.OnFailure {
+	case "", "abort":
+	case "continue":
+		params.continueOnError = true
+	default:
+		return actionParams{}, errors.New("on_failure must be abort or continue")
+	}
+	// Асинхронный режим также включается заголовком Prefer: respond-async (RFC 7240)
+	params.async = body.Async != nil && *body.Async ||
+		strings.Contains(strings.ToLower(c.Request().Header.Get("Prefer")), "respond-async")
+	return params, nil
+}
+
+// runAction выполняет действие и формирует ответ; ошибка возвращается, если действие не удалось
+func runAction(ctx context.Context, action string, container *containers.Container, opts actions.Options) (actionResponse, error) {
+	result, err := actions.Do(ctx, action, container, opts)
+	if err != nil {
+		log.Printf("[docker-dashboard] %v", err)
+		response := actionResponse{Status: "error", Message: err.Error(), Action: action, Container: container.Name}
+		var actionErr *actions.Error
+		if errors.As(err, &actionErr) {
+			response.Error = actionErr
+		}
+		return response, err
+	}
+	return actionResponse{
+		Status:    "success",
+		Message:   result.Message,
+		Action:    action,
+		Container: result.Container,
+		Unchanged: result.Unchanged,
+	}, nil
+}
+
+// actionHTTPStatus переводит ошибку Docker в HTTP статус ответа
+func actionHTTPStatus(response actionResponse) int {
+	if response.Error == nil {
+		if response.Status == "success" {
+			return http.StatusOK
+		}
+		return http.StatusBadGateway
+	}
+	switch response.Error.StatusCode {
+	case http.StatusBadRequest, http.StatusNotFound, http.StatusConflict:
+		return response.Error.StatusCode
+	}
+	return http.StatusBadGateway
+}
+
+// jobOwner — владелец задач пользователя (задачи видны только тому, кто их запустил)
+func jobOwner(user *auth.User) string {
+	if user == nil {
+		return ""
+	}
+	return user.Name
+}
+
+// containerActionHandler выполняет действие над контейнером:
+//
+//	POST /api/containers/:id/:action
+//
+// По умолчанию ответ приходит после завершения действия. С async=true (или Prefer: respond-async)
+// сразу возвращается 202 и задача, состояние которой доступно в /api/jobs/:id и /ws/jobs/:id.
+// Повтор запроса с тем же заголовком Idempotency-Key не выполняет действие повторно
+func containerActionHandler(action string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		container, err := authorizeContainer(c, c.Param("id"), rbac.Action(action))
+		if err != nil {
+			return err
+		}
+		params, err := parseActionRequest(c)
+		if err == nil {
+			err = params.opts.Validate(action)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		opts := params.opts
+
+		owner := jobOwner(auth.UserFromContext(c))
+		idempotencyKey, err := idempotencyKeyFor(c, owner)
+		if err != nil {
+			return err
+		}
+		fingerprint := actionFingerprint(action, container.FullID, opts)
+
+		entry := newAuditEntry(c, action, container)
+		entry.Details = actionDetails(opts)
+		job, err := jobs.Default().Start(idempotencyKey, fingerprint, action, container.Name, owner,
+			actions.Deadline(action, opts),
+			func(ctx context.Context, _ func(interface{})) (interface{}, error) {
+				response, err := runAction(ctx, action, container, opts)
+				entry.JobID = jobs.IDFromContext(ctx)
+				recordActionAudit(entry, response, err)
+				return response, err
+			})
+		return respondJob(c, job, err, params.async, func(job jobs.Job) error {
+			response, ok := job.Result.(actionResponse)
+			if !ok {
+				return echo.NewHTTPError(http.StatusInternalServerError, job.Error)
+			}
+			response.JobID = job.ID
+			return c.JSON(actionHTTPStatus(response), response)
+		})
+	}
+}
+
+// idempotencyKeyFor возвращает ключ идемпотентности из заголовка Idempotency-Key
+// с учетом пользователя (ключи разных пользователей не пересекаются)
+func idempotencyKeyFor(c echo.Context, owner string) (string, error) {
+	key := c.Request().Header.Get("Idempotency-Key")
+	if key == "" {
+		return "", nil
+	}
+	if len(key) > 255 {
+		return "", echo.NewHTTPError(http.StatusBadRequest, "Idempotency-Key is too long")
+	}
+	return owner + "\x00" + key, nil
+}
+
+// respondJob отвечает на запуск задачи: в асинхронном режиме сразу 202 с задачей,
+// иначе ждет ее завершения и отвечает через respond
+func respondJob(c echo.Context, job jobs.Job, startErr error, async bool, respond func(jobs.Job) error) error {
+	if errors.Is(startErr, jobs.ErrIdempotencyMismatch) {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, startErr.Error())
+	}
+	if startErr != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, startErr.Error())
+	}
+
+	if async {
+		c.Response().Header().Set(echo.HeaderLocation, "/api/jobs/"+job.ID)
+		return c.JSON(http.StatusAccepted, job)
+	}
+
+	// Повтор по ключу идемпотентности может вернуть уже завершенную задачу,
+	// которой нет в менеджере: ее итог отдается сразу
+	if !job.Done() {
+		job, _ = jobs.Default().Wait(c.Request().Context(), job.ID)
+	}
+	if !job.Done() {
+		// Клиент отключился, действие продолжает выполняться в фоне
+		return c.Request().Context().Err()
+	}
+	return respond(job)
+}
+
+// actionDetails — параметры действия для журнала аудита
+func actionDetails(opts actions.Options) map[string]string {
+	details := make(map[string]string)
+	if opts.Timeout != nil {
+		details["timeout"] = strconv.Itoa(*opts.Timeout)
+	}
+	if opts.Signal != "" {
+		details["signal"] = opts.Signal
+	}
+	if opts.RemoveVolumes {
+		details["volumes"] = "true"
+	}
+	if opts.Force {
+		details["force"] = "true"
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+// recordActionAudit записывает результат действия в журнал аудита
+func recordActionAudit(entry audit.Entry, response actionResponse, err error) {
+	entry.Time = time.Now()
+	entry.Result = audit.ResultSuccess
+	if err != nil {
+		entry.Result = audit.ResultError
+		entry.Error = err.Error()
+		if response.Error != nil {
+			entry.Error = response.Error.Message
+			entry.DockerStatus = response.Error.StatusCode
+		}
+	}
+	audit.Record(entry)
+}
+
+// actionFingerprint описывает запрос для проверки повторного использования ключа идемпотентности
+func actionFingerprint(action, containerID string, opts actions.Options) string {
+	timeout := "default"
+	if opts.Timeout != nil {
+		timeout = strconv.Itoa(*opts.Timeout)
+	}
+	return fmt.Sprintf("%s %s timeout=%s signal=%s volumes=%t force=%t",
+		action, containerID, timeout, opts.Signal, opts.RemoveVolumes, opts.Force)
+}
+
+// lookupJob возвращает задачу текущего пользователя
+func lookupJob(c echo.Context) (jobs.Job, error) {
+	job, ok := jobs.Default().Get(c.Param("id"))
+	if !ok || job.User != jobOwner(auth.UserFromContext(c)) {
+		return jobs.Job{}, echo.NewHTTPError(http.StatusNotFound, "Job not found")
+	}
+	return job, nil
+}
+
+// getJobHandler возвращает состояние задачи (для опроса)
+func getJobHandler(c echo.Context) error {
+	job, err := lookupJob(c)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, job)
+}
+
+// jobWebSocketHandler отправляет состояние задачи при каждом изменении и закрывает соединение после ее завершения
+func jobWebSocketHandler(c echo.Context) error {
+	job, err := lookupJob(c)
+	if err != nil {
+		return err
+	}
+	updates, cancel, ok := jobs.Default().Watch(job.ID)
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "Job not found")
+	}
+	defer cancel()
+
+	conn, err := upgrader.Upgrade(c.Response().Writer, c.Request(), nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return err
+	}
+	defer conn.Close()
+
+	// Канал для обработки закрытия соединения клиентом
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case job, ok := <-updates:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+				return nil
+			}
+			conn.SetWriteDeadline(time.Now().Add(hubWriteTimeout))
+			if err := conn.WriteJSON(job); err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				return nil
+			}
+		}
+	}
+}
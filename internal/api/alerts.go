@@ -0,0 +1,105 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"docker-dashboard/internal/alerts"
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/labstack/echo/v4"
+)
+
+type alertsResponse struct {
+	Alerts []alerts.Alert `json:"alerts"`
+}
+
+type alertTestResponse struct {
+	Results []alerts.TestResult `json:"results"`
+}
+
+// alertsHandler возвращает активные и разрешенные за последние сутки алерты:
+//
+//	GET /api/alerts?state=firing&severity=critical
+//
+// Сначала идут активные алерты, затем разрешенные, внутри — от новых к старым.
+// Алерты контейнеров, которые пользователь не может видеть, пропускаются
+func alertsHandler(c echo.Context) error {
+	state := c.QueryParam("state")
+	if state != "" && state != alerts.StateFiring && state != alerts.StateResolved {
+		return echo.NewHTTPError(http.StatusBadRequest, "state must be firing or resolved")
+	}
+	severity := c.QueryParam("severity")
+
+	manager := alerts.Default()
+	var list []alerts.Alert
+	if state != alerts.StateResolved {
+		list = append(list, manager.Active()...)
+	}
+	if state != alerts.StateFiring {
+		list = append(list, manager.Resolved()...)
+	}
+
+	user := auth.UserFromContext(c)
+	access := newRecordAccess(user)
+	result := make([]alerts.Alert, 0, len(list))
+	for _, alert := range list {
+		if severity != "" && alert.Severity != severity {
+			continue
+		}
+		if alert.Container == "" {
+			// Алерты хоста видны всем, кому доступен просмотр
+			if !rbac.CanAccess(user, rbac.ActionView) {
+				continue
+			}
+		} else if !access.canView("", alert.Container, alert.Project) {
+			continue
+		}
+		result = append(result, alert)
+	}
+	return c.JSON(http.StatusOK, alertsResponse{Alerts: result})
+}
+
+// alertTestHandler отправляет тестовое уведомление в канал из ALERTS_CONFIG_FILE
+// (или во все каналы, если notifier не указан) и возвращает результат каждой отправки:
+//
+//	POST /api/alerts/test?notifier=ops-slack
+//
+// Если хотя бы одна отправка не удалась, ответ — 502
+func alertTestHandler(c echo.Context) error {
+	if !rbac.CanAccess(auth.UserFromContext(c), rbac.ActionAlerts) {
+		return echo.NewHTTPError(http.StatusForbidden, "Not allowed to send test notifications")
+	}
+	name := c.QueryParam("notifier")
+	entry := newAuditEntry(c, string(rbac.ActionAlerts), nil)
+	entry.Details = map[string]string{"test": "true"}
+	if name != "" {
+		entry.Details["notifier"] = name
+	}
+
+	results, err := alerts.Default().Test(c.Request().Context(), name, entry.User)
+	if errors.Is(err, alerts.ErrUnknownNotifier) {
+		return echo.NewHTTPError(http.StatusNotFound, "Notifier not found")
+	}
+	if len(results) == 0 {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "No notifiers configured in ALERTS_CONFIG_FILE")
+	}
+
+	status := http.StatusOK
+	entry.Result = audit.ResultSuccess
+	var failed []string
+	for _, r := range results {
+		if r.Error != "" {
+			failed = append(failed, r.Notifier+": "+r.Error)
+		}
+	}
+	if len(failed) > 0 {
+		status = http.StatusBadGateway
+		entry.Result, entry.Error = audit.ResultError, strings.Join(failed, "; ")
+	}
+	audit.Record(entry)
+	return c.JSON(status, alertTestResponse{Results: results})
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/labstack/echo/v4"
+)
+
+type auditResponse struct {
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to"`
+	Entries []audit.Entry `json:"entries"`
+}
+
+// IPExtractorFromEnv возвращает способ определения IP клиента для журнала аудита.
+// По умолчанию берется адрес TCP соединения: заголовки X-Forwarded-For и X-Real-IP
+// присылает сам клиент, и им нельзя верить. Если дашборд работает за reverse proxy,
+// его адреса перечисляются в TRUSTED_PROXIES (IP или CIDR через запятую), и тогда
+// IP берется из X-Forwarded-For, но только из записей, добавленных этими прокси
+func IPExtractorFromEnv() (echo.IPExtractor, error) {
+	value := strings.TrimSpace(os.Getenv("TRUSTED_PROXIES"))
+	if value == "" {
+		return echo.ExtractIPDirect(), nil
+	}
+	options := []echo.TrustOption{
+		echo.TrustLoopback(false),
+		echo.TrustLinkLocal(false),
+		echo.TrustPrivateNet(false),
+	}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", item)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			item = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q", item)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// newAuditEntry заполняет запись аудита данными запроса: пользователь, IP и контейнер
+func newAuditEntry(c echo.Context, action string, container *containers.Container) audit.Entry {
+	e := audit.Entry{
+		Time:   time.Now(),
+		IP:     c.RealIP(),
+		Action: action,
+	}
+	if user := auth.UserFromContext(c); user != nil {
+		e.User = user.Name
+		e.AuthMethod = user.Method
+	}
+	if container != nil {
+		e.Container = container.Name
+		e.ContainerID = container.FullID
+		e.Project = container.ComposeProject
+	}
+	return e
+}
+
+// auditHandler возвращает записи журнала аудита (только для пользователей с правом audit)
+func auditHandler(c echo.Context) error {
+	if !rbac.CanAccess(auth.UserFromContext(c), rbac.ActionAudit) {
+		return echo.NewHTTPError(http.StatusForbidden, "Not allowed to read the audit log")
+	}
+	auditLog := audit.Default()
+	if auditLog == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Audit log requires AUDIT_LOG_FILE or DATA_DIR")
+	}
+
+	now := time.Now()
+	fromParam := c.QueryParam("from")
+	if fromParam == "" {
+		fromParam = "168h"
+	}
+	from, err := parseQueryTime(fromParam, now)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	to, err := parseQueryTime(c.QueryParam("to"), now)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	limit := 1000
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit")
+		}
+	}
+
+	entries, err := auditLog.Query(audit.Filter{
+		From:      from,
+		To:        to,
+		User:      c.QueryParam("user"),
+		Action:    c.QueryParam("action"),
+		Container: c.QueryParam("container"),
+		Project:   c.QueryParam("project"),
+		Result:    c.QueryParam("result"),
+		Limit:     limit,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read audit log: "+err.Error())
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+	return c.JSON(http.StatusOK, auditResponse{From: from, To: to, Entries: entries})
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/storage"
+
+	"github.com/labstack/echo/v4"
+)
+
+type eventsResponse struct {
+	From   time.Time                   `json:"from"`
+	To     time.Time                   `json:"to"`
+	Events []containers.ContainerEvent `json:"events"`
+}
+
+// eventsHandler возвращает историю событий контейнеров из хранилища на диске
+func eventsHandler(c echo.Context) error {
+	store := storage.Default()
+	if store == nil {
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Event history requires DATA_DIR")
+	}
+
+	now := time.Now()
+	fromParam := c.QueryParam("from")
+	if fromParam == "" {
+		fromParam = "24h"
+	}
+	from, err := parseQueryTime(fromParam, now)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	to, err := parseQueryTime(c.QueryParam("to"), now)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	limit := 1000
+	if limitParam := c.QueryParam("limit"); limitParam != "" {
+		limit, err = strconv.Atoi(limitParam)
+		if err != nil || limit <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid limit")
+		}
+	}
+
+	// Оставляем только события контейнеров, доступных пользователю
+	access := newRecordAccess(auth.UserFromContext(c))
+	events, err := store.QueryEvents(storage.EventFilter{
+		From:      from,
+		To:        to,
+		Container: c.QueryParam("container"),
+		Project:   c.QueryParam("project"),
+		Action:    c.QueryParam("action"),
+		Visible: func(ev containers.ContainerEvent) bool {
+			return access.canView(ev.ID, ev.Name, ev.ComposeProject)
+		},
+		Limit: limit,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to read events: "+err.Error())
+	}
+	if events == nil {
+		events = []containers.ContainerEvent{}
+	}
+	return c.JSON(http.StatusOK, eventsResponse{From: from, To: to, Events: events})
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+// Команда терминала по умолчанию
+var defaultExecCmd = []string{"/bin/sh"}
+
+const (
+	// Максимальный размер сообщения от клиента терминала
+	execMaxMessageSize = 64 * 1024
+	// Размер буфера вывода терминала
+	execOutputBufferSize = 32 * 1024
+	// Максимальный размер терминала в строках и столбцах
+	execMaxTerminalSize = 1000
+)
+
+// execControlMessage — текстовое сообщение клиента терминала:
+//
+//	{"type": "input", "data": "ls -la\r"}
+//	{"type": "resize", "cols": 120, "rows": 40}
+//
+// Бинарные сообщения передаются в stdin как есть
+type execControlMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data,omitempty"`
+	Cols int    `json:"cols,omitempty"`
+	Rows int    `json:"rows,omitempty"`
+}
+
+// execServerMessage — служебное сообщение сервера; вывод терминала идет бинарными сообщениями
+type execServerMessage struct {
+	Type     string `json:"type"` // exit или error
+	ExitCode *int   `json:"exit_code,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// parseExecCmd возвращает команду из параметров cmd: один параметр делится по пробелам
+// (cmd=bash -l), несколько передаются как аргументы (cmd=bash&cmd=-l)
+func parseExecCmd(values []string) []string {
+	switch len(values) {
+	case 0:
+		return defaultExecCmd
+	case 1:
+		if fields := strings.Fields(values[0]); len(fields) > 0 {
+			return fields
+		}
+		return defaultExecCmd
+	}
+	return values
+}
+
+// parseTerminalSize читает начальный размер терминала из query (cols, rows); 0 — не задан
+func parseTerminalSize(c echo.Context) (int, int, error) {
+	var size [2]int
+	for i, name := range []string{"cols", "rows"} {
+		v := c.QueryParam(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > execMaxTerminalSize {
+			return 0, 0, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name)
+		}
+		size[i] = n
+	}
+	return size[0], size[1], nil
+}
+
+// containerExecWebSocketHandler открывает интерактивный терминал в контейнере:
+//
+//	WS /ws/containers/:id/exec?cmd=/bin/sh&user=&workdir=&cols=80&rows=24
+//
+// Создает exec с TTY, перехватывает соединение с Docker и передает ввод и вывод через WebSocket.
+// Требует право exec (CONTAINER_EXEC=true); каждое открытие терминала записывается в журнал аудита
+func containerExecWebSocketHandler(c echo.Context) error {
+	container, err := authorizeContainer(c, c.Param("id"), rbac.ActionExec)
+	if err != nil {
+		return err
+	}
+	if container.State != "running" {
+		return echo.NewHTTPError(http.StatusConflict, "Container is not running")
+	}
+	cols, rows, err := parseTerminalSize(c)
+	if err != nil {
+		return err
+	}
+	cfg := containers.ExecConfig{
+		Cmd:        parseExecCmd(c.QueryParams()["cmd"]),
+		User:       c.QueryParam("user"),
+		WorkingDir: c.QueryParam("workdir"),
+	}
+
+	conn, err := upgrader.Upgrade(c.Response().Writer, c.Request(), nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return err
+	}
+	defer conn.Close()
+	conn.SetReadLimit(execMaxMessageSize)
+
+	entry := newAuditEntry(c, string(rbac.ActionExec), container)
+	entry.Details = map[string]string{"cmd": strings.Join(cfg.Cmd, " ")}
+	if cfg.User != "" {
+		entry.Details["user"] = cfg.User
+	}
+	if cfg.WorkingDir != "" {
+		entry.Details["workdir"] = cfg.WorkingDir
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	session, err := startExecSession(ctx, container.FullID, cfg)
+	cancel()
+	if err != nil {
+		log.Printf("[docker-dashboard] Exec in %s failed: %v", container.Name, err)
+		entry.Result, entry.Error = audit.ResultError, err.Error()
+		audit.Record(entry)
+		conn.WriteJSON(execServerMessage{Type: "error", Error: "Failed to start exec: " + err.Error()})
+		return nil
+	}
+	defer session.Close()
+	entry.Result = audit.ResultSuccess
+	entry.Details["exec_id"] = session.ID
+	audit.Record(entry)
+
+	started := time.Now()
+	log.Printf("[docker-dashboard] Exec session %s started in %s: %s", shortExecID(session.ID), container.Name, entry.Details["cmd"])
+
+	if cols > 0 && rows > 0 {
+		resizeExec(session.ID, cols, rows)
+	}
+
+	// Ввод: сообщения клиента в stdin процесса
+	inputDone := make(chan struct{})
+	go func() {
+		defer close(inputDone)
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if messageType == websocket.BinaryMessage {
+				if _, err := session.Write(data); err != nil {
+					return
+				}
+				continue
+			}
+			var msg execControlMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				continue
+			}
+			switch msg.Type {
+			case "input":
+				if _, err := session.Write([]byte(msg.Data)); err != nil {
+					return
+				}
+			case "resize":
+				if msg.Cols > 0 && msg.Rows > 0 && msg.Cols <= execMaxTerminalSize && msg.Rows <= execMaxTerminalSize {
+					resizeExec(session.ID, msg.Cols, msg.Rows)
+				}
+			}
+		}
+	}()
+
+	// Вывод: поток терминала клиенту бинарными сообщениями
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		buf := make([]byte, execOutputBufferSize)
+		for {
+			n, err := session.Read(buf)
+			if n > 0 {
+				conn.SetWriteDeadline(time.Now().Add(hubWriteTimeout))
+				if werr := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case <-inputDone:
+		// Клиент отключился: закрываем stdin, процесс завершится сам
+		session.Close()
+		<-outputDone
+	case <-outputDone:
+		// Процесс завершился: сообщаем код выхода
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		code, exited, err := containers.ExecExitCode(ctx, session.ID)
+		cancel()
+		msg := execServerMessage{Type: "exit"}
+		if err == nil && exited {
+			msg.ExitCode = &code
+		}
+		conn.SetWriteDeadline(time.Now().Add(hubWriteTimeout))
+		conn.WriteJSON(msg)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	}
+	log.Printf("[docker-dashboard] Exec session %s in %s finished after %s",
+		shortExecID(session.ID), container.Name, time.Since(started).Round(time.Second))
+	return nil
+}
+
+// startExecSession создает exec и подключается к нему
+func startExecSession(ctx context.Context, containerID string, cfg containers.ExecConfig) (*containers.ExecSession, error) {
+	execID, err := containers.CreateExec(ctx, containerID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return containers.StartExec(ctx, execID)
+}
+
+// resizeExec меняет размер терминала; ошибки только логируются
+func resizeExec(execID string, cols, rows int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := containers.ResizeExec(ctx, execID, cols, rows); err != nil {
+		log.Printf("[docker-dashboard] Exec resize failed: %v", err)
+	}
+}
+
+func shortExecID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// Размер буфера исходящих сообщений одного клиента
+	hubClientBuffer = 4
+	// Максимальное время записи одного сообщения в WebSocket
+	hubWriteTimeout = 10 * time.Second
+)
+
+// hub — общий сборщик данных для одного потока WebSocket.
+// Данные собираются один раз за интервал и рассылаются всем подписчикам.
+// Сборщик работает только пока есть хотя бы один подписчик
+type hub struct {
+	name     string
+	interval time.Duration
+	collect  func() (interface{}, error)
+
+	mu      sync.Mutex
+	clients map[*hubClient]struct{}
+	last    interface{}
+	stop    chan struct{}
+}
+
+// hubClient — подписчик хаба со своим буфером отправки.
+// transform (если задан) готовит данные для конкретного клиента, например
+// оставляет только контейнеры, доступные пользователю
+type hubClient struct {
+	send      chan []byte
+	transform func(interface{}) interface{}
+}
+
+func newHub(name string, interval time.Duration, collect func() (interface{}, error)) *hub {
+	return &hub{
+		name:     name,
+		interval: interval,
+		collect:  collect,
+		clients:  make(map[*hubClient]struct{}),
+	}
+}
+
+// subscribe регистрирует нового клиента и сразу отдает ему последний снимок
+func (h *hub) subscribe(transform func(interface{}) interface{}) *hubClient {
+	client := &hubClient{send: make(chan []byte, hubClientBuffer), transform: transform}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[client] = struct{}{}
+	if h.last != nil {
+		if msg, err := h.encode(client, h.last, nil); err == nil {
+			client.send <- msg
+		}
+	}
+	if h.stop == nil {
+		h.stop = make(chan struct{})
+		go h.run(h.stop)
+	}
+	return client
+}
+
+// unsubscribe удаляет клиента; при уходе последнего клиента сборщик останавливается
+func (h *hub) unsubscribe(client *hubClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[client]; ok {
+		delete(h.clients, client)
+		close(client.send)
+	}
+	if len(h.clients) == 0 && h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+		h.last = nil
+	}
+}
+
+func (h *hub) run(stop chan struct{}) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	h.collectAndBroadcast(stop)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			h.collectAndBroadcast(stop)
+		}
+	}
+}
+
+func (h *hub) collectAndBroadcast(stop chan struct{}) {
+	data, err := h.collect()
+	if err != nil {
+		log.Printf("Failed to get %s: %v", h.name, err)
+		return
+	}
+	h.broadcast(stop, data)
+}
+
+// encode сериализует данные для клиента. Сообщение без transform
+// сериализуется один раз и переиспользуется через shared
+func (h *hub) encode(client *hubClient, data interface{}, shared *[]byte) ([]byte, error) {
+	if client.transform != nil {
+		return json.Marshal(client.transform(data))
+	}
+	if shared != nil && *shared != nil {
+		return *shared, nil
+	}
+	msg, err := json.Marshal(data)
+	if err == nil && shared != nil {
+		*shared = msg
+	}
+	return msg, err
+}
+
+// broadcast рассылает данные всем клиентам.
+// stop — канал остановки сборщика, собравшего данные: если сборщик уже остановлен
+// (последний клиент ушел во время сбора), данные устарели и не сохраняются.
+// Медленные клиенты с заполненным буфером отключаются, чтобы не блокировать сборщик
+func (h *hub) broadcast(stop chan struct{}, data interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stop != stop {
+		return
+	}
+	h.last = data
+	var shared []byte
+	for client := range h.clients {
+		msg, err := h.encode(client, data, &shared)
+		if err != nil {
+			log.Printf("Failed to marshal %s: %v", h.name, err)
+			continue
+		}
+		select {
+		case client.send <- msg:
+		default:
+			log.Printf("WebSocket %s: dropping slow client", h.name)
+			delete(h.clients, client)
+			close(client.send)
+		}
+	}
+}
+
+// serveHub подключает WebSocket клиента к хабу и пересылает ему снимки.
+// transform может быть nil, тогда все клиенты получают одинаковые данные
+func serveHub(c echo.Context, h *hub, transform func(interface{}) interface{}) error {
+	conn, err := upgrader.Upgrade(c.Response().Writer, c.Request(), nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return err
+	}
+	defer conn.Close()
+
+	// Канал для обработки закрытия соединения клиентом
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	client := h.subscribe(transform)
+	defer h.unsubscribe(client)
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case msg, ok := <-client.send:
+			if !ok {
+				// Клиент отключен хабом как медленный
+				return nil
+			}
+			conn.SetWriteDeadline(time.Now().Add(hubWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				return nil
+			}
+		}
+	}
+}
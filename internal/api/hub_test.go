@@ -0,0 +1,39 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHubDropsSnapshotCollectedBeforeStop(t *testing.T) {
+	h := newHub("test", time.Hour, func() (interface{}, error) { return "fresh", nil })
+	// Горутина сборщика не запускается: тест сам вызывает broadcast вместо run
+	stopped := make(chan struct{})
+	client := &hubClient{send: make(chan []byte, hubClientBuffer)}
+	h.clients[client] = struct{}{}
+	h.stop = stopped
+
+	// Последний клиент уходит, пока сборщик собирает данные
+	h.unsubscribe(client)
+	h.broadcast(stopped, "stale")
+	if h.last != nil {
+		t.Fatalf("last = %v after stop, want nil", h.last)
+	}
+
+	// Новый сборщик: его данные сохраняются, а данные старого по-прежнему нет
+	running := make(chan struct{})
+	next := &hubClient{send: make(chan []byte, hubClientBuffer)}
+	h.clients[next] = struct{}{}
+	h.stop = running
+	h.broadcast(stopped, "stale")
+	if h.last != nil || len(next.send) != 0 {
+		t.Fatalf("stale snapshot was delivered: last = %v", h.last)
+	}
+	h.broadcast(running, "fresh")
+	if h.last != "fresh" {
+		t.Errorf("last = %v, want fresh", h.last)
+	}
+	if msg := <-next.send; string(msg) != `"fresh"` {
+		t.Errorf("client got %s, want \"fresh\"", msg)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/logstream"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Символы, недопустимые в имени скачиваемого файла
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// containerLogDownloadHandler отдает логи контейнера файлом:
+//
+//	GET /api/containers/:id/logs/download?since=24h&until=&stdout=true&stderr=true&timestamps=true&gzip=true
+//
+// Параметры — как у /ws/containers/:id/logs, по умолчанию все логи (tail=all).
+// Логи передаются потоком по мере чтения из Docker, без загрузки в память целиком
+func containerLogDownloadHandler(c echo.Context) error {
+	container, err := authorizeContainer(c, c.Param("id"), rbac.ActionLogs)
+	if err != nil {
+		return err
+	}
+	opts, err := parseLogOptions(c)
+	if err != nil {
+		return err
+	}
+	if c.QueryParam("tail") == "" {
+		opts.Tail = "all"
+	}
+	// В файл пишется только текст: строки разбираются, лишь если заданы условия filter
+	opts.Parse = false
+	compress := false
+	if v := c.QueryParam("gzip"); v != "" {
+		if compress, err = strconv.ParseBool(v); err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid gzip")
+		}
+	}
+
+	entry := newAuditEntry(c, string(rbac.ActionLogs), container)
+	entry.Details = map[string]string{"download": "true"}
+
+	ctx := c.Request().Context()
+	reader, body, err := openLogs(ctx, container, opts, false)
+	if err != nil {
+		entry.Result, entry.Error = audit.ResultError, err.Error()
+		var statusErr *logStatusError
+		if errors.As(err, &statusErr) {
+			entry.DockerStatus = statusErr.StatusCode
+		}
+		audit.Record(entry)
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to get logs: "+err.Error())
+	}
+	defer body.Close()
+	entry.Result = audit.ResultSuccess
+	audit.Record(entry)
+
+	filename := unsafeFilenameChars.ReplaceAllString(container.Name, "_") + "-" + time.Now().UTC().Format("20060102-150405") + ".log"
+	header := c.Response().Header()
+	if compress {
+		filename += ".gz"
+		header.Set(echo.HeaderContentType, "application/gzip")
+	} else {
+		header.Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+	}
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	c.Response().WriteHeader(http.StatusOK)
+
+	var out io.Writer = c.Response()
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(out)
+		out = gz
+	}
+	buffered := bufio.NewWriterSize(out, 32*1024)
+	if err := writeLogLines(buffered, reader, opts); err != nil && ctx.Err() == nil {
+		// Заголовки уже отправлены: файл просто обрывается, ошибка только в логе процесса
+		log.Printf("[docker-dashboard] Log download of %s failed: %v", container.Name, err)
+	}
+	buffered.Flush()
+	if gz != nil {
+		gz.Close()
+	}
+	return nil
+}
+
+// writeLogLines пишет строки лога в текстовом виде, как docker logs
+func writeLogLines(w *bufio.Writer, reader *logstream.Reader, opts logOptions) error {
+	for {
+		line, err := reader.Next()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if _, ok := opts.match(line); !ok {
+			continue
+		}
+		if opts.Timestamps && !line.Timestamp.IsZero() {
+			w.WriteString(logstream.FormatTimestamp(line.Timestamp))
+			w.WriteByte(' ')
+		}
+		w.WriteString(line.Text)
+		if err := w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+}
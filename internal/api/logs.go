@@ -0,0 +1,315 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/logstream"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Число последних строк лога по умолчанию
+const defaultLogTail = "100"
+
+// logOptions — параметры запроса логов контейнера
+type logOptions struct {
+	Tail       string    // число строк или "all"
+	Since      time.Time // нулевое — без ограничения
+	Until      time.Time
+	Timestamps bool // оставлять метку времени в тексте строки
+	Stdout     bool
+	Stderr     bool
+	// Filter — фильтр строк на стороне сервера (q, regex, ignore_case, invert); nil — все строки
+	Filter *logstream.Matcher
+	// Parse — разбирать JSON и logfmt строки и передавать их в поле structured
+	Parse bool
+	// Fields — поля structured, которые передаются клиенту (пусто — все)
+	Fields []string
+	// Where — условия на поля структурированных строк (level>=warn, request_id=abc), все должны выполняться
+	Where []logstream.Condition
+}
+
+// logMessage — строка лога в WebSocket потоке
+type logMessage struct {
+	Log        string                `json:"log"`
+	Stream     int                   `json:"stream"` // 1 — stdout, 2 — stderr
+	Timestamp  *time.Time            `json:"timestamp,omitempty"`
+	Structured *logstream.Structured `json:"structured,omitempty"`
+}
+
+// parseLogTime разбирает момент времени: RFC3339, unix время в секундах
+// или длительность назад от текущего момента (15m, 2h, 7d)
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Unix(0, int64(seconds*float64(time.Second))), nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.Add(-time.Duration(n) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("expected RFC3339 time, unix timestamp or duration like 15m")
+}
+
+// parseLogOptions читает параметры логов из query:
+// tail (число или all), since, until, timestamps, stdout, stderr
+// и фильтр строк: q (подстрока или регулярное выражение при regex=true), ignore_case, invert.
+// Разбор структурированных строк: parse (по умолчанию true), fields=a,b, filter=level>=warn (можно несколько)
+func parseLogOptions(c echo.Context) (logOptions, error) {
+	opts := logOptions{Tail: defaultLogTail, Stdout: true, Stderr: true, Parse: true}
+	if v := c.QueryParam("tail"); v != "" {
+		if v != "all" {
+			if n, err := strconv.Atoi(v); err != nil || n < 0 {
+				return opts, echo.NewHTTPError(http.StatusBadRequest, "tail must be a non-negative number or all")
+			}
+		}
+		opts.Tail = v
+	}
+	now := time.Now()
+	for name, target := range map[string]*time.Time{"since": &opts.Since, "until": &opts.Until} {
+		if v := c.QueryParam(name); v != "" {
+			t, err := parseLogTime(v, now)
+			if err != nil {
+				return opts, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name+": "+err.Error())
+			}
+			*target = t
+		}
+	}
+	if !opts.Since.IsZero() && !opts.Until.IsZero() && !opts.Since.Before(opts.Until) {
+		return opts, echo.NewHTTPError(http.StatusBadRequest, "since must be before until")
+	}
+	var regex, ignoreCase, invert bool
+	flags := map[string]*bool{
+		"timestamps": &opts.Timestamps, "stdout": &opts.Stdout, "stderr": &opts.Stderr,
+		"regex": &regex, "ignore_case": &ignoreCase, "invert": &invert, "parse": &opts.Parse,
+	}
+	for name, target := range flags {
+		if v := c.QueryParam(name); v != "" {
+			value, err := strconv.ParseBool(v)
+			if err != nil {
+				return opts, echo.NewHTTPError(http.StatusBadRequest, "invalid "+name)
+			}
+			*target = value
+		}
+	}
+	if !opts.Stdout && !opts.Stderr {
+		return opts, echo.NewHTTPError(http.StatusBadRequest, "at least one of stdout and stderr must be enabled")
+	}
+	filter, err := logstream.NewMatcher(c.QueryParam("q"), regex, ignoreCase, invert)
+	if err != nil {
+		return opts, echo.NewHTTPError(http.StatusBadRequest, "invalid q: "+err.Error())
+	}
+	opts.Filter = filter
+	for _, expr := range c.QueryParams()["filter"] {
+		cond, err := logstream.ParseCondition(expr)
+		if err != nil {
+			return opts, echo.NewHTTPError(http.StatusBadRequest, "invalid filter: "+err.Error())
+		}
+		opts.Where = append(opts.Where, cond)
+	}
+	if v := c.QueryParam("fields"); v != "" {
+		for _, field := range strings.Split(v, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				opts.Fields = append(opts.Fields, field)
+			}
+		}
+	}
+	return opts, nil
+}
+
+// match проверяет строку фильтрами q и filter. Возвращает разобранную строку,
+// если разбор понадобился (parse=true или есть условия filter)
+func (o logOptions) match(line logstream.Line) (*logstream.Structured, bool) {
+	if !o.Filter.Match(line.Text) {
+		return nil, false
+	}
+	if !o.Parse && len(o.Where) == 0 {
+		return nil, true
+	}
+	structured := logstream.Parse(line.Text)
+	for _, cond := range o.Where {
+		if !cond.Match(structured) {
+			return structured, false
+		}
+	}
+	return structured, true
+}
+
+// message формирует сообщение для клиента; false — строка не прошла фильтры
+func (o logOptions) message(line logstream.Line) (logMessage, bool) {
+	structured, ok := o.match(line)
+	msg := newLogMessage(line, o.Timestamps)
+	if o.Parse {
+		msg.Structured = structured.Select(o.Fields)
+	}
+	return msg, ok
+}
+
+// dockerTime форматирует время для параметров since и until Docker API
+func dockerTime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// query возвращает параметры запроса /containers/{id}/logs.
+// Метки времени запрашиваются всегда: из них берется поле timestamp сообщения
+func (o logOptions) query(follow bool) string {
+	query := url.Values{}
+	query.Set("follow", strconv.FormatBool(follow))
+	query.Set("stdout", strconv.FormatBool(o.Stdout))
+	query.Set("stderr", strconv.FormatBool(o.Stderr))
+	query.Set("tail", o.Tail)
+	query.Set("timestamps", "true")
+	if !o.Since.IsZero() {
+		query.Set("since", dockerTime(o.Since))
+	}
+	if !o.Until.IsZero() {
+		query.Set("until", dockerTime(o.Until))
+	}
+	return query.Encode()
+}
+
+// newLogMessage формирует сообщение из строки лога; с keepTimestamp метка времени
+// остается и в начале текста, как в docker logs --timestamps
+func newLogMessage(line logstream.Line, keepTimestamp bool) logMessage {
+	msg := logMessage{Log: line.Text, Stream: line.Stream}
+	if !line.Timestamp.IsZero() {
+		ts := line.Timestamp
+		msg.Timestamp = &ts
+		if keepTimestamp {
+			msg.Log = logstream.FormatTimestamp(ts) + " " + line.Text
+		}
+	}
+	return msg
+}
+
+// logStatusError — Docker ответил на запрос логов ошибкой
+type logStatusError struct {
+	StatusCode int
+}
+
+func (e *logStatusError) Error() string {
+	return "Docker API returned status " + strconv.Itoa(e.StatusCode)
+}
+
+// openLogs запрашивает логи контейнера у Docker и возвращает построчный reader;
+// body закрывает вызывающий. Запрос живет, пока не отменен ctx
+func openLogs(ctx context.Context, container *containers.Container, opts logOptions, follow bool) (*logstream.Reader, io.Closer, error) {
+	logsURL, err := containers.DockerURL("/containers/" + container.FullID + "/logs?" + opts.query(follow))
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Printf("[docker-dashboard] GET %s", logsURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logsURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := containers.DockerStreamClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, &logStatusError{StatusCode: resp.StatusCode}
+	}
+	return logstream.NewReader(resp.Body, container.Tty, true), resp.Body, nil
+}
+
+// containerLogsWebSocketHandler передает логи контейнера в реальном времени:
+//
+//	WS /ws/containers/:id/logs?tail=100&since=15m&until=&timestamps=false&stdout=true&stderr=true&q=error&ignore_case=true&filter=level>=warn
+//
+// Строки, не прошедшие фильтры q и filter, не отправляются
+func containerLogsWebSocketHandler(c echo.Context) error {
+	container, err := authorizeContainer(c, c.Param("id"), rbac.ActionLogs)
+	if err != nil {
+		return err
+	}
+	opts, err := parseLogOptions(c)
+	if err != nil {
+		return err
+	}
+	w := c.Response().Writer
+	r := c.Request()
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return err
+	}
+	defer conn.Close()
+
+	// Канал для обработки закрытия соединения клиентом
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Запрос к Docker отменяется при закрытии WebSocket
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-done
+		cancel()
+	}()
+
+	// В журнал аудита попадает каждое открытие потока логов
+	entry := newAuditEntry(c, string(rbac.ActionLogs), container)
+	entry.Result = audit.ResultSuccess
+
+	reader, body, err := openLogs(ctx, container, opts, true)
+	if err != nil {
+		log.Printf("Failed to get logs: %v", err)
+		entry.Result, entry.Error = audit.ResultError, err.Error()
+		var statusErr *logStatusError
+		if errors.As(err, &statusErr) {
+			entry.DockerStatus = statusErr.StatusCode
+		}
+		audit.Record(entry)
+		conn.WriteJSON(map[string]string{"error": "Failed to get logs: " + err.Error()})
+		return nil
+	}
+	defer body.Close()
+	audit.Record(entry)
+
+	for {
+		line, err := reader.Next()
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				log.Printf("Error reading logs of %s: %v", container.Name, err)
+				conn.WriteJSON(map[string]string{"error": "Failed to read logs: " + err.Error()})
+			}
+			return nil
+		}
+		msg, ok := opts.message(line)
+		if !ok {
+			continue
+		}
+		if err := conn.WriteJSON(msg); err != nil {
+			log.Printf("WebSocket write error: %v", err)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/logstream"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultSearchContext = 3
+	maxSearchContext     = 50
+	defaultSearchLimit   = 100
+	maxSearchLimit       = 1000
+	// Максимальное время просмотра логов одним запросом поиска
+	searchTimeout = 60 * time.Second
+)
+
+// logSearchMatch — найденная строка с контекстом
+type logSearchMatch struct {
+	logMessage
+	Line   int          `json:"line"` // номер строки в просмотренном диапазоне, с 1
+	Before []logMessage `json:"before"`
+	After  []logMessage `json:"after"`
+}
+
+// logSearchResponse — результат поиска по логам контейнера
+type logSearchResponse struct {
+	Container string           `json:"container"`
+	Matches   []logSearchMatch `json:"matches"`
+	Scanned   int              `json:"scanned"` // просмотрено строк
+	// Truncated — просмотр остановлен досрочно: совпадений больше limit или чтение прервано
+	Truncated bool `json:"truncated"`
+}
+
+// parseBoundedInt читает целый параметр query в пределах [0, max]
+func parseBoundedInt(c echo.Context, name string, def, max int) (int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > max {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, name+" must be between 0 and "+strconv.Itoa(max))
+	}
+	return n, nil
+}
+
+// containerLogSearchHandler ищет строки в логах контейнера за период:
+//
+//	GET /api/containers/:id/logs/search?q=timeout&regex=false&ignore_case=true&filter=level>=warn&since=24h&context=3&limit=100
+//
+// Параметры периода и потоков — как у /ws/containers/:id/logs, по умолчанию просматриваются все логи
+// (tail=all). Для каждого совпадения возвращаются context строк до и после
+func containerLogSearchHandler(c echo.Context) error {
+	container, err := authorizeContainer(c, c.Param("id"), rbac.ActionLogs)
+	if err != nil {
+		return err
+	}
+	if c.QueryParam("q") == "" && c.QueryParam("filter") == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q or filter is required")
+	}
+	opts, err := parseLogOptions(c)
+	if err != nil {
+		return err
+	}
+	if c.QueryParam("tail") == "" {
+		opts.Tail = "all"
+	}
+	contextLines, err := parseBoundedInt(c, "context", defaultSearchContext, maxSearchContext)
+	if err != nil {
+		return err
+	}
+	limit, err := parseBoundedInt(c, "limit", defaultSearchLimit, maxSearchLimit)
+	if err != nil {
+		return err
+	}
+	if limit == 0 {
+		limit = defaultSearchLimit
+	}
+
+	entry := newAuditEntry(c, string(rbac.ActionLogs), container)
+	entry.Details = map[string]string{"search": c.QueryParam("q")}
+	if where := c.QueryParams()["filter"]; len(where) > 0 {
+		entry.Details["filter"] = strings.Join(where, " ")
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request().Context(), searchTimeout)
+	defer cancel()
+	reader, body, err := openLogs(ctx, container, opts, false)
+	if err != nil {
+		entry.Result, entry.Error = audit.ResultError, err.Error()
+		var statusErr *logStatusError
+		if errors.As(err, &statusErr) {
+			entry.DockerStatus = statusErr.StatusCode
+		}
+		audit.Record(entry)
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to get logs: "+err.Error())
+	}
+	defer body.Close()
+	entry.Result = audit.ResultSuccess
+	audit.Record(entry)
+
+	response, err := searchLines(reader, opts, contextLines, limit)
+	if err != nil && ctx.Err() == nil {
+		log.Printf("[docker-dashboard] Error reading logs of %s: %v", container.Name, err)
+	}
+	response.Container = container.Name
+	return c.JSON(http.StatusOK, response)
+}
+
+// searchLines читает строки до конца потока и собирает совпадения с контекстом.
+// После limit совпадений новые не принимаются (Truncated), но чтение продолжается,
+// пока найденные совпадения не получат свои строки после.
+// Ошибка чтения (в том числе по таймауту) возвращается вместе с найденным до нее, с Truncated
+func searchLines(reader *logstream.Reader, opts logOptions, contextLines, limit int) (logSearchResponse, error) {
+	response := logSearchResponse{Matches: []logSearchMatch{}}
+	// before — последние строки перед текущей; open — совпадения, ожидающие строки после себя
+	before := make([]logMessage, 0, contextLines)
+	var open []int
+	for {
+		line, err := reader.Next()
+		if err != nil {
+			if err != io.EOF {
+				response.Truncated = true
+				return response, err
+			}
+			return response, nil
+		}
+		response.Scanned++
+		msg, matched := opts.message(line)
+
+		for i := 0; i < len(open); {
+			match := &response.Matches[open[i]]
+			match.After = append(match.After, msg)
+			if len(match.After) >= contextLines {
+				open = append(open[:i], open[i+1:]...)
+				continue
+			}
+			i++
+		}
+
+		if matched && len(response.Matches) == limit {
+			response.Truncated = true
+		} else if matched {
+			response.Matches = append(response.Matches, logSearchMatch{
+				logMessage: msg,
+				Line:       response.Scanned,
+				Before:     append([]logMessage{}, before...),
+				After:      []logMessage{},
+			})
+			if contextLines > 0 {
+				open = append(open, len(response.Matches)-1)
+			}
+		}
+		if response.Truncated && len(open) == 0 {
+			return response, nil
+		}
+
+		if contextLines > 0 {
+			if len(before) == contextLines {
+				before = append(before[:0], before[1:]...)
+			}
+			before = append(before, msg)
+		}
+	}
+}
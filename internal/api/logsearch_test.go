@@ -0,0 +1,193 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"docker-dashboard/internal/logstream"
+
+	"github.com/labstack/echo/v4"
+)
+
+// searchText ищет строки в тексте лога контейнера с TTY (без кадров и меток времени)
+func searchText(t *testing.T, text, q string, contextLines, limit int) logSearchResponse {
+	t.Helper()
+	filter, err := logstream.NewMatcher(q, false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := logstream.NewReader(strings.NewReader(text), true, false)
+	response, err := searchLines(reader, logOptions{Filter: filter}, contextLines, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func logTexts(messages []logMessage) string {
+	texts := make([]string, len(messages))
+	for i, msg := range messages {
+		texts[i] = msg.Log
+	}
+	return strings.Join(texts, ",")
+}
+
+func TestSearchContext(t *testing.T) {
+	response := searchText(t, "a\nb\nc\nERROR 1\nd\ne\nf\ng\n", "ERROR", 2, 10)
+	if response.Scanned != 8 || response.Truncated {
+		t.Fatalf("scanned = %d, truncated = %v; want 8, false", response.Scanned, response.Truncated)
+	}
+	if len(response.Matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(response.Matches))
+	}
+	match := response.Matches[0]
+	if match.Log != "ERROR 1" || match.Line != 4 {
+		t.Errorf("match = %q at line %d, want ERROR 1 at line 4", match.Log, match.Line)
+	}
+	if got := logTexts(match.Before); got != "b,c" {
+		t.Errorf("before = %s, want b,c", got)
+	}
+	if got := logTexts(match.After); got != "d,e" {
+		t.Errorf("after = %s, want d,e", got)
+	}
+}
+
+func TestSearchContextAtStreamEdges(t *testing.T) {
+	response := searchText(t, "ERROR first\na\nERROR last\n", "ERROR", 3, 10)
+	if len(response.Matches) != 2 {
+		t.Fatalf("got %d matches, want 2", len(response.Matches))
+	}
+	first, last := response.Matches[0], response.Matches[1]
+	if got := logTexts(first.Before); got != "" {
+		t.Errorf("first match before = %s, want nothing", got)
+	}
+	if got := logTexts(first.After); got != "a,ERROR last" {
+		t.Errorf("first match after = %s, want a,ERROR last", got)
+	}
+	if got := logTexts(last.After); got != "" {
+		t.Errorf("last match after = %s, want nothing", got)
+	}
+}
+
+func TestSearchOverlappingMatches(t *testing.T) {
+	// Совпадения ближе context друг к другу попадают в контекст соседних
+	response := searchText(t, "a\nERROR 1\nERROR 2\nb\nc\n", "ERROR", 2, 10)
+	if len(response.Matches) != 2 {
+		t.Fatalf("got %d matches, want 2", len(response.Matches))
+	}
+	first, second := response.Matches[0], response.Matches[1]
+	if got := logTexts(first.Before); got != "a" {
+		t.Errorf("first before = %s, want a", got)
+	}
+	if got := logTexts(first.After); got != "ERROR 2,b" {
+		t.Errorf("first after = %s, want ERROR 2,b", got)
+	}
+	if got := logTexts(second.Before); got != "a,ERROR 1" {
+		t.Errorf("second before = %s, want a,ERROR 1", got)
+	}
+	if got := logTexts(second.After); got != "b,c" {
+		t.Errorf("second after = %s, want b,c", got)
+	}
+}
+
+func TestSearchWithoutContext(t *testing.T) {
+	response := searchText(t, "a\nERROR\nb\n", "ERROR", 0, 10)
+	if len(response.Matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(response.Matches))
+	}
+	if len(response.Matches[0].Before) != 0 || len(response.Matches[0].After) != 0 {
+		t.Errorf("match has context: %+v", response.Matches[0])
+	}
+}
+
+func TestSearchLimit(t *testing.T) {
+	response := searchText(t, "ERROR 1\nERROR 2\na\nERROR 3\nb\n", "ERROR", 1, 2)
+	if !response.Truncated {
+		t.Error("truncated = false, want true")
+	}
+	if len(response.Matches) != 2 {
+		t.Fatalf("got %d matches, want 2", len(response.Matches))
+	}
+	// Просмотр останавливается на третьем совпадении
+	if response.Scanned != 4 {
+		t.Errorf("scanned = %d, want 4", response.Scanned)
+	}
+	if got := logTexts(response.Matches[1].After); got != "a" {
+		t.Errorf("second match after = %s, want a", got)
+	}
+}
+
+func TestSearchLimitKeepsAfterContext(t *testing.T) {
+	// Лимит достигнут на ERROR 2, но ERROR 1 еще ждет строки после себя
+	response := searchText(t, "ERROR 1\nERROR 2\na\nb\nERROR 3\n", "ERROR", 2, 1)
+	if !response.Truncated || len(response.Matches) != 1 {
+		t.Fatalf("truncated = %v, matches = %d; want true, 1", response.Truncated, len(response.Matches))
+	}
+	if got := logTexts(response.Matches[0].After); got != "ERROR 2,a" {
+		t.Errorf("after = %s, want ERROR 2,a", got)
+	}
+	// Чтение останавливается, как только контекст собран
+	if response.Scanned != 3 {
+		t.Errorf("scanned = %d, want 3", response.Scanned)
+	}
+
+	// Поток закончился раньше, чем набрался контекст
+	response = searchText(t, "a\nERROR 1\nERROR 2\n", "ERROR", 3, 1)
+	if !response.Truncated || len(response.Matches) != 1 || logTexts(response.Matches[0].After) != "ERROR 2" {
+		t.Errorf("response = %+v, want truncated with after ERROR 2", response)
+	}
+}
+
+func TestSearchLimitNotReached(t *testing.T) {
+	response := searchText(t, "ERROR 1\nERROR 2\n", "ERROR", 0, 2)
+	if response.Truncated || len(response.Matches) != 2 {
+		t.Errorf("truncated = %v, matches = %d; want false, 2", response.Truncated, len(response.Matches))
+	}
+}
+
+func TestSearchReadError(t *testing.T) {
+	filter, _ := logstream.NewMatcher("ERROR", false, false, false)
+	failing := iotest.TimeoutReader(strings.NewReader("ERROR 1\n"))
+	reader := logstream.NewReader(iotest.OneByteReader(failing), true, false)
+	response, err := searchLines(reader, logOptions{Filter: filter}, 0, 10)
+	if !errors.Is(err, iotest.ErrTimeout) {
+		t.Fatalf("err = %v, want timeout", err)
+	}
+	if !response.Truncated {
+		t.Error("truncated = false, want true")
+	}
+}
+
+func TestParseLogOptionsInvalidRegex(t *testing.T) {
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"q=a(b&regex=true", http.StatusBadRequest},
+		{"q=a(b", 0}, // без regex скобка — обычный символ
+		{"q=" + strings.Repeat("x", 2000), http.StatusBadRequest},
+		{"q=x&ignore_case=maybe", http.StatusBadRequest},
+		{"q=x&regex=true&ignore_case=true&invert=true", 0},
+	}
+	e := echo.New()
+	for _, tt := range tests {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil), httptest.NewRecorder())
+		_, err := parseLogOptions(c)
+		status := 0
+		if err != nil {
+			var httpErr *echo.HTTPError
+			if !errors.As(err, &httpErr) {
+				t.Errorf("%.40s: unexpected error %v", tt.query, err)
+				continue
+			}
+			status = httpErr.Code
+		}
+		if status != tt.status {
+			t.Errorf("%.40s: status %d, want %d", tt.query, status, tt.status)
+		}
+	}
+}
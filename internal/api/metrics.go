@@ -0,0 +1,122 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/history"
+
+	"github.com/labstack/echo/v4"
+)
+
+type metricsQueryResponse struct {
+	Container string          `json:"container,omitempty"`
+	Metric    string          `json:"metric"`
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"`
+	Step      int64           `json:"step"` // seconds
+	Points    []history.Point `json:"points"`
+}
+
+// parseQueryTime разбирает время в формате RFC3339, unix seconds
+// или относительное ("15m", "-1h" — столько времени назад; "now")
+func parseQueryTime(value string, now time.Time) (time.Time, error) {
+	if value == "" || value == "now" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(value, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// resolveContainerScope находит имя контейнера по имени или короткому ID
+func resolveContainerScope(value string) string {
+	list, err := containers.GetContainers()
+	if err != nil {
+		return value
+	}
+	for _, c := range list {
+		if c.Name == value || c.ID == value || strings.HasPrefix(value, c.ID) {
+			return c.Name
+		}
+	}
+	return value
+}
+
+func metricsQueryHandler(c echo.Context) error {
+	now := time.Now()
+	metric := c.QueryParam("metric")
+	container := c.QueryParam("container")
+
+	known := history.HostMetrics
+	if container != "" {
+		known = history.ContainerMetrics
+	}
+	if !slices.Contains(known, metric) {
+		return echo.NewHTTPError(http.StatusBadRequest,
+			fmt.Sprintf("Unknown metric %q, available: %s", metric, strings.Join(known, ", ")))
+	}
+
+	fromParam := c.QueryParam("from")
+	if fromParam == "" {
+		fromParam = "1h"
+	}
+	from, err := parseQueryTime(fromParam, now)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	to, err := parseQueryTime(c.QueryParam("to"), now)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if !from.Before(to) {
+		return echo.NewHTTPError(http.StatusBadRequest, "from must be before to")
+	}
+
+	var step time.Duration
+	if stepParam := c.QueryParam("step"); stepParam != "" {
+		step, err = time.ParseDuration(stepParam)
+		if err != nil {
+			if sec, errInt := strconv.ParseInt(stepParam, 10, 64); errInt == nil {
+				step, err = time.Duration(sec)*time.Second, nil
+			}
+		}
+		if err != nil || step < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid step")
+		}
+	}
+
+	scope := history.HostScope
+	if container != "" {
+		scope = resolveContainerScope(container)
+		if !newRecordAccess(auth.UserFromContext(c)).canView("", scope, "") {
+			return echo.NewHTTPError(http.StatusNotFound, "Container not found")
+		}
+	}
+
+	points, resolution, _ := history.Default().Query(scope, metric, from, to, step)
+	if points == nil {
+		points = []history.Point{}
+	}
+	return c.JSON(http.StatusOK, metricsQueryResponse{
+		Container: scope,
+		Metric:    metric,
+		From:      from,
+		To:        to,
+		Step:      int64(resolution / time.Second),
+		Points:    points,
+	})
+}
@@ -0,0 +1,321 @@
+package api
+
+import (
+	"context"
+	"hash/fnv"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// Число цветов, по которым распределяются контейнеры в общем потоке
+	projectLogColors = 8
+	// Строки, пришедшие за это время, сортируются по метке времени перед отправкой
+	projectLogMergeWindow = 250 * time.Millisecond
+	// Как часто искать новые и перезапущенные контейнеры проекта
+	projectLogRescanInterval = 2 * time.Second
+)
+
+// projectLogMessage — строка лога контейнера в общем потоке проекта
+type projectLogMessage struct {
+	logMessage
+	Container string `json:"container"`
+	Service   string `json:"service,omitempty"`
+	Color     int    `json:"color"` // номер цвета контейнера, 0..7
+}
+
+// logColor — стабильный номер цвета для имени контейнера
+func logColor(name string) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return int(h.Sum32() % projectLogColors)
+}
+
+// projectLogContainers возвращает контейнеры проекта, логи которых пользователь может читать.
+// Контейнеры без права на логи пропускаются
+func projectLogContainers(c echo.Context, project string) ([]containers.Container, error) {
+	list, err := containers.GetContainers()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Failed to get containers: "+err.Error())
+	}
+	user := auth.UserFromContext(c)
+	var result []containers.Container
+	visible := 0
+	for i := range list {
+		if list[i].ComposeProject != project || !rbac.Can(user, &list[i], rbac.ActionView) {
+			continue
+		}
+		visible++
+		if rbac.Can(user, &list[i], rbac.ActionLogs) {
+			result = append(result, list[i])
+		}
+	}
+	if visible == 0 {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Project not found")
+	}
+	if len(result) == 0 {
+		entry := newAuditEntry(c, string(rbac.ActionLogs), nil)
+		entry.Project = project
+		entry.Result = audit.ResultDenied
+		if !rbac.FeatureEnabled(rbac.ActionLogs) {
+			entry.Error = "Container logs is disabled"
+			audit.Record(entry)
+			return nil, echo.NewHTTPError(http.StatusForbidden, "Container logs is disabled")
+		}
+		entry.Error = "Not allowed to read logs of this project"
+		audit.Record(entry)
+		return nil, echo.NewHTTPError(http.StatusForbidden, "Not allowed to read logs of this project")
+	}
+	return result, nil
+}
+
+// projectLogStream — общий поток логов контейнеров проекта
+type projectLogStream struct {
+	project string
+	user    *auth.User
+	opts    logOptions
+	lines   chan projectLogMessage
+
+	mu       sync.Mutex
+	active   map[string]bool      // полный ID -> идет чтение логов
+	lastSeen map[string]time.Time // полный ID -> метка времени последней строки
+}
+
+// readContainer читает логи контейнера до конца потока (или отмены ctx) и передает строки в fn
+func (s *projectLogStream) readContainer(ctx context.Context, container containers.Container, opts logOptions, follow bool,
+	fn func(projectLogMessage)) {
+	reader, body, err := openLogs(ctx, &container, opts, follow)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("[docker-dashboard] Failed to get logs of %s: %v", container.Name, err)
+		}
+		return
+	}
+	defer body.Close()
+	color := logColor(container.Name)
+	service := container.AllLabels["com.docker.compose.service"]
+	for {
+		line, err := reader.Next()
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				log.Printf("[docker-dashboard] Error reading logs of %s: %v", container.Name, err)
+			}
+			return
+		}
+		if line.Timestamp.IsZero() {
+			line.Timestamp = time.Now()
+		}
+		s.mu.Lock()
+		if line.Timestamp.After(s.lastSeen[container.FullID]) {
+			s.lastSeen[container.FullID] = line.Timestamp
+		}
+		s.mu.Unlock()
+		msg, ok := s.opts.message(line)
+		if !ok {
+			continue
+		}
+		fn(projectLogMessage{
+			logMessage: msg,
+			Container:  container.Name,
+			Service:    service,
+			Color:      color,
+		})
+	}
+}
+
+// history читает сохраненные логи всех контейнеров до момента until и сортирует их по времени
+func (s *projectLogStream) history(ctx context.Context, list []containers.Container, until time.Time) []projectLogMessage {
+	opts := s.opts
+	opts.Until = until
+	var (
+		mu     sync.Mutex
+		result []projectLogMessage
+		wg     sync.WaitGroup
+	)
+	for _, container := range list {
+		wg.Add(1)
+		go func(container containers.Container) {
+			defer wg.Done()
+			s.readContainer(ctx, container, opts, false, func(msg projectLogMessage) {
+				mu.Lock()
+				result = append(result, msg)
+				mu.Unlock()
+			})
+		}(container)
+	}
+	wg.Wait()
+	sortProjectLogs(result)
+	return result
+}
+
+// follow запускает чтение новых строк запущенных контейнеров проекта, для которых оно еще не идет.
+// Контейнеры, запущенные или перезапущенные позже, подхватываются при следующем вызове
+func (s *projectLogStream) follow(ctx context.Context, since time.Time) {
+	list, err := containers.GetContainers()
+	if err != nil {
+		return
+	}
+	for i := range list {
+		container := list[i]
+		if container.ComposeProject != s.project || container.State != "running" ||
+			!rbac.Can(s.user, &container, rbac.ActionLogs) {
+			continue
+		}
+		s.mu.Lock()
+		if s.active[container.FullID] {
+			s.mu.Unlock()
+			continue
+		}
+		s.active[container.FullID] = true
+		opts := s.opts
+		opts.Tail = "all"
+		opts.Since = since
+		if last, ok := s.lastSeen[container.FullID]; ok && !last.Before(since) {
+			opts.Since = last.Add(time.Nanosecond)
+		}
+		s.mu.Unlock()
+
+		go func() {
+			defer func() {
+				s.mu.Lock()
+				delete(s.active, container.FullID)
+				s.mu.Unlock()
+			}()
+			s.readContainer(ctx, container, opts, true, func(msg projectLogMessage) {
+				select {
+				case s.lines <- msg:
+				case <-ctx.Done():
+				}
+			})
+		}()
+	}
+}
+
+func sortProjectLogs(lines []projectLogMessage) {
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp.Before(*lines[j].Timestamp)
+	})
+}
+
+// projectLogsWebSocketHandler передает общий поток логов контейнеров compose проекта:
+//
+//	WS /ws/projects/:project/logs?tail=100&since=15m&until=&timestamps=false&stdout=true&stderr=true
+//
+// Сначала отправляются сохраненные логи всех контейнеров (tail — для каждого контейнера),
+// упорядоченные по времени, затем новые строки по мере появления. Контейнеры проекта,
+// запущенные позже, подключаются автоматически. Каждая строка содержит имя контейнера и номер цвета
+func projectLogsWebSocketHandler(c echo.Context) error {
+	project := c.Param("project")
+	opts, err := parseLogOptions(c)
+	if err != nil {
+		return err
+	}
+	list, err := projectLogContainers(c, project)
+	if err != nil {
+		return err
+	}
+
+	conn, err := upgrader.Upgrade(c.Response().Writer, c.Request(), nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return err
+	}
+	defer conn.Close()
+
+	names := make([]string, len(list))
+	for i := range list {
+		names[i] = list[i].Name
+	}
+	entry := newAuditEntry(c, string(rbac.ActionLogs), nil)
+	entry.Project = project
+	entry.Result = audit.ResultSuccess
+	entry.Details = map[string]string{"containers": strings.Join(names, ",")}
+	audit.Record(entry)
+
+	// Канал для обработки закрытия соединения клиентом
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	stream := &projectLogStream{
+		project:  project,
+		user:     auth.UserFromContext(c),
+		opts:     opts,
+		lines:    make(chan projectLogMessage, 256),
+		active:   make(map[string]bool),
+		lastSeen: make(map[string]time.Time),
+	}
+
+	// Сохраненные логи до момента подключения
+	start := time.Now()
+	historyUntil := start
+	if !opts.Until.IsZero() && opts.Until.Before(start) {
+		historyUntil = opts.Until
+	}
+	for _, msg := range stream.history(ctx, list, historyUntil) {
+		conn.SetWriteDeadline(time.Now().Add(hubWriteTimeout))
+		if err := conn.WriteJSON(msg); err != nil {
+			return nil
+		}
+	}
+	if !opts.Until.IsZero() && !opts.Until.After(start) {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		return nil
+	}
+
+	// Новые строки: чтение с момента подключения, слияние окнами по времени
+	since := start.Add(time.Nanosecond)
+	stream.follow(ctx, since)
+	rescan := time.NewTicker(projectLogRescanInterval)
+	defer rescan.Stop()
+	flush := time.NewTicker(projectLogMergeWindow)
+	defer flush.Stop()
+	var pending []projectLogMessage
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-stream.lines:
+			pending = append(pending, msg)
+		case <-rescan.C:
+			if !opts.Until.IsZero() && time.Now().After(opts.Until) {
+				continue
+			}
+			stream.follow(ctx, since)
+		case <-flush.C:
+			if len(pending) == 0 {
+				continue
+			}
+			sortProjectLogs(pending)
+			for _, msg := range pending {
+				conn.SetWriteDeadline(time.Now().Add(hubWriteTimeout))
+				if err := conn.WriteJSON(msg); err != nil {
+					log.Printf("WebSocket write error: %v", err)
+					return nil
+				}
+			}
+			pending = pending[:0]
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-dashboard/internal/actions"
+	"docker-dashboard/internal/audit"
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/jobs"
+	"docker-dashboard/internal/rbac"
+
+	"github.com/labstack/echo/v4"
+)
+
+// projectActionResponse — ход и результат действия над compose проектом.
+// Используется и как прогресс задачи (/ws/jobs/:id), и как итоговый ответ
+type projectActionResponse struct {
+	Status    string         `json:"status"` // running, success или error
+	Project   string         `json:"project"`
+	Action    string         `json:"action"`
+	OnFailure string         `json:"on_failure"`
+	Completed int            `json:"completed"`
+	Failed    int            `json:"failed"`
+	Total     int            `json:"total"`
+	Steps     []actions.Step `json:"steps"`
+	JobID     string         `json:"job_id,omitempty"`
+}
+
+// projectContainers возвращает контейнеры проекта, доступные пользователю для действия.
+// Если хотя бы один контейнер проекта недоступен, действие над проектом запрещено целиком,
+// иначе порядок зависимостей был бы нарушен
+func projectContainers(c echo.Context, project, action string) ([]containers.Container, error) {
+	list, err := containers.GetContainers()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadGateway, "Failed to get containers: "+err.Error())
+	}
+	user := auth.UserFromContext(c)
+	var result []containers.Container
+	visible := 0
+	for i := range list {
+		if list[i].ComposeProject != project {
+			continue
+		}
+		result = append(result, list[i])
+		if rbac.Can(user, &list[i], rbac.ActionView) {
+			visible++
+		}
+	}
+	if visible == 0 {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Project not found")
+	}
+
+	var denied []string
+	for i := range result {
+		if !rbac.Can(user, &result[i], rbac.Action(action)) {
+			denied = append(denied, result[i].Name)
+		}
+	}
+	if len(denied) > 0 {
+		entry := newAuditEntry(c, "project_"+action, nil)
+		entry.Project = project
+		entry.Result = audit.ResultDenied
+		entry.Error = "not allowed for " + strconv.Itoa(len(denied)) + " containers"
+		audit.Record(entry)
+		if !rbac.FeatureEnabled(rbac.Action(action)) {
+			return nil, echo.NewHTTPError(http.StatusForbidden, "Container "+action+" is disabled")
+		}
+		return nil, echo.NewHTTPError(http.StatusForbidden,
+			fmt.Sprintf("Not allowed to %s %d of %d containers in this project", action, len(denied), len(result)))
+	}
+	return result, nil
+}
+
+// projectActionHandler выполняет действие над всеми контейнерами compose проекта:
+//
+//	POST /api/projects/:project/:action
+//
+// Контейнеры обрабатываются по очереди в порядке зависимостей (com.docker.compose.depends_on).
+// on_failure=abort (по умолчанию) пропускает оставшиеся контейнеры после первой ошибки,
+// on_failure=continue продолжает. Прогресс по каждому контейнеру доступен в /ws/jobs/:id
+func projectActionHandler(action string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		project := c.Param("project")
+		params, err := parseActionRequest(c)
+		if err == nil {
+			err = params.opts.Validate(action)
+		}
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		list, err := projectContainers(c, project, action)
+		if err != nil {
+			return err
+		}
+		ordered, err := actions.ProjectOrder(list, action)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+
+		owner := jobOwner(auth.UserFromContext(c))
+		idempotencyKey, err := idempotencyKeyFor(c, owner)
+		if err != nil {
+			return err
+		}
+		onFailure := "abort"
+		if params.continueOnError {
+			onFailure = "continue"
+		}
+		fingerprint := fmt.Sprintf("project %s %s on_failure=%s", project,
+			actionFingerprint(action, "", params.opts), onFailure)
+
+		template := newAuditEntry(c, action, nil)
+		template.Details = actionDetails(params.opts)
+		if template.Details == nil {
+			template.Details = make(map[string]string)
+		}
+		template.Details["project_action"] = "true"
+
+		timeout := time.Duration(len(ordered)) * actions.Deadline(action, params.opts)
+		job, err := jobs.Default().Start(idempotencyKey, fingerprint, "project_"+action, project, owner, timeout,
+			func(ctx context.Context, update func(interface{})) (interface{}, error) {
+				return runProjectAction(ctx, update, project, action, ordered, params, onFailure, template)
+			})
+		return respondJob(c, job, err, params.async, func(job jobs.Job) error {
+			response, ok := job.Result.(projectActionResponse)
+			if !ok {
+				return echo.NewHTTPError(http.StatusInternalServerError, job.Error)
+			}
+			response.JobID = job.ID
+			status := http.StatusOK
+			if response.Failed > 0 {
+				status = http.StatusBadGateway
+			}
+			return c.JSON(status, response)
+		})
+	}
+}
+
+// runProjectAction выполняет задачу проектного действия, публикуя прогресс после каждого шага
+func runProjectAction(ctx context.Context, update func(interface{}), project, action string, ordered []containers.Container,
+	params actionParams, onFailure string, template audit.Entry) (interface{}, error) {
+	jobID := jobs.IDFromContext(ctx)
+	progress := projectActionResponse{
+		Status:    "running",
+		Project:   project,
+		Action:    action,
+		OnFailure: onFailure,
+		Total:     len(ordered),
+		Steps:     actions.NewSteps(ordered),
+	}
+	publish := func() {
+		snapshot := progress
+		snapshot.Steps = append([]actions.Step(nil), progress.Steps...)
+		update(snapshot)
+	}
+	publish()
+
+	failed := actions.RunProject(ctx, action, ordered, params.opts, params.continueOnError,
+		func(i int, step actions.Step, err error) {
+			progress.Steps[i] = step
+			switch step.Status {
+			case actions.StepSuccess, actions.StepError:
+				progress.Completed++
+				entry := template
+				entry.Time = time.Now()
+				entry.Container = ordered[i].Name
+				entry.ContainerID = ordered[i].FullID
+				entry.Project = project
+				entry.JobID = jobID
+				recordActionAudit(entry, actionResponse{Error: step.Error}, err)
+			}
+			if step.Status == actions.StepError {
+				progress.Failed++
+			}
+			publish()
+		})
+
+	progress.Status = "success"
+	if failed > 0 {
+		progress.Status = "error"
+		var names []string
+		for _, step := range progress.Steps {
+			if step.Status == actions.StepError {
+				names = append(names, step.Container)
+			}
+		}
+		return progress, fmt.Errorf("failed to %s %s", action, strings.Join(names, ", "))
+	}
+	return progress, nil
+}
@@ -0,0 +1,185 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Результаты действий в журнале аудита
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+	ResultDenied  = "denied"
+)
+
+// Entry — запись журнала аудита
+type Entry struct {
+	Time         time.Time         `json:"time"`
+	User         string            `json:"user"`
+	AuthMethod   string            `json:"auth_method,omitempty"`
+	IP           string            `json:"ip"`
+	Action       string            `json:"action"` // restart, stop, logs, ...
+	Container    string            `json:"container,omitempty"`
+	ContainerID  string            `json:"container_id,omitempty"` // полный ID
+	Project      string            `json:"project,omitempty"`
+	Result       string            `json:"result"` // success, error или denied
+	Error        string            `json:"error,omitempty"`
+	DockerStatus int               `json:"docker_status,omitempty"` // HTTP статус ответа Docker при ошибке
+	JobID        string            `json:"job_id,omitempty"`
+	Details      map[string]string `json:"details,omitempty"`
+}
+
+// Log — журнал аудита в файле JSON lines. Записи только добавляются
+type Log struct {
+	path string
+	mu   sync.Mutex
+	file *os.File
+}
+
+var (
+	defaultLog     *Log
+	defaultLogOnce sync.Once
+)
+
+// Default возвращает журнал из AUDIT_LOG_FILE, по умолчанию DATA_DIR/audit.jsonl.
+// Если не задано ни то ни другое, возвращает nil: записи попадают только в лог процесса
+func Default() *Log {
+	defaultLogOnce.Do(func() {
+		path := os.Getenv("AUDIT_LOG_FILE")
+		if path == "" && os.Getenv("DATA_DIR") != "" {
+			path = filepath.Join(os.Getenv("DATA_DIR"), "audit.jsonl")
+		}
+		if path == "" {
+			log.Printf("[docker-dashboard] Audit: AUDIT_LOG_FILE and DATA_DIR are not set, audit entries go to the process log only")
+			return
+		}
+		l, err := Open(path)
+		if err != nil {
+			log.Printf("[docker-dashboard] Audit: %v", err)
+			return
+		}
+		log.Printf("[docker-dashboard] Audit: writing to %s", path)
+		defaultLog = l
+	})
+	return defaultLog
+}
+
+// Open открывает (или создает) файл журнала для дописывания
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &Log{path: path, file: file}, nil
+}
+
+// Append дописывает запись в журнал и сбрасывает ее на диск
+func (l *Log) Append(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Record пишет запись в журнал по умолчанию и в лог процесса
+func Record(e Entry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	summary := fmt.Sprintf("[docker-dashboard] audit: user=%s ip=%s action=%s container=%s result=%s",
+		e.User, e.IP, e.Action, e.Container, e.Result)
+	if e.Error != "" {
+		summary += " error=" + e.Error
+	}
+	log.Print(summary)
+
+	if l := Default(); l != nil {
+		if err := l.Append(e); err != nil {
+			log.Printf("[docker-dashboard] Audit: failed to write entry: %v", err)
+		}
+	}
+}
+
+// Filter — условия выборки записей журнала; пустые поля не проверяются
+type Filter struct {
+	From      time.Time
+	To        time.Time
+	User      string
+	Action    string
+	Container string // имя, короткий или полный ID
+	Project   string
+	Result    string
+	Limit     int // максимум последних записей
+}
+
+func (f Filter) match(e Entry) bool {
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && e.Time.After(f.To) {
+		return false
+	}
+	if f.User != "" && e.User != f.User {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if f.Container != "" && e.Container != f.Container &&
+		(e.ContainerID == "" || !strings.HasPrefix(e.ContainerID, f.Container)) {
+		return false
+	}
+	if f.Project != "" && e.Project != f.Project {
+		return false
+	}
+	if f.Result != "" && e.Result != f.Result {
+		return false
+	}
+	return true
+}
+
+// Query возвращает записи, подходящие под фильтр, в порядке записи
+func (l *Log) Query(filter Filter) ([]Entry, error) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	var result []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) == nil && filter.match(e) {
+			result = append(result, e)
+			if filter.Limit > 0 && len(result) > 2*filter.Limit {
+				// Держим в памяти только хвост
+				result = append(result[:0], result[len(result)-filter.Limit:]...)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[len(result)-filter.Limit:]
+	}
+	return result, nil
+}
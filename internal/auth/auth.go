@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const userContextKey = "auth_user"
+
+// User — аутентифицированный пользователь
+type User struct {
+	Name   string   `json:"name"`
+	Groups []string `json:"groups,omitempty"`
+	Method string   `json:"method"` // basic, token, oidc или anonymous
+}
+
+// anonymousUser используется, когда аутентификация не настроена
+var anonymousUser = &User{Name: "anonymous", Method: "anonymous"}
+
+// Authenticator проверяет учетные данные запросов.
+// Поддерживаются basic auth (htpasswd), статические bearer токены и OIDC с cookie сессией
+type Authenticator struct {
+	htpasswd *htpasswdFile
+	tokens   map[string]string // токен -> имя пользователя
+	oidc     *oidcProvider
+	sessions *sessionCodec
+}
+
+// FromEnv создает Authenticator по переменным окружения:
+//
+//	AUTH_HTPASSWD_FILE — файл htpasswd (bcrypt или {SHA})
+//	AUTH_TOKENS        — статические токены "name:token,name2:token2"
+//	OIDC_ISSUER, OIDC_CLIENT_ID, OIDC_CLIENT_SECRET, OIDC_REDIRECT_URL — вход через OIDC
+//	SESSION_SECRET, SESSION_TTL — подпись и срок жизни cookie сессии
+func FromEnv() (*Authenticator, error) {
+	a := &Authenticator{tokens: make(map[string]string)}
+
+	if path := os.Getenv("AUTH_HTPASSWD_FILE"); path != "" {
+		h, err := loadHtpasswd(path)
+		if err != nil {
+			return nil, err
+		}
+		a.htpasswd = h
+		log.Printf("[docker-dashboard] Auth: basic auth enabled (%d users)", h.size())
+	}
+
+	if tokens := os.Getenv("AUTH_TOKENS"); tokens != "" {
+		for _, entry := range strings.Split(tokens, ",") {
+			name, token, ok := strings.Cut(strings.TrimSpace(entry), ":")
+			if !ok || name == "" || token == "" {
+				log.Printf("[docker-dashboard] Auth: ignoring malformed AUTH_TOKENS entry")
+				continue
+			}
+			a.tokens[token] = name
+		}
+		log.Printf("[docker-dashboard] Auth: API tokens enabled (%d tokens)", len(a.tokens))
+	}
+
+	ttl := 12 * time.Hour
+	if v := os.Getenv("SESSION_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Printf("[docker-dashboard] Invalid SESSION_TTL=%q, using %s", v, ttl)
+		} else {
+			ttl = d
+		}
+	}
+	a.sessions = newSessionCodec(os.Getenv("SESSION_SECRET"), ttl)
+
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		a.oidc = newOIDCProvider(oidcConfig{
+			Issuer:        issuer,
+			ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret:  os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:   os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:        os.Getenv("OIDC_SCOPES"),
+			UsernameClaim: os.Getenv("OIDC_USERNAME_CLAIM"),
+			GroupsClaim:   os.Getenv("OIDC_GROUPS_CLAIM"),
+		})
+		log.Printf("[docker-dashboard] Auth: OIDC login enabled (issuer %s)", issuer)
+	}
+
+	if !a.Enabled() {
+		log.Printf("[docker-dashboard] Auth: no authentication configured, dashboard is open to everyone")
+	}
+	return a, nil
+}
+
+// Enabled сообщает, настроен ли хотя бы один способ аутентификации
+func (a *Authenticator) Enabled() bool {
+	return a.htpasswd != nil || len(a.tokens) > 0 || a.oidc != nil
+}
+
+// authenticate определяет пользователя по заголовку Authorization или cookie сессии.
+// Для cookie сессии также возвращается ее значение (из него выводится CSRF токен)
+func (a *Authenticator) authenticate(r *http.Request) (*User, string) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		scheme, value, _ := strings.Cut(header, " ")
+		switch strings.ToLower(scheme) {
+		case "bearer":
+			for token, name := range a.tokens {
+				if subtle.ConstantTimeCompare([]byte(token), []byte(value)) == 1 {
+					return &User{Name: name, Method: "token"}, ""
+				}
+			}
+		case "basic":
+			if a.htpasswd != nil {
+				if username, password, ok := r.BasicAuth(); ok && a.htpasswd.verify(username, password) {
+					return &User{Name: username, Method: "basic"}, ""
+				}
+			}
+		}
+	}
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		if user, ok := a.sessions.decode(cookie.Value); ok {
+			return user, cookie.Value
+		}
+	}
+	return nil, ""
+}
+
+// Публичные пути, доступные без аутентификации (вход через OIDC)
+var publicPaths = []string{"/auth/login", "/auth/callback", "/auth/logout"}
+
+// Middleware проверяет аутентификацию для всех REST, WebSocket и статических маршрутов,
+// а для изменяющих запросов — Origin и CSRF токен
+func (a *Authenticator) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !a.Enabled() {
+				c.Set(userContextKey, anonymousUser)
+				if err := a.checkCSRF(c); err != nil {
+					return err
+				}
+				return next(c)
+			}
+			path := c.Request().URL.Path
+			for _, public := range publicPaths {
+				if path == public {
+					return next(c)
+				}
+			}
+			if user, session := a.authenticate(c.Request()); user != nil {
+				c.Set(userContextKey, user)
+				c.Set(sessionContextKey, session)
+				if err := a.checkCSRF(c); err != nil {
+					return err
+				}
+				return next(c)
+			}
+			return a.challenge(c)
+		}
+	}
+}
+
+// challenge отвечает неаутентифицированному клиенту:
+// браузер перенаправляется на вход через OIDC, остальным возвращается 401
+func (a *Authenticator) challenge(c echo.Context) error {
+	r := c.Request()
+	isAPI := strings.HasPrefix(r.URL.Path, "/api/") || strings.HasPrefix(r.URL.Path, "/ws/") || r.URL.Path == "/metrics"
+	if a.oidc != nil && r.Method == http.MethodGet && !isAPI {
+		return c.Redirect(http.StatusFound, "/auth/login?redirect="+urlQueryEscape(r.URL.RequestURI()))
+	}
+	if a.htpasswd != nil {
+		c.Response().Header().Set("WWW-Authenticate", `Basic realm="docker-dashboard", charset="UTF-8"`)
+	} else if len(a.tokens) > 0 {
+		c.Response().Header().Set("WWW-Authenticate", `Bearer realm="docker-dashboard"`)
+	}
+	return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+}
+
+// RegisterRoutes регистрирует маршруты входа и выхода
+func (a *Authenticator) RegisterRoutes(e *echo.Echo) {
+	e.GET("/auth/login", a.loginHandler)
+	e.GET("/auth/callback", a.callbackHandler)
+	e.GET("/auth/logout", a.logoutHandler)
+	e.GET("/auth/me", a.meHandler)
+}
+
+func (a *Authenticator) loginHandler(c echo.Context) error {
+	if a.oidc == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "OIDC login is not configured")
+	}
+	return a.oidc.startLogin(c, a.sessions)
+}
+
+func (a *Authenticator) callbackHandler(c echo.Context) error {
+	if a.oidc == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "OIDC login is not configured")
+	}
+	user, redirect, err := a.oidc.finishLogin(c, a.sessions)
+	if err != nil {
+		log.Printf("[docker-dashboard] OIDC login failed: %v", err)
+		return echo.NewHTTPError(http.StatusUnauthorized, "OIDC login failed: "+err.Error())
+	}
+	log.Printf("[docker-dashboard] OIDC login: %s", user.Name)
+	a.sessions.setCookie(c, user)
+	return c.Redirect(http.StatusFound, redirect)
+}
+
+func (a *Authenticator) logoutHandler(c echo.Context) error {
+	a.sessions.clearCookie(c)
+	return c.Redirect(http.StatusFound, "/")
+}
+
+// meResponse — текущий пользователь и CSRF токен для изменяющих запросов из браузера
+type meResponse struct {
+	*User
+	CSRFToken string `json:"csrf_token,omitempty"`
+}
+
+func (a *Authenticator) meHandler(c echo.Context) error {
+	user := UserFromContext(c)
+	if user == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Authentication required")
+	}
+	return c.JSON(http.StatusOK, meResponse{User: user, CSRFToken: a.CSRFToken(c)})
+}
+
+// UserFromContext возвращает пользователя, установленного Middleware
+func UserFromContext(c echo.Context) *User {
+	if user, ok := c.Get(userContextKey).(*User); ok {
+		return user
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// newTestServer подключает Middleware и маршруты входа, как main, и добавляет тестовые маршруты API
+func newTestServer(a *Authenticator) *echo.Echo {
+	e := echo.New()
+	e.Use(a.Middleware())
+	a.RegisterRoutes(e)
+	e.GET("/", func(c echo.Context) error { return c.String(http.StatusOK, "index") })
+	e.GET("/api/whoami", func(c echo.Context) error {
+		user := UserFromContext(c)
+		return c.String(http.StatusOK, user.Name+" "+user.Method)
+	})
+	e.POST("/api/containers/:id/restart", func(c echo.Context) error { return c.NoContent(http.StatusNoContent) })
+	return e
+}
+
+func serve(e *echo.Echo, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func findCookie(rec *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name && cookie.MaxAge >= 0 {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// sessionCookie выдает cookie сессии так же, как callbackHandler после входа через OIDC
+func sessionCookie(t *testing.T, codec *sessionCodec, user *User) *http.Cookie {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	codec.setCookie(echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/auth/callback", nil), rec), user)
+	cookie := findCookie(rec, sessionCookieName)
+	if cookie == nil {
+		t.Fatal("session cookie is not set")
+	}
+	return cookie
+}
+
+func TestBearerToken(t *testing.T) {
+	a := &Authenticator{tokens: map[string]string{"t0ken": "ci"}, sessions: newSessionCodec("secret", time.Hour)}
+	e := newTestServer(a)
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+		body   string
+	}{
+		{"valid token", "Bearer t0ken", http.StatusOK, "ci token"},
+		{"scheme is case-insensitive", "bearer t0ken", http.StatusOK, "ci token"},
+		{"wrong token", "Bearer t0ken2", http.StatusUnauthorized, ""},
+		{"token prefix", "Bearer t0k", http.StatusUnauthorized, ""},
+		{"empty token", "Bearer ", http.StatusUnauthorized, ""},
+		{"token as basic password", "Basic Y2k6dDBrZW4=", http.StatusUnauthorized, ""},
+		{"no credentials", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/whoami", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := serve(e, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d", rec.Code, tt.status)
+			}
+			if tt.body != "" && rec.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.body)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != `Bearer realm="docker-dashboard"` {
+				t.Errorf("WWW-Authenticate = %q", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestSessionCookie(t *testing.T) {
+	codec := newSessionCodec("secret", time.Hour)
+	user := &User{Name: "alice", Groups: []string{"ops"}, Method: "oidc"}
+	cookie := sessionCookie(t, codec, user)
+	if !cookie.HttpOnly || cookie.Path != "/" || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("cookie attributes = %+v", cookie)
+	}
+
+	decoded, ok := codec.decode(cookie.Value)
+	if !ok || decoded.Name != "alice" || decoded.Method != "oidc" || strings.Join(decoded.Groups, ",") != "ops" {
+		t.Fatalf("decode = %+v, %v", decoded, ok)
+	}
+
+	// Подмененные данные с исходной подписью
+	data, _ := codec.verify(cookie.Value)
+	_, signature, _ := strings.Cut(cookie.Value, ".")
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(strings.Replace(string(data), "alice", "admin", 1))) + "." + signature
+	invalid := map[string]string{
+		"signed with another key": sessionCookie(t, newSessionCodec("other", time.Hour), user).Value,
+		"tampered payload":        tampered,
+		"no signature":            strings.Split(cookie.Value, ".")[0],
+		"garbage":                 "not base64.at all",
+		"expired":                 sessionCookie(t, newSessionCodec("secret", -time.Second), user).Value,
+		"login state":             codec.sign([]byte(`{"s":"state","e":9999999999}`)),
+	}
+	for name, value := range invalid {
+		if user, ok := codec.decode(value); ok {
+			t.Errorf("%s: decoded as %+v", name, user)
+		}
+	}
+
+	// Через Middleware: действующая сессия пропускается, истекшая требует входа
+	e := newTestServer(&Authenticator{tokens: map[string]string{"t": "ci"}, sessions: codec})
+	req := httptest.NewRequest(http.MethodGet, "/api/whoami", nil)
+	req.AddCookie(cookie)
+	if rec := serve(e, req); rec.Code != http.StatusOK || rec.Body.String() != "alice oidc" {
+		t.Errorf("valid session: status %d, body %q", rec.Code, rec.Body.String())
+	}
+	req = httptest.NewRequest(http.MethodGet, "/api/whoami", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: invalid["expired"]})
+	if rec := serve(e, req); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expired session: status %d, want 401", rec.Code)
+	}
+
+	// Выход удаляет cookie
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/auth/logout", nil))
+	cleared := rec.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].Name != sessionCookieName || cleared[0].MaxAge >= 0 {
+		t.Errorf("logout cookies = %+v", cleared)
+	}
+}
+
+func TestCSRF(t *testing.T) {
+	codec := newSessionCodec("secret", time.Hour)
+	a := &Authenticator{tokens: map[string]string{"t0ken": "ci"}, sessions: codec}
+	e := newTestServer(a)
+	cookie := sessionCookie(t, codec, &User{Name: "alice", Method: "oidc"})
+	token := codec.csrfToken(cookie.Value)
+	otherToken := codec.csrfToken(sessionCookie(t, codec, &User{Name: "bob", Method: "oidc"}).Value)
+
+	// CSRF токен для браузера выдается в /auth/me
+	req := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	req.AddCookie(cookie)
+	if rec := serve(e, req); !strings.Contains(rec.Body.String(), `"csrf_token":"`+token+`"`) {
+		t.Errorf("/auth/me = %s, want csrf_token", rec.Body.String())
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		session bool
+		bearer  bool
+		token   string
+		origin  string
+		status  int
+	}{
+		{"GET with session needs no token", http.MethodGet, true, false, "", "", http.StatusOK},
+		{"POST with session and token", http.MethodPost, true, false, token, "", http.StatusNoContent},
+		{"POST with session without token", http.MethodPost, true, false, "", "", http.StatusForbidden},
+		{"POST with session and wrong token", http.MethodPost, true, false, "wrong", "", http.StatusForbidden},
+		{"POST with token of another session", http.MethodPost, true, false, otherToken, "", http.StatusForbidden},
+		{"DELETE with session without token", http.MethodDelete, true, false, "", "", http.StatusForbidden},
+		{"POST with session from same origin", http.MethodPost, true, false, token, "http://example.com", http.StatusNoContent},
+		{"POST with session from another origin", http.MethodPost, true, false, token, "https://evil.example", http.StatusForbidden},
+		{"POST with bearer token needs no CSRF token", http.MethodPost, false, true, "", "", http.StatusNoContent},
+		{"POST with bearer token from another origin", http.MethodPost, false, true, "", "https://evil.example", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/api/containers/abc/restart"
+			if tt.method == http.MethodGet {
+				path = "/api/whoami"
+			}
+			req := httptest.NewRequest(tt.method, path, nil)
+			if tt.session {
+				req.AddCookie(cookie)
+			}
+			if tt.bearer {
+				req.Header.Set("Authorization", "Bearer t0ken")
+			}
+			if tt.token != "" {
+				req.Header.Set(CSRFHeaderName, tt.token)
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if rec := serve(e, req); rec.Code != tt.status {
+				t.Errorf("status %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCSRFWithoutAuth(t *testing.T) {
+	e := newTestServer(&Authenticator{tokens: map[string]string{}, sessions: newSessionCodec("secret", time.Hour)})
+	req := httptest.NewRequest(http.MethodPost, "/api/containers/abc/restart", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	if rec := serve(e, req); rec.Code != http.StatusForbidden {
+		t.Errorf("cross-origin POST without auth: status %d, want 403", rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/api/containers/abc/restart", nil)
+	if rec := serve(e, req); rec.Code != http.StatusNoContent {
+		t.Errorf("POST without auth: status %d, want 204", rec.Code)
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	a := &Authenticator{
+		tokens:   map[string]string{},
+		sessions: newSessionCodec("secret", time.Hour),
+		oidc:     newOIDCProvider(oidcConfig{Issuer: "https://issuer.example", ClientID: testClientID}),
+	}
+	e := newTestServer(a)
+
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/?tab=logs", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/auth/login?redirect=%2F%3Ftab%3Dlogs" {
+		t.Errorf("browser request: status %d, location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec := serve(e, httptest.NewRequest(http.MethodGet, "/api/whoami", nil)); rec.Code != http.StatusUnauthorized {
+		t.Errorf("API request: status %d, want 401", rec.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// htpasswdFile — пользователи из файла htpasswd.
+// Поддерживаются хэши bcrypt ($2y$, $2a$, $2b$) и {SHA}
+type htpasswdFile struct {
+	users map[string]string // имя -> хэш
+}
+
+func loadHtpasswd(path string) (*htpasswdFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open htpasswd file: %w", err)
+	}
+	defer f.Close()
+
+	h := &htpasswdFile{users: make(map[string]string)}
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, hash, ok := strings.Cut(line, ":")
+		if !ok || name == "" || hash == "" {
+			log.Printf("[docker-dashboard] htpasswd: skipping malformed line %d", lineNo)
+			continue
+		}
+		if !supportedHash(hash) {
+			log.Printf("[docker-dashboard] htpasswd: unsupported hash for user %s (use bcrypt: htpasswd -B)", name)
+			continue
+		}
+		h.users[name] = hash
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read htpasswd file: %w", err)
+	}
+	return h, nil
+}
+
+func supportedHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2y$") || strings.HasPrefix(hash, "$2a$") ||
+		strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "{SHA}")
+}
+
+func (h *htpasswdFile) size() int {
+	return len(h.users)
+}
+
+func (h *htpasswdFile) verify(username, password string) bool {
+	hash, ok := h.users[username]
+	if !ok {
+		return false
+	}
+	if strings.HasPrefix(hash, "{SHA}") {
+		sum := sha1.Sum([]byte(password))
+		expected := base64.StdEncoding.EncodeToString(sum[:])
+		return subtle.ConstantTimeCompare([]byte(expected), []byte(strings.TrimPrefix(hash, "{SHA}"))) == 1
+	}
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeHtpasswd(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "htpasswd")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func bcryptHash(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(hash)
+}
+
+func TestHtpasswd(t *testing.T) {
+	sum := sha1.Sum([]byte("sha-pass"))
+	// htpasswd -B пишет хэши с префиксом $2y$
+	apacheBcrypt := "$2y$" + strings.TrimPrefix(bcryptHash(t, "apache-pass"), "$2a$")
+	path := writeHtpasswd(t,
+		"# users",
+		"alice:"+bcryptHash(t, "alice-pass"),
+		"",
+		"bob:{SHA}"+base64.StdEncoding.EncodeToString(sum[:]),
+		"carol:"+apacheBcrypt,
+		"dave:$apr1$salt$hash",
+		"malformed line",
+		"eve:",
+	)
+	h, err := loadHtpasswd(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.size() != 3 {
+		t.Errorf("loaded %d users, want 3 (md5 and malformed lines skipped)", h.size())
+	}
+
+	tests := []struct {
+		user, password string
+		ok             bool
+	}{
+		{"alice", "alice-pass", true},
+		{"alice", "wrong", false},
+		{"alice", "", false},
+		{"bob", "sha-pass", true},
+		{"bob", "sha-pass ", false},
+		{"carol", "apache-pass", true},
+		{"dave", "anything", false},
+		{"eve", "", false},
+		{"nobody", "alice-pass", false},
+	}
+	for _, tt := range tests {
+		if got := h.verify(tt.user, tt.password); got != tt.ok {
+			t.Errorf("verify(%s, %q) = %v, want %v", tt.user, tt.password, got, tt.ok)
+		}
+	}
+
+	if _, err := loadHtpasswd(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("missing file: expected error")
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	h, err := loadHtpasswd(writeHtpasswd(t, "alice:"+bcryptHash(t, "alice-pass")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newTestServer(&Authenticator{htpasswd: h, tokens: map[string]string{}, sessions: newSessionCodec("secret", time.Hour)})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/whoami", nil)
+	req.SetBasicAuth("alice", "alice-pass")
+	if rec := serve(e, req); rec.Code != http.StatusOK || rec.Body.String() != "alice basic" {
+		t.Errorf("valid password: status %d, body %q", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/whoami", nil)
+	req.SetBasicAuth("alice", "wrong")
+	rec := serve(e, req)
+	if rec.Code != http.StatusUnauthorized || !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic ") {
+		t.Errorf("wrong password: status %d, WWW-Authenticate %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	// Basic auth не использует cookie, поэтому CSRF токен не нужен
+	req = httptest.NewRequest(http.MethodPost, "/api/containers/abc/restart", nil)
+	req.SetBasicAuth("alice", "alice-pass")
+	if rec := serve(e, req); rec.Code != http.StatusNoContent {
+		t.Errorf("POST with basic auth: status %d, want 204", rec.Code)
+	}
+}
@@ -0,0 +1,450 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/labstack/echo/v4"
+)
+
+type oidcConfig struct {
+	Issuer        string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string // если пусто, вычисляется из запроса: <scheme>://<host>/auth/callback
+	Scopes        string // через пробел, по умолчанию "openid profile email"
+	UsernameClaim string // по умолчанию preferred_username, затем email и sub
+	GroupsClaim   string // по умолчанию groups
+}
+
+// oidcDiscovery — нужные поля /.well-known/openid-configuration
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// oidcProvider реализует authorization code flow с PKCE и проверку ID токена
+type oidcProvider struct {
+	cfg    oidcConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]crypto.PublicKey
+	keysAt    time.Time
+}
+
+// oidcLoginState хранится в подписанной cookie между /auth/login и /auth/callback
+type oidcLoginState struct {
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	Redirect string `json:"r"`
+	Expires  int64  `json:"e"`
+}
+
+func newOIDCProvider(cfg oidcConfig) *oidcProvider {
+	cfg.Issuer = strings.TrimSuffix(cfg.Issuer, "/")
+	if cfg.Scopes == "" {
+		cfg.Scopes = "openid profile email"
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	return &oidcProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// getDiscovery загружает метаданные провайдера (кэшируются после первой успешной загрузки)
+func (p *oidcProvider) getDiscovery() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+	var d oidcDiscovery
+	if err := p.getJSON(p.cfg.Issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery: issuer mismatch: %q", d.Issuer)
+	}
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+func (p *oidcProvider) getJSON(u string, v interface{}) error {
+	resp, err := p.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", u, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (p *oidcProvider) redirectURL(r *http.Request) string {
+	if p.cfg.RedirectURL != "" {
+		return p.cfg.RedirectURL
+	}
+	scheme := "http"
+	if isSecureRequest(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + "/auth/callback"
+}
+
+// startLogin перенаправляет пользователя на страницу входа провайдера
+func (p *oidcProvider) startLogin(c echo.Context, sessions *sessionCodec) error {
+	d, err := p.getDiscovery()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, "OIDC provider unavailable: "+err.Error())
+	}
+
+	state := oidcLoginState{
+		State:    randomString(24),
+		Nonce:    randomString(24),
+		Verifier: randomString(48),
+		Redirect: localRedirect(c.QueryParam("redirect")),
+		Expires:  time.Now().Add(10 * time.Minute).Unix(),
+	}
+	value, err := sessions.encodeValue(state)
+	if err != nil {
+		return err
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     loginCookieName,
+		Value:    value,
+		Path:     "/auth/",
+		MaxAge:   600,
+		HttpOnly: true,
+		Secure:   isSecureRequest(c.Request()),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.redirectURL(c.Request())},
+		"scope":                 {p.cfg.Scopes},
+		"state":                 {state.State},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return c.Redirect(http.StatusFound, d.AuthorizationEndpoint+sep+params.Encode())
+}
+
+// localRedirect разрешает возврат после входа только на локальные пути, чтобы не было open redirect.
+// Обратная косая черта и управляющие символы отклоняются: браузеры превращают "/\evil.example"
+// в "//evil.example", а табуляцию и переводы строк в URL просто удаляют
+func localRedirect(redirect string) string {
+	if !strings.HasPrefix(redirect, "/") || strings.HasPrefix(redirect, "//") {
+		return "/"
+	}
+	for _, r := range redirect {
+		if r == '\\' || unicode.IsControl(r) {
+			return "/"
+		}
+	}
+	u, err := url.Parse(redirect)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.User != nil {
+		return "/"
+	}
+	return redirect
+}
+
+// finishLogin обменивает код на токены и проверяет ID токен
+func (p *oidcProvider) finishLogin(c echo.Context, sessions *sessionCodec) (*User, string, error) {
+	cookie, err := c.Cookie(loginCookieName)
+	if err != nil {
+		return nil, "", errors.New("missing login state")
+	}
+	c.SetCookie(&http.Cookie{Name: loginCookieName, Value: "", Path: "/auth/", MaxAge: -1})
+
+	var state oidcLoginState
+	if !sessions.decodeValue(cookie.Value, &state) || time.Now().Unix() > state.Expires {
+		return nil, "", errors.New("invalid or expired login state")
+	}
+	if errParam := c.QueryParam("error"); errParam != "" {
+		return nil, "", fmt.Errorf("provider error: %s %s", errParam, c.QueryParam("error_description"))
+	}
+	if c.QueryParam("state") != state.State {
+		return nil, "", errors.New("state mismatch")
+	}
+	code := c.QueryParam("code")
+	if code == "" {
+		return nil, "", errors.New("missing code")
+	}
+
+	d, err := p.getDiscovery()
+	if err != nil {
+		return nil, "", err
+	}
+	idToken, err := p.exchangeCode(d, code, state.Verifier, p.redirectURL(c.Request()))
+	if err != nil {
+		return nil, "", err
+	}
+	claims, err := p.verifyIDToken(d, idToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != state.Nonce {
+		return nil, "", errors.New("nonce mismatch")
+	}
+	user := &User{Name: p.username(claims), Groups: stringList(claims[p.cfg.GroupsClaim]), Method: "oidc"}
+	if user.Name == "" {
+		return nil, "", errors.New("ID token has no username claim")
+	}
+	return user, state.Redirect, nil
+}
+
+func (p *oidcProvider) exchangeCode(d *oidcDiscovery, code, verifier, redirectURI string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret != "" {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	resp, err := p.client.PostForm(d.TokenEndpoint, form)
+	if err != nil {
+		return "", fmt.Errorf("token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d: %s", resp.StatusCode, string(body))
+	}
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return "", fmt.Errorf("token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+	return tokens.IDToken, nil
+}
+
+// verifyIDToken проверяет подпись (RS256/ES256), issuer, audience и срок действия
+func (p *oidcProvider) verifyIDToken(d *oidcDiscovery, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed ID token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("ID token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("ID token signature: %w", err)
+	}
+	key, err := p.key(d, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	switch header.Alg {
+	case "RS256":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok || rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return nil, errors.New("invalid ID token signature")
+		}
+	case "ES256":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || len(signature) != 64 {
+			return nil, errors.New("invalid ID token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return nil, errors.New("invalid ID token signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported ID token algorithm %q", header.Alg)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("ID token claims: %w", err)
+	}
+	if iss, _ := claims["iss"].(string); strings.TrimSuffix(iss, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", iss)
+	}
+	validAudience := false
+	for _, aud := range stringList(claims["aud"]) {
+		if aud == p.cfg.ClientID {
+			validAudience = true
+		}
+	}
+	if !validAudience {
+		return nil, errors.New("ID token audience does not match client ID")
+	}
+	now := time.Now().Unix()
+	const leeway = 60
+	if exp, ok := claims["exp"].(float64); !ok || int64(exp)+leeway < now {
+		return nil, errors.New("ID token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && int64(nbf)-leeway > now {
+		return nil, errors.New("ID token not yet valid")
+	}
+	return claims, nil
+}
+
+// key возвращает ключ подписи по kid; при неизвестном kid JWKS перечитывается (ротация ключей)
+func (p *oidcProvider) key(d *oidcDiscovery, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	if time.Since(p.keysAt) < 10*time.Second {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	p.keysAt = time.Now()
+	if err := p.getJSON(d.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("jwks: %w", err)
+	}
+	p.keys = make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if key, err := parseJWK(jwk); err == nil {
+			p.keys[jwk.Kid] = key
+		}
+	}
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *oidcProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+	// Токен без kid допустим, если у провайдера один ключ
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+func parseJWK(jwk jsonWebKey) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+}
+
+func (p *oidcProvider) username(claims map[string]interface{}) string {
+	candidates := []string{"preferred_username", "email", "sub"}
+	if p.cfg.UsernameClaim != "" {
+		candidates = append([]string{p.cfg.UsernameClaim}, candidates...)
+	}
+	for _, claim := range candidates {
+		if v, ok := claims[claim].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// stringList приводит claim (строка или массив строк) к списку строк
+func stringList(v interface{}) []string {
+	switch value := v.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,558 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	testClientID     = "dashboard"
+	testClientSecret = "client-secret"
+)
+
+// mockIssuer — локальный OIDC провайдер: discovery, JWKS (RSA и EC ключи),
+// выдача кода авторизации и обмен кода на ID токен с проверкой PKCE
+type mockIssuer struct {
+	*httptest.Server
+
+	mu             sync.Mutex
+	issuer         string // issuer в discovery, по умолчанию адрес сервера
+	rsaKey         *rsa.PrivateKey
+	rsaKid         string
+	ecKey          *ecdsa.PrivateKey
+	codes          map[string]url.Values        // код -> параметры запроса авторизации
+	claims         func(map[string]interface{}) // изменяет claims выдаваемого ID токена
+	discoveryCalls int
+	jwksCalls      int
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockIssuer{rsaKey: rsaKey, rsaKid: "rsa-1", ecKey: ecKey, codes: make(map[string]url.Values)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.discoveryCalls++
+		issuer := m.issuer
+		m.mu.Unlock()
+		if issuer == "" {
+			issuer = m.URL
+		}
+		writeTestJSON(w, oidcDiscovery{
+			Issuer:                issuer,
+			AuthorizationEndpoint: m.URL + "/authorize?tenant=test",
+			TokenEndpoint:         m.URL + "/token",
+			JWKSURI:               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.jwksCalls++
+		keys := []jsonWebKey{
+			{
+				Kty: "RSA", Kid: m.rsaKid,
+				N: base64.RawURLEncoding.EncodeToString(m.rsaKey.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.rsaKey.E)).Bytes()),
+			},
+			{
+				Kty: "EC", Kid: "ec-1", Crv: "P-256",
+				X: base64.RawURLEncoding.EncodeToString(m.ecKey.X.FillBytes(make([]byte, 32))),
+				Y: base64.RawURLEncoding.EncodeToString(m.ecKey.Y.FillBytes(make([]byte, 32))),
+			},
+		}
+		m.mu.Unlock()
+		writeTestJSON(w, map[string]interface{}{"keys": keys})
+	})
+	mux.HandleFunc("/token", m.token)
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+func writeTestJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// authorize имитирует вход пользователя у провайдера и возвращает код авторизации
+func (m *mockIssuer) authorize(params url.Values) string {
+	code := randomString(16)
+	m.mu.Lock()
+	m.codes[code] = params
+	m.mu.Unlock()
+	return code
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.Method != http.MethodPost {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+	form := r.PostForm
+	m.mu.Lock()
+	params, ok := m.codes[form.Get("code")]
+	delete(m.codes, form.Get("code"))
+	modify := m.claims
+	m.mu.Unlock()
+
+	challenge := sha256.Sum256([]byte(form.Get("code_verifier")))
+	if !ok || form.Get("grant_type") != "authorization_code" ||
+		form.Get("redirect_uri") != params.Get("redirect_uri") ||
+		form.Get("client_id") != testClientID || form.Get("client_secret") != testClientSecret ||
+		base64.RawURLEncoding.EncodeToString(challenge[:]) != params.Get("code_challenge") {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims := m.defaultClaims()
+	claims["nonce"] = params.Get("nonce")
+	if modify != nil {
+		modify(claims)
+	}
+	writeTestJSON(w, map[string]string{"access_token": "opaque", "id_token": m.signRS256(claims)})
+}
+
+func (m *mockIssuer) defaultClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":                m.URL,
+		"aud":                testClientID,
+		"sub":                "user-1",
+		"preferred_username": "alice",
+		"groups":             []string{"ops", "dev"},
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+	}
+}
+
+func (m *mockIssuer) signRS256(claims map[string]interface{}) string {
+	m.mu.Lock()
+	key, kid := m.rsaKey, m.rsaKid
+	m.mu.Unlock()
+	return signTestToken(map[string]string{"alg": "RS256", "kid": kid}, claims, rsaSigner(key))
+}
+
+// rotate заменяет RSA ключ провайдера новым ключом с другим kid
+func (m *mockIssuer) rotate(t *testing.T, kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	m.rsaKey, m.rsaKid = key, kid
+	m.mu.Unlock()
+}
+
+func (m *mockIssuer) counts() (discovery, jwks int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.discoveryCalls, m.jwksCalls
+}
+
+func signTestToken(header map[string]string, claims map[string]interface{}, sign func(input []byte) []byte) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(input)))
+}
+
+func rsaSigner(key *rsa.PrivateKey) func([]byte) []byte {
+	return func(input []byte) []byte {
+		digest := sha256.Sum256(input)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			panic(err)
+		}
+		return sig
+	}
+}
+
+func ecSigner(key *ecdsa.PrivateKey) func([]byte) []byte {
+	return func(input []byte) []byte {
+		digest := sha256.Sum256(input)
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			panic(err)
+		}
+		return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+}
+
+func newOIDCAuthenticator(issuer *mockIssuer) *Authenticator {
+	return &Authenticator{
+		tokens:   map[string]string{},
+		sessions: newSessionCodec("session-secret", time.Hour),
+		oidc: newOIDCProvider(oidcConfig{
+			Issuer:       issuer.URL + "/",
+			ClientID:     testClientID,
+			ClientSecret: testClientSecret,
+		}),
+	}
+}
+
+// loginFlow — состояние входа между /auth/login и /auth/callback
+type loginFlow struct {
+	params url.Values   // параметры перенаправления на страницу входа провайдера
+	cookie *http.Cookie // cookie с состоянием входа
+}
+
+func startTestLogin(t *testing.T, e *echo.Echo, redirect string) loginFlow {
+	t.Helper()
+	rec := serve(e, httptest.NewRequest(http.MethodGet, "/auth/login?redirect="+url.QueryEscape(redirect), nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login: status %d: %s", rec.Code, rec.Body.String())
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookie := findCookie(rec, loginCookieName)
+	if cookie == nil {
+		t.Fatal("login state cookie is not set")
+	}
+	return loginFlow{params: location.Query(), cookie: cookie}
+}
+
+func (f loginFlow) callback(e *echo.Echo, query url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/callback?"+query.Encode(), nil)
+	if f.cookie != nil {
+		req.AddCookie(f.cookie)
+	}
+	return serve(e, req)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	issuer := newMockIssuer(t)
+	a := newOIDCAuthenticator(issuer)
+	e := newTestServer(a)
+
+	flow := startTestLogin(t, e, "/containers?project=shop")
+	want := map[string]string{
+		"tenant":                "test",
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          "http://example.com/auth/callback",
+		"scope":                 "openid profile email",
+		"code_challenge_method": "S256",
+	}
+	for key, value := range want {
+		if got := flow.params.Get(key); got != value {
+			t.Errorf("authorization parameter %s = %q, want %q", key, got, value)
+		}
+	}
+	for _, key := range []string{"state", "nonce", "code_challenge"} {
+		if flow.params.Get(key) == "" {
+			t.Errorf("authorization parameter %s is missing", key)
+		}
+	}
+
+	code := issuer.authorize(flow.params)
+	rec := flow.callback(e, url.Values{"state": {flow.params.Get("state")}, "code": {code}})
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/containers?project=shop" {
+		t.Fatalf("callback: status %d, location %q: %s", rec.Code, rec.Header().Get("Location"), rec.Body.String())
+	}
+	session := findCookie(rec, sessionCookieName)
+	if session == nil || !session.HttpOnly {
+		t.Fatalf("session cookie = %+v", session)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/me", nil)
+	req.AddCookie(session)
+	rec = serve(e, req)
+	var me meResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &me); err != nil || me.User == nil {
+		t.Fatalf("me: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if me.Name != "alice" || me.Method != "oidc" || strings.Join(me.Groups, ",") != "ops,dev" || me.CSRFToken == "" {
+		t.Errorf("me = %+v", me)
+	}
+
+	// Код одноразовый: повторный callback с тем же кодом отклоняется
+	rec = flow.callback(e, url.Values{"state": {flow.params.Get("state")}, "code": {code}})
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("reused code: status %d, want 401", rec.Code)
+	}
+}
+
+func TestOIDCCallbackRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(map[string]interface{})
+		query  func(flow loginFlow, code string) url.Values
+		modify func(flow *loginFlow)
+	}{
+		{
+			name:  "state mismatch",
+			query: func(flow loginFlow, code string) url.Values { return url.Values{"state": {"other"}, "code": {code}} },
+		},
+		{
+			name:   "nonce mismatch",
+			claims: func(c map[string]interface{}) { c["nonce"] = "replayed" },
+		},
+		{
+			name:   "wrong PKCE verifier",
+			modify: func(flow *loginFlow) { flow.params.Set("code_challenge", "another-challenge") },
+		},
+		{
+			name:   "missing login state",
+			modify: func(flow *loginFlow) { flow.cookie = nil },
+		},
+		{
+			name:   "forged login state",
+			modify: func(flow *loginFlow) { flow.cookie.Value = newSessionCodec("other", time.Hour).sign([]byte(`{}`)) },
+		},
+		{
+			name: "provider error",
+			query: func(flow loginFlow, code string) url.Values {
+				return url.Values{"state": {flow.params.Get("state")}, "error": {"access_denied"}}
+			},
+		},
+		{
+			name:   "no username",
+			claims: func(c map[string]interface{}) { delete(c, "preferred_username"); delete(c, "sub") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newMockIssuer(t)
+			issuer.claims = tt.claims
+			e := newTestServer(newOIDCAuthenticator(issuer))
+
+			flow := startTestLogin(t, e, "/")
+			if tt.modify != nil {
+				tt.modify(&flow)
+			}
+			code := issuer.authorize(flow.params)
+			query := url.Values{"state": {flow.params.Get("state")}, "code": {code}}
+			if tt.query != nil {
+				query = tt.query(flow, code)
+			}
+			rec := flow.callback(e, query)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("status %d, want 401: %s", rec.Code, rec.Body.String())
+			}
+			if findCookie(rec, sessionCookieName) != nil {
+				t.Error("session cookie is set")
+			}
+		})
+	}
+}
+
+func TestOIDCDiscovery(t *testing.T) {
+	issuer := newMockIssuer(t)
+	p := newOIDCAuthenticator(issuer).oidc
+
+	d, err := p.getDiscovery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.TokenEndpoint != issuer.URL+"/token" || d.JWKSURI != issuer.URL+"/jwks" {
+		t.Errorf("discovery = %+v", d)
+	}
+	if _, err := p.getDiscovery(); err != nil {
+		t.Fatal(err)
+	}
+	if calls, _ := issuer.counts(); calls != 1 {
+		t.Errorf("discovery requested %d times, want 1 (cached)", calls)
+	}
+
+	issuer.mu.Lock()
+	issuer.issuer = "https://evil.example"
+	issuer.mu.Unlock()
+	p = newOIDCAuthenticator(issuer).oidc
+	if _, err := p.getDiscovery(); err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Errorf("err = %v, want issuer mismatch", err)
+	}
+
+	// Провайдер недоступен: вход отвечает 502, а не перенаправляет
+	issuer.Close()
+	e := newTestServer(newOIDCAuthenticator(issuer))
+	if rec := serve(e, httptest.NewRequest(http.MethodGet, "/auth/login", nil)); rec.Code != http.StatusBadGateway {
+		t.Errorf("login with unavailable provider: status %d, want 502", rec.Code)
+	}
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	issuer := newMockIssuer(t)
+	p := newOIDCAuthenticator(issuer).oidc
+	d, err := p.getDiscovery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	with := func(key string, value interface{}) string {
+		claims := issuer.defaultClaims()
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return issuer.signRS256(claims)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		token string
+		err   string // пусто — токен валиден
+	}{
+		{"valid RS256", issuer.signRS256(issuer.defaultClaims()), ""},
+		{"valid ES256", signTestToken(map[string]string{"alg": "ES256", "kid": "ec-1"}, issuer.defaultClaims(), ecSigner(issuer.ecKey)), ""},
+		{"audience list", with("aud", []string{"other", testClientID}), ""},
+		{"expired within leeway", with("exp", now.Add(-30*time.Second).Unix()), ""},
+		{"issuer with trailing slash", with("iss", issuer.URL+"/"), ""},
+		{"wrong issuer", with("iss", "https://evil.example"), "unexpected issuer"},
+		{"wrong audience", with("aud", "other-client"), "audience"},
+		{"missing audience", with("aud", nil), "audience"},
+		{"expired", with("exp", now.Add(-2*time.Minute).Unix()), "expired"},
+		{"missing exp", with("exp", nil), "expired"},
+		{"not yet valid", with("nbf", now.Add(5*time.Minute).Unix()), "not yet valid"},
+		{"signed by another key", signTestToken(map[string]string{"alg": "RS256", "kid": "rsa-1"}, issuer.defaultClaims(), rsaSigner(otherKey)), "invalid ID token signature"},
+		{"RSA key with ES256", signTestToken(map[string]string{"alg": "ES256", "kid": "rsa-1"}, issuer.defaultClaims(), ecSigner(issuer.ecKey)), "invalid ID token signature"},
+		{"alg none", signTestToken(map[string]string{"alg": "none", "kid": "rsa-1"}, issuer.defaultClaims(), func([]byte) []byte { return nil }), "unsupported"},
+		{"HS256", signTestToken(map[string]string{"alg": "HS256", "kid": "rsa-1"}, issuer.defaultClaims(), func([]byte) []byte { return []byte("mac") }), "unsupported"},
+		{"unknown kid", signTestToken(map[string]string{"alg": "RS256", "kid": "rsa-9"}, issuer.defaultClaims(), rsaSigner(issuer.rsaKey)), "unknown signing key"},
+		{"malformed", "not-a-jwt", "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.verifyIDToken(d, tt.token)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if claims["sub"] != "user-1" {
+					t.Errorf("claims = %v", claims)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("err = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	issuer := newMockIssuer(t)
+	p := newOIDCAuthenticator(issuer).oidc
+	d, err := p.getDiscovery()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksCalls := func() int {
+		_, calls := issuer.counts()
+		return calls
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.verifyIDToken(d, issuer.signRS256(issuer.defaultClaims())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := jwksCalls(); got != 1 {
+		t.Fatalf("JWKS requested %d times, want 1 (cached)", got)
+	}
+
+	// Неизвестный kid сразу после загрузки JWKS не приводит к повторному запросу
+	issuer.rotate(t, "rsa-2")
+	rotated := issuer.signRS256(issuer.defaultClaims())
+	if _, err := p.verifyIDToken(d, rotated); err == nil || !strings.Contains(err.Error(), "unknown signing key") {
+		t.Fatalf("err = %v, want unknown signing key", err)
+	}
+	if got := jwksCalls(); got != 1 {
+		t.Fatalf("JWKS requested %d times, want 1", got)
+	}
+
+	p.mu.Lock()
+	p.keysAt = time.Now().Add(-time.Minute)
+	p.mu.Unlock()
+	if _, err := p.verifyIDToken(d, rotated); err != nil {
+		t.Fatalf("token signed with the rotated key: %v", err)
+	}
+	if got := jwksCalls(); got != 2 {
+		t.Errorf("JWKS requested %d times, want 2", got)
+	}
+}
+
+func TestLocalRedirect(t *testing.T) {
+	tests := map[string]string{
+		"":                            "/",
+		"/":                           "/",
+		"/containers?project=shop#x":  "/containers?project=shop#x",
+		"/logs/api%2Fweb":             "/logs/api%2Fweb",
+		"containers":                  "/",
+		"//evil.example":              "/",
+		"/\\evil.example":             "/",
+		"/\\/evil.example":            "/",
+		"\\\\evil.example":            "/",
+		"/\t/evil.example":            "/",
+		"/\n/evil.example":            "/",
+		"https://evil.example/":       "/",
+		"javascript:alert(1)":         "/",
+		"/%zz":                        "/",
+		"/\u0085/evil.example":        "/",
+		"/containers/../\\evil":       "/",
+		"/path?next=//evil.example":   "/path?next=//evil.example",
+		"/path?next=https://evil.com": "/path?next=https://evil.com",
+	}
+	for redirect, want := range tests {
+		if got := localRedirect(redirect); got != want {
+			t.Errorf("localRedirect(%q) = %q, want %q", redirect, got, want)
+		}
+	}
+}
+
+func TestOIDCLoginRedirectIsLocal(t *testing.T) {
+	issuer := newMockIssuer(t)
+	e := newTestServer(newOIDCAuthenticator(issuer))
+
+	flow := startTestLogin(t, e, "/\\evil.example")
+	code := issuer.authorize(flow.params)
+	rec := flow.callback(e, url.Values{"state": {flow.params.Get("state")}, "code": {code}})
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/" {
+		t.Errorf("callback: status %d, location %q; want redirect to /", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestOIDCTokenEndpointError(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"error":"invalid_client"}`)
+	}))
+	defer tokenServer.Close()
+	p := newOIDCProvider(oidcConfig{Issuer: "https://issuer.example", ClientID: testClientID})
+	_, err := p.exchangeCode(&oidcDiscovery{TokenEndpoint: tokenServer.URL}, "code", "verifier", "http://example.com/auth/callback")
+	if err == nil || !strings.Contains(err.Error(), "status 400") || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("err = %v, want status 400 with provider error", err)
+	}
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	sessionCookieName = "dd_session"
+	loginCookieName   = "dd_oidc_login"
+)
+
+// sessionCodec подписывает данные cookie HMAC-SHA256.
+// Сессии не хранятся на сервере: cookie содержит пользователя и срок действия
+type sessionCodec struct {
+	key []byte
+	ttl time.Duration
+}
+
+type sessionPayload struct {
+	User    *User `json:"u"`
+	Expires int64 `json:"e"`
+}
+
+func newSessionCodec(secret string, ttl time.Duration) *sessionCodec {
+	key := []byte(secret)
+	if secret == "" {
+		// Без SESSION_SECRET сессии не переживают перезапуск дашборда
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			log.Fatalf("failed to generate session key: %v", err)
+		}
+	}
+	return &sessionCodec{key: key, ttl: ttl}
+}
+
+func (s *sessionCodec) sign(data []byte) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(data)
+	return base64.RawURLEncoding.EncodeToString(data) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *sessionCodec) verify(value string) ([]byte, bool) {
+	encoded, signature, ok := strings.Cut(value, ".")
+	if !ok {
+		return nil, false
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(data)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, false
+	}
+	return data, true
+}
+
+// encodeValue подписывает произвольное значение со сроком действия
+func (s *sessionCodec) encodeValue(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return s.sign(data), nil
+}
+
+func (s *sessionCodec) decodeValue(value string, v interface{}) bool {
+	data, ok := s.verify(value)
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+func (s *sessionCodec) decode(value string) (*User, bool) {
+	var payload sessionPayload
+	if !s.decodeValue(value, &payload) || payload.User == nil {
+		return nil, false
+	}
+	if time.Now().Unix() > payload.Expires {
+		return nil, false
+	}
+	return payload.User, true
+}
+
+func (s *sessionCodec) setCookie(c echo.Context, user *User) {
+	expires := time.Now().Add(s.ttl)
+	value, err := s.encodeValue(sessionPayload{User: user, Expires: expires.Unix()})
+	if err != nil {
+		log.Printf("[docker-dashboard] failed to encode session: %v", err)
+		return
+	}
+	c.SetCookie(&http.Cookie{
+		Name:     sessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   isSecureRequest(c.Request()),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *sessionCodec) clearCookie(c echo.Context) {
+	c.SetCookie(&http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(c.Request()),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// isSecureRequest учитывает TLS-терминацию на обратном прокси
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("failed to generate random value: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func urlQueryEscape(s string) string {
+	return url.QueryEscape(s)
+}
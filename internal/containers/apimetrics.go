@@ -0,0 +1,126 @@
+package containers
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Границы гистограммы длительности запросов к Docker API (секунды)
+var DockerAPILatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DockerAPIEndpointMetrics — статистика запросов к одному эндпоинту Docker API.
+// Для потоковых запросов длительность считается до получения заголовков ответа
+type DockerAPIEndpointMetrics struct {
+	Method       string
+	Endpoint     string // путь с плейсхолдерами, например "/containers/{id}/json"
+	Requests     uint64
+	Errors       uint64
+	DurationSum  float64
+	BucketCounts []uint64 // накопительные счетчики по DockerAPILatencyBuckets
+}
+
+type apiMetricsKey struct {
+	method   string
+	endpoint string
+}
+
+var dockerAPIMetrics struct {
+	mu        sync.Mutex
+	endpoints map[apiMetricsKey]*DockerAPIEndpointMetrics
+}
+
+var apiVersionPathRe = regexp.MustCompile(`^/v[0-9]+\.[0-9]+`)
+
+// Коллекции Docker API, у которых второй сегмент пути — идентификатор объекта
+var idCollections = map[string]bool{
+	"containers": true, "images": true, "exec": true, "networks": true, "volumes": true,
+}
+
+// endpointLabel заменяет идентификаторы в пути на плейсхолдеры, чтобы не плодить метрики
+func endpointLabel(path string) string {
+	path = apiVersionPathRe.ReplaceAllString(path, "")
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segs) >= 2 && idCollections[segs[0]] {
+		switch segs[1] {
+		case "json", "create", "prune":
+		default:
+			if segs[0] == "images" {
+				// Имя образа может содержать "/", считаем ID все до последнего действия
+				if len(segs) > 2 {
+					segs = []string{segs[0], "{id}", segs[len(segs)-1]}
+				} else {
+					segs = []string{segs[0], "{id}"}
+				}
+			} else {
+				segs[1] = "{id}"
+			}
+		}
+	}
+	return "/" + strings.Join(segs, "/")
+}
+
+func recordDockerAPICall(method, path string, duration time.Duration, failed bool) {
+	key := apiMetricsKey{method: method, endpoint: endpointLabel(path)}
+
+	dockerAPIMetrics.mu.Lock()
+	defer dockerAPIMetrics.mu.Unlock()
+	if dockerAPIMetrics.endpoints == nil {
+		dockerAPIMetrics.endpoints = make(map[apiMetricsKey]*DockerAPIEndpointMetrics)
+	}
+	m, ok := dockerAPIMetrics.endpoints[key]
+	if !ok {
+		m = &DockerAPIEndpointMetrics{
+			Method:       key.method,
+			Endpoint:     key.endpoint,
+			BucketCounts: make([]uint64, len(DockerAPILatencyBuckets)),
+		}
+		dockerAPIMetrics.endpoints[key] = m
+	}
+	seconds := duration.Seconds()
+	m.Requests++
+	m.DurationSum += seconds
+	if failed {
+		m.Errors++
+	}
+	for i, bound := range DockerAPILatencyBuckets {
+		if seconds <= bound {
+			m.BucketCounts[i]++
+		}
+	}
+}
+
+// GetDockerAPIMetrics возвращает копию статистики запросов к Docker API
+func GetDockerAPIMetrics() []DockerAPIEndpointMetrics {
+	dockerAPIMetrics.mu.Lock()
+	defer dockerAPIMetrics.mu.Unlock()
+	result := make([]DockerAPIEndpointMetrics, 0, len(dockerAPIMetrics.endpoints))
+	for _, m := range dockerAPIMetrics.endpoints {
+		c := *m
+		c.BucketCounts = append([]uint64(nil), m.BucketCounts...)
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Endpoint != result[j].Endpoint {
+			return result[i].Endpoint < result[j].Endpoint
+		}
+		return result[i].Method < result[j].Method
+	})
+	return result
+}
+
+// instrumentedTransport учитывает длительность и ошибки запросов к Docker API
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	failed := err != nil || resp.StatusCode >= 400
+	recordDockerAPICall(req.Method, req.URL.Path, time.Since(start), failed)
+	return resp, err
+}
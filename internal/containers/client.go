@@ -0,0 +1,217 @@
+package containers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+// Глобальный HTTP клиент для Docker API для переиспользования соединений
+var (
+	dockerClient       *http.Client
+	dockerStreamClient *http.Client
+	dockerBaseURL      string
+	// Описание подключения для /api/docker/info (unix:///... или tcp://...)
+	dockerHostDescription string
+	// Настройки подключения для запросов с перехватом соединения (exec)
+	dockerEndpointConf *dockerEndpoint
+	dockerEndpointErr  error
+	dockerClientOnce   sync.Once
+)
+
+// dockerEndpoint — разобранные настройки подключения к Docker
+type dockerEndpoint struct {
+	network string // unix или tcp
+	address string // путь к сокету или host:port
+	tls     *tls.Config
+}
+
+// parseDockerEndpoint читает DOCKER_HOST, DOCKER_TLS_VERIFY и DOCKER_CERT_PATH
+// по тем же правилам, что и docker CLI
+func parseDockerEndpoint() (*dockerEndpoint, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = defaultDockerHost
+		// Rootless Docker: сокет в $XDG_RUNTIME_DIR, если системного сокета нет
+		if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+			rootless := filepath.Join(runtimeDir, "docker.sock")
+			if _, err := os.Stat("/var/run/docker.sock"); err != nil {
+				if _, err := os.Stat(rootless); err == nil {
+					host = "unix://" + rootless
+				}
+			}
+		}
+	}
+
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DOCKER_HOST %q: %w", host, err)
+	}
+
+	endpoint := &dockerEndpoint{}
+	switch u.Scheme {
+	case "unix":
+		endpoint.network = "unix"
+		endpoint.address = u.Path
+		if endpoint.address == "" {
+			return nil, fmt.Errorf("invalid DOCKER_HOST %q: empty socket path", host)
+		}
+	case "tcp", "http", "https":
+		endpoint.network = "tcp"
+		endpoint.address = u.Host
+		if endpoint.address == "" {
+			return nil, fmt.Errorf("invalid DOCKER_HOST %q: empty address", host)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported DOCKER_HOST scheme %q", u.Scheme)
+	}
+
+	tlsVerify := os.Getenv("DOCKER_TLS_VERIFY") != ""
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if endpoint.network == "tcp" && (tlsVerify || certPath != "" || u.Scheme == "https") {
+		if certPath == "" {
+			home, _ := os.UserHomeDir()
+			certPath = filepath.Join(home, ".docker")
+		}
+		tlsConfig, err := loadDockerTLSConfig(certPath, tlsVerify, u.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		endpoint.tls = tlsConfig
+	}
+
+	// Порты по умолчанию: 2375 без TLS, 2376 с TLS
+	if endpoint.network == "tcp" && u.Port() == "" {
+		port := "2375"
+		if endpoint.tls != nil {
+			port = "2376"
+		}
+		endpoint.address = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	return endpoint, nil
+}
+
+// loadDockerTLSConfig загружает ca.pem, cert.pem и key.pem из каталога сертификатов.
+// Без DOCKER_TLS_VERIFY сертификат сервера не проверяется (как в docker CLI)
+func loadDockerTLSConfig(certPath string, verify bool, serverName string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         serverName,
+		InsecureSkipVerify: !verify,
+	}
+
+	caFile := filepath.Join(certPath, "ca.pem")
+	if caPEM, err := os.ReadFile(caFile); err == nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("failed to parse CA certificate %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	} else if verify {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	certFile := filepath.Join(certPath, "cert.pem")
+	keyFile := filepath.Join(certPath, "key.pem")
+	if _, err := os.Stat(certFile); err == nil {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+func getDockerClient() *http.Client {
+	dockerClientOnce.Do(func() {
+		tr := &http.Transport{
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+		}
+
+		endpoint, err := parseDockerEndpoint()
+		dockerEndpointConf, dockerEndpointErr = endpoint, err
+		if err != nil {
+			// Ошибка конфигурации возвращается при каждом запросе к Docker
+			log.Printf("[docker-dashboard] Docker endpoint configuration error: %v", err)
+			tr.DialContext = func(_ context.Context, _, _ string) (net.Conn, error) {
+				return nil, err
+			}
+			dockerBaseURL = "http://docker"
+		} else {
+			dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+			tr.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, endpoint.network, endpoint.address)
+			}
+			switch {
+			case endpoint.network == "unix":
+				dockerBaseURL = "http://unix"
+			case endpoint.tls != nil:
+				tr.TLSClientConfig = endpoint.tls
+				dockerBaseURL = "https://" + endpoint.address
+			default:
+				dockerBaseURL = "http://" + endpoint.address
+			}
+			dockerHostDescription = endpoint.network + "://" + endpoint.address
+			log.Printf("[docker-dashboard] Docker endpoint: %s (tls: %t)", dockerHostDescription, endpoint.tls != nil)
+		}
+
+		instrumented := &instrumentedTransport{next: tr}
+		dockerClient = &http.Client{
+			Transport: instrumented,
+			Timeout:   5 * time.Second,
+		}
+		// Клиент без таймаута для долгоживущих потоков (events, logs)
+		dockerStreamClient = &http.Client{
+			Transport: instrumented,
+		}
+	})
+	return dockerClient
+}
+
+// getDockerStreamClient возвращает клиент без общего таймаута для потоковых запросов
+func getDockerStreamClient() *http.Client {
+	getDockerClient()
+	return dockerStreamClient
+}
+
+// DockerClient возвращает общий HTTP клиент Docker API с коротким таймаутом
+func DockerClient() *http.Client {
+	return getDockerClient()
+}
+
+// DockerStreamClient возвращает общий HTTP клиент Docker API без таймаута
+// для потоковых и долгих запросов; время жизни запроса задается через context
+func DockerStreamClient() *http.Client {
+	return getDockerStreamClient()
+}
+
+// DockerURL возвращает полный URL Docker API для указанного пути (например "/containers/json")
+// с префиксом согласованной версии API. Если версию согласовать не удалось, возвращается ошибка
+func DockerURL(path string) (string, error) {
+	getDockerClient()
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	prefix, err := apiVersionPrefix()
+	if err != nil {
+		return "", err
+	}
+	return dockerBaseURL + prefix + path, nil
+}
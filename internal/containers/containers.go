@@ -1,12 +1,11 @@
 package containers
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net"
 	"net/http"
 	"os"
 	"strconv"
@@ -15,28 +14,10 @@ import (
 	"time"
 )
 
-// Глобальный HTTP клиент для Docker API для переиспользования соединений
-var (
-	dockerClient     *http.Client
-	dockerClientOnce sync.Once
-)
-
-// Кэш для результатов GetContainers
-type containersCache struct {
-	data      []Container
-	expiresAt time.Time
-	mu        sync.RWMutex
-}
-
-var (
-	containersCacheInstance *containersCache
-	containersCacheOnce     sync.Once
-)
-
 // Семафор для ограничения параллелизма запросов к Docker API
 var (
 	requestSemaphore chan struct{}
-	semaphoreOnce     sync.Once
+	semaphoreOnce    sync.Once
 )
 
 func initSemaphore() {
@@ -52,80 +33,39 @@ func initSemaphore() {
 	})
 }
 
-func getContainersCache() *containersCache {
-	containersCacheOnce.Do(func() {
-		containersCacheInstance = &containersCache{}
-	})
-	return containersCacheInstance
-}
-
-func (c *containersCache) get() ([]Container, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	if c.data == nil || time.Now().After(c.expiresAt) {
-		return nil, false
-	}
-	// Возвращаем копию данных
-	result := make([]Container, len(c.data))
-	copy(result, c.data)
-	return result, true
-}
-
-func (c *containersCache) set(data []Container, ttl time.Duration) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.data = make([]Container, len(data))
-	copy(c.data, data)
-	c.expiresAt = time.Now().Add(ttl)
-}
-
-func (c *containersCache) clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.data = nil
-	c.expiresAt = time.Time{}
-}
-
-func getDockerClient() *http.Client {
-	dockerClientOnce.Do(func() {
-		tr := &http.Transport{
-			DialContext: func(_ context.Context, _, _ string) (net.Conn, error) {
-				return net.Dial("unix", "/var/run/docker.sock")
-			},
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 10,
-			IdleConnTimeout:     90 * time.Second,
-		}
-		dockerClient = &http.Client{
-			Transport: tr,
-			Timeout:   5 * time.Second,
-		}
-	})
-	return dockerClient
-}
-
 type DeployResources struct {
 	CPULimit          string `json:"CPULimit,omitempty"`
 	MemoryLimit       string `json:"MemoryLimit,omitempty"`
 	CPUReservation    string `json:"CPUReservation,omitempty"`
 	MemoryReservation string `json:"MemoryReservation,omitempty"`
+
+	// Те же значения в числовом виде (для метрик и алертов)
+	CPULimitCores          float64 `json:"CPULimitCores,omitempty"`
+	MemoryLimitBytes       int64   `json:"MemoryLimitBytes,omitempty"`
+	MemoryReservationBytes int64   `json:"MemoryReservationBytes,omitempty"`
 }
 
 type Container struct {
-	ID             string            `json:"ID"`
-	Name           string            `json:"Name"`
-	Image          string            `json:"Image"`
-	TagCommit      string            `json:"TagCommit"`
-	ImageCreatedAt string            `json:"ImageCreatedAt"`
-	CreatedAt      string            `json:"CreatedAt"`
-	Uptime         string            `json:"Uptime"`
-	State          string            `json:"State"`
-	Health         string            `json:"Health"`
-	Run            bool              `json:"Run"`
-	Restart        bool              `json:"Restart"`
-	Labels         map[string]string `json:"Labels"`
-	ComposeProject string            `json:"ComposeProject,omitempty"`
-	DeployResources *DeployResources `json:"DeployResources,omitempty"`
+	ID              string            `json:"ID"`
+	FullID          string            `json:"-"` // полный ID для запросов к Docker API и аудита
+	Name            string            `json:"Name"`
+	Image           string            `json:"Image"`
+	TagCommit       string            `json:"TagCommit"`
+	ImageCreatedAt  string            `json:"ImageCreatedAt"`
+	CreatedAt       string            `json:"CreatedAt"`
+	Uptime          string            `json:"Uptime"`
+	State           string            `json:"State"`
+	Health          string            `json:"Health"`
+	Run             bool              `json:"Run"`
+	Restart         bool              `json:"Restart"`
+	RestartCount    int               `json:"RestartCount"`
+	StartedAt       string            `json:"StartedAt,omitempty"`
+	ExitCode        int               `json:"ExitCode"`
+	Labels          map[string]string `json:"Labels"`
+	AllLabels       map[string]string `json:"-"` // все метки без учета LABEL_PREFIX (для RBAC селекторов)
+	Tty             bool              `json:"-"` // логи без мультиплексирования stdout/stderr
+	ComposeProject  string            `json:"ComposeProject,omitempty"`
+	DeployResources *DeployResources  `json:"DeployResources,omitempty"`
 }
 
 type dockerAPIContainer struct {
@@ -140,6 +80,9 @@ type dockerAPIContainer struct {
 }
 
 type dockerContainerInspect struct {
+	ID      string `json:"Id"`
+	Name    string `json:"Name"`
+	Image   string `json:"Image"`
 	Created string `json:"Created"`
 	State   struct {
 		Status     string `json:"Status"`
@@ -151,10 +94,12 @@ type dockerContainerInspect struct {
 		} `json:"Health"`
 		Restarting   bool `json:"Restarting"`
 		RestartCount int  `json:"RestartCount"`
+		ExitCode     int  `json:"ExitCode"`
 	} `json:"State"`
 	Config struct {
 		Labels map[string]string `json:"Labels"`
 		Image  string            `json:"Image"`
+		Tty    bool              `json:"Tty"`
 	} `json:"Config"`
 	HostConfig struct {
 		Memory            int64 `json:"Memory"`
@@ -171,13 +116,14 @@ type dockerImageInspect struct {
 }
 
 type dockerStats struct {
+	Read     time.Time `json:"read"`
 	CPUStats struct {
 		CPUUsage struct {
 			TotalUsage uint64   `json:"total_usage"`
 			Percpu     []uint64 `json:"percpu_usage,omitempty"`
 		} `json:"cpu_usage"`
 		SystemCPUUsage uint64 `json:"system_cpu_usage"`
-		OnlineCPUs      uint32 `json:"online_cpus"`
+		OnlineCPUs     uint32 `json:"online_cpus"`
 	} `json:"cpu_stats"`
 	PreCPUStats struct {
 		CPUUsage struct {
@@ -186,15 +132,49 @@ type dockerStats struct {
 		SystemCPUUsage uint64 `json:"system_cpu_usage"`
 	} `json:"precpu_stats"`
 	MemoryStats struct {
-		Usage uint64 `json:"usage"`
-		Limit uint64 `json:"limit"`
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
 	} `json:"memory_stats"`
+	PidsStats struct {
+		Current uint64 `json:"current"`
+	} `json:"pids_stats"`
+	BlkioStats struct {
+		IoServiceBytesRecursive []struct {
+			Op    string `json:"op"`
+			Value uint64 `json:"value"`
+		} `json:"io_service_bytes_recursive"`
+	} `json:"blkio_stats"`
+	Networks map[string]struct {
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"networks"`
+}
+
+// NetworkStats — сетевая статистика одного интерфейса контейнера
+type NetworkStats struct {
+	Interface string  `json:"Interface"`
+	RxBytes   uint64  `json:"RxBytes"`
+	TxBytes   uint64  `json:"TxBytes"`
+	RxRate    float64 `json:"RxRate"` // bytes/s
+	TxRate    float64 `json:"TxRate"` // bytes/s
 }
 
 type ContainerStats struct {
-	ID          string  `json:"ID"`
-	CPUUsage    float64 `json:"CPUUsage"`    // CPU usage in cores
-	MemoryUsage int64   `json:"MemoryUsage"` // Memory usage in bytes
+	ID             string         `json:"ID"`
+	CPUUsage       float64        `json:"CPUUsage"`       // CPU usage in cores
+	MemoryUsage    int64          `json:"MemoryUsage"`    // Memory working set in bytes (without page cache)
+	MemoryRawUsage int64          `json:"MemoryRawUsage"` // Memory usage in bytes as reported by Docker (with page cache)
+	MemoryLimit    int64          `json:"MemoryLimit"`    // Memory limit in bytes (host memory if no limit set)
+	MemoryPercent  float64        `json:"MemoryPercent"`  // Working set as percent of limit
+	NetworkRxBytes uint64         `json:"NetworkRxBytes"` // Sum over all interfaces
+	NetworkTxBytes uint64         `json:"NetworkTxBytes"`
+	NetworkRxRate  float64        `json:"NetworkRxRate"` // bytes/s
+	NetworkTxRate  float64        `json:"NetworkTxRate"` // bytes/s
+	Networks       []NetworkStats `json:"Networks,omitempty"`
+	BlockRead      uint64         `json:"BlockRead"`  // bytes
+	BlockWrite     uint64         `json:"BlockWrite"` // bytes
+	PIDs           uint64         `json:"PIDs"`
 }
 
 func filterLabels(labels map[string]string) map[string]string {
@@ -278,6 +258,13 @@ func parseResources(inspect dockerContainerInspect) *DeployResources {
 
 	// Parse Memory limit
 	resources.MemoryLimit = formatMemory(inspect.HostConfig.Memory)
+	resources.MemoryLimitBytes = inspect.HostConfig.Memory
+	resources.MemoryReservationBytes = inspect.HostConfig.MemoryReservation
+	if inspect.HostConfig.NanoCpus > 0 {
+		resources.CPULimitCores = float64(inspect.HostConfig.NanoCpus) / 1e9
+	} else if inspect.HostConfig.CpuQuota > 0 && inspect.HostConfig.CpuPeriod > 0 {
+		resources.CPULimitCores = float64(inspect.HostConfig.CpuQuota) / float64(inspect.HostConfig.CpuPeriod)
+	}
 
 	// Parse CPU reservation (same logic as limit, but typically not set separately in HostConfig)
 	// For now, we'll leave it empty unless there's a specific field
@@ -295,362 +282,150 @@ func parseResources(inspect dockerContainerInspect) *DeployResources {
 	return resources
 }
 
+// GetContainers возвращает текущее состояние контейнеров из модели,
+// которая поддерживается в актуальном состоянии потоком Docker /events
 func GetContainers() ([]Container, error) {
-	// Инициализируем семафор
-	initSemaphore()
+	store := getContainerStore()
+	store.startWatcher()
 
-	// Проверяем кэш
-	cache := getContainersCache()
-	cacheTTL := 2 * time.Second // TTL кэша 2 секунды
-	if cached, ok := cache.get(); ok {
-		log.Printf("[docker-dashboard] GetContainers: returning cached data")
-		return cached, nil
+	// Пока поток событий не синхронизирован, заполняем модель сами
+	if !store.isSynced() {
+		if err := store.resync(); err != nil {
+			return nil, err
+		}
 	}
+	return store.snapshot(), nil
+}
 
-	debug := false
-	if os.Getenv("DEBUG") == "true" {
-		debug = true
-	}
-	log.Println("[docker-dashboard] GetContainers: start (net/http raw)")
-	client := getDockerClient()
-	url := "http://unix/containers/json?all=1"
-	log.Printf("[docker-dashboard] GET %s", url)
-	resp, err := client.Get(url)
+// GetContainer возвращает контейнер по полному или короткому ID либо по имени
+func GetContainer(idOrName string) (*Container, error) {
+	list, err := GetContainers()
 	if err != nil {
-		log.Printf("[docker-dashboard] http.Get error: %v", err)
-		cache.clear() // Очищаем кэш при ошибке
-		return nil, err
-	}
-	defer resp.Body.Close()
-	log.Printf("[docker-dashboard] status: %d", resp.StatusCode)
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("[docker-dashboard] read body error: %v", err)
-		cache.clear()
-		return nil, err
-	}
-	if debug {
-		log.Printf("[docker-dashboard] raw body: %s", string(body))
-	}
-	if resp.StatusCode != 200 {
-		cache.clear()
-		return nil, fmt.Errorf("docker API status %d", resp.StatusCode)
-	}
-	var apiContainers []dockerAPIContainer
-	if err := json.Unmarshal(body, &apiContainers); err != nil {
-		log.Printf("[docker-dashboard] json.Unmarshal error: %v", err)
-		cache.clear()
 		return nil, err
 	}
-	log.Printf("[docker-dashboard] containers found: %d", len(apiContainers))
-
-	type containerResult struct {
-		container Container
-		index     int
-		err       error
+	idOrName = strings.TrimPrefix(idOrName, "/")
+	for _, c := range list {
+		if c.ID == idOrName || c.Name == idOrName || (len(idOrName) > 12 && strings.HasPrefix(idOrName, c.ID)) {
+			result := c
+			return &result, nil
+		}
 	}
+	return nil, errContainerNotFound
+}
 
-	resultChan := make(chan containerResult, len(apiContainers))
-	var wg sync.WaitGroup
-
-	for i, c := range apiContainers {
-		wg.Add(1)
-		go func(idx int, container dockerAPIContainer) {
-			defer wg.Done()
-
-			// Ограничиваем параллелизм через семафор
-			requestSemaphore <- struct{}{} // Захватываем семафор
-			defer func() { <-requestSemaphore }() // Освобождаем семафор
-
-			name := ""
-			if len(container.Names) > 0 {
-				name = strings.TrimLeft(container.Names[0], "/")
-			}
-
-			// Получаем подробную информацию о контейнере
-			inspectURL := fmt.Sprintf("http://unix/containers/%s/json", container.ID)
-			inspectResp, err := client.Get(inspectURL)
-			if err != nil {
-				log.Printf("[docker-dashboard] inspect error: %v", err)
-				resultChan <- containerResult{err: err, index: idx}
-				return
-			}
-			inspectBody, err := io.ReadAll(inspectResp.Body)
-			inspectResp.Body.Close()
-			if err != nil {
-				log.Printf("[docker-dashboard] inspect read error: %v", err)
-				resultChan <- containerResult{err: err, index: idx}
-				return
-			}
-			var inspect dockerContainerInspect
-			if err := json.Unmarshal(inspectBody, &inspect); err != nil {
-				log.Printf("[docker-dashboard] inspect unmarshal error: %v", err)
-				resultChan <- containerResult{err: err, index: idx}
-				return
-			}
-
-			// Получаем информацию об образе (только один раз)
-			imageCreatedAt := ""
-			tagCommit := ""
-			if v, ok := container.Labels["org.quickex.frontend.commit"]; ok && v != "" {
-				tagCommit = v
-			}
-
-			if container.ImageID != "" && tagCommit == "" {
-				// Запрос образа выполняется в той же горутине, семафор уже захвачен
-				imageURL := fmt.Sprintf("http://unix/images/%s/json", container.ImageID)
-				imageResp, err := client.Get(imageURL)
-				if err == nil {
-					imageBody, err := io.ReadAll(imageResp.Body)
-					imageResp.Body.Close()
-					if err == nil {
-						var imageInfo dockerImageInspect
-						if err := json.Unmarshal(imageBody, &imageInfo); err == nil {
-							imageCreatedAt = imageInfo.Created
-							if len(imageInfo.RepoTags) > 0 {
-								tagCommit = imageInfo.RepoTags[0]
-							}
-						}
-					}
-				}
-			}
-
-			// create image/create container — ISO8601 (оригинал)
-			createdAt := inspect.Created
-			imageCreated := imageCreatedAt
-
-			// uptime — человекочитаемый (29h26m2s)
-			uptimeVal := ""
-			if inspect.State.StartedAt != "" && inspect.State.Status == "running" {
-				start, err := time.Parse(time.RFC3339Nano, inspect.State.StartedAt)
-				if err == nil {
-					dur := time.Since(start)
-					uptimeVal = dur.Truncate(time.Second).String()
-				}
-			}
-
-			// restart — bool
-			restart := false
-			if inspect.State.RestartCount > 0 {
-				restart = true
-			}
-
-			health := ""
-			if inspect.State.Health != nil {
-				health = inspect.State.Health.Status
-			}
-			shortID := container.ID
-			if len(shortID) > 12 {
-				shortID = shortID[:12]
-			}
-			filteredLabels := filterLabels(container.Labels)
-
-			// Извлекаем compose project до фильтрации меток
-			composeProject := ""
-			if container.Labels != nil {
-				if project, ok := container.Labels["com.docker.compose.project"]; ok {
-					composeProject = project
-				}
-			}
-
-			// Парсим ресурсы deploy
-			deployResources := parseResources(inspect)
-
-			resultChan <- containerResult{
-				container: Container{
-					ID:             shortID,
-					Name:           name,
-					Image:          container.Image,
-					TagCommit:      tagCommit,
-					ImageCreatedAt: imageCreated,
-					CreatedAt:      createdAt,
-					Uptime:         uptimeVal,
-					State:          inspect.State.Status,
-					Health:         health,
-					Run:            inspect.State.Running,
-					Restart:        restart,
-					Labels:         filteredLabels,
-					ComposeProject: composeProject,
-					DeployResources: deployResources,
-				},
-				index: idx,
-			}
-		}(i, c)
-	}
+// IsNotFound сообщает, что контейнер не найден
+func IsNotFound(err error) bool {
+	return errors.Is(err, errContainerNotFound)
+}
 
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
+// inspectContainer запрашивает подробную информацию о контейнере и его образе
+// и собирает из нее запись для модели контейнеров
+func (s *containerStore) inspectContainer(id string) (*containerRecord, error) {
+	client := getDockerClient()
 
-	// Собираем результаты с сохранением порядка
-	results := make([]*Container, len(apiContainers))
-	for res := range resultChan {
-		if res.err == nil {
-			results[res.index] = &res.container
-		}
-	}
+	// Ограничиваем параллелизм через семафор
+	initSemaphore()
+	requestSemaphore <- struct{}{}
+	defer func() { <-requestSemaphore }()
 
-	// Фильтруем nil значения и формируем финальный результат
-	result := make([]Container, 0, len(apiContainers))
-	for _, res := range results {
-		if res != nil {
-			result = append(result, *res)
-		}
+	inspectURL, err := DockerURL(fmt.Sprintf("/containers/%s/json", id))
+	if err != nil {
+		return nil, fmt.Errorf("inspect error: %w", err)
 	}
-
-	// Сохраняем в кэш только при успешном результате
-	if len(result) > 0 || len(apiContainers) == 0 {
-		cache.set(result, cacheTTL)
-	} else {
-		cache.clear()
+	inspectResp, err := client.Get(inspectURL)
+	if err != nil {
+		return nil, fmt.Errorf("inspect error: %w", err)
 	}
-
-	return result, nil
-}
-
-// GetContainerStats получает статистику использования CPU и RAM для контейнера
-func GetContainerStats(containerID string) (*ContainerStats, error) {
-	client := getDockerClient()
-	
-	// Делаем первый запрос для получения базовой статистики
-	statsURL1 := fmt.Sprintf("http://unix/containers/%s/stats?stream=false&one-shot=true", containerID)
-	resp1, err := client.Get(statsURL1)
+	inspectBody, err := io.ReadAll(inspectResp.Body)
+	inspectResp.Body.Close()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get first stats: %w", err)
+		return nil, fmt.Errorf("inspect read error: %w", err)
 	}
-	defer resp1.Body.Close()
-
-	if resp1.StatusCode != 200 {
-		return nil, fmt.Errorf("docker stats API returned status %d", resp1.StatusCode)
+	if inspectResp.StatusCode == http.StatusNotFound {
+		return nil, errContainerNotFound
 	}
-
-	var stats1 dockerStats
-	if err := json.NewDecoder(resp1.Body).Decode(&stats1); err != nil {
-		return nil, fmt.Errorf("failed to decode first stats: %w", err)
+	if inspectResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("docker API status %d", inspectResp.StatusCode)
 	}
-
-	// Ждем 1 секунду для получения дельты CPU
-	time.Sleep(1 * time.Second)
-
-	// Делаем второй запрос
-	statsURL2 := fmt.Sprintf("http://unix/containers/%s/stats?stream=false&one-shot=true", containerID)
-	resp2, err := client.Get(statsURL2)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get second stats: %w", err)
+	var inspect dockerContainerInspect
+	if err := json.Unmarshal(inspectBody, &inspect); err != nil {
+		return nil, fmt.Errorf("inspect unmarshal error: %w", err)
 	}
-	defer resp2.Body.Close()
 
-	if resp2.StatusCode != 200 {
-		return nil, fmt.Errorf("docker stats API returned status %d", resp2.StatusCode)
-	}
+	labels := inspect.Config.Labels
 
-	var stats2 dockerStats
-	if err := json.NewDecoder(resp2.Body).Decode(&stats2); err != nil {
-		return nil, fmt.Errorf("failed to decode second stats: %w", err)
+	// Получаем информацию об образе (кэшируется по ID образа)
+	imageCreatedAt := ""
+	tagCommit := ""
+	if v, ok := labels["org.quickex.frontend.commit"]; ok && v != "" {
+		tagCommit = v
 	}
-
-	// Вычисляем CPU usage в ядрах используя два снимка
-	var cpuCores float64
-	
-	if stats1.CPUStats.SystemCPUUsage > 0 && stats2.CPUStats.SystemCPUUsage > stats1.CPUStats.SystemCPUUsage {
-		cpuDelta := float64(stats2.CPUStats.CPUUsage.TotalUsage - stats1.CPUStats.CPUUsage.TotalUsage)
-		systemDelta := float64(stats2.CPUStats.SystemCPUUsage - stats1.CPUStats.SystemCPUUsage)
-		
-		if systemDelta > 0 && stats2.CPUStats.OnlineCPUs > 0 {
-			// Вычисляем процент использования CPU
-			cpuPercent := (cpuDelta / systemDelta) * float64(stats2.CPUStats.OnlineCPUs) * 100.0
-			// Конвертируем процент в количество ядер
-			cpuCores = cpuPercent / 100.0
-			// Ограничиваем количеством доступных ядер
-			if cpuCores > float64(stats2.CPUStats.OnlineCPUs) {
-				cpuCores = float64(stats2.CPUStats.OnlineCPUs)
-			}
-			// Не показываем отрицательные значения
-			if cpuCores < 0 {
-				cpuCores = 0
+	if inspect.Image != "" && tagCommit == "" {
+		if imageInfo, ok := s.imageInfo(inspect.Image); ok {
+			imageCreatedAt = imageInfo.Created
+			if len(imageInfo.RepoTags) > 0 {
+				tagCommit = imageInfo.RepoTags[0]
 			}
 		}
 	}
 
-	// Получаем использование памяти из первого снимка (как было раньше)
-	memoryUsage := int64(stats1.MemoryStats.Usage)
-
-	return &ContainerStats{
-		ID:          containerID,
-		CPUUsage:    cpuCores,
-		MemoryUsage: memoryUsage,
-	}, nil
-}
-
-// GetContainersStats получает статистику для всех запущенных контейнеров
-func GetContainersStats() ([]ContainerStats, error) {
-	client := getDockerClient()
-	url := "http://unix/containers/json?all=1"
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get containers list: %w", err)
+	var startedAt time.Time
+	if inspect.State.StartedAt != "" && inspect.State.Status == "running" {
+		if start, err := time.Parse(time.RFC3339Nano, inspect.State.StartedAt); err == nil {
+			startedAt = start
+		}
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("docker API status %d", resp.StatusCode)
+	startedAtValue := ""
+	if !startedAt.IsZero() {
+		startedAtValue = inspect.State.StartedAt
 	}
-
-	var apiContainers []dockerAPIContainer
-	if err := json.NewDecoder(resp.Body).Decode(&apiContainers); err != nil {
-		return nil, fmt.Errorf("failed to decode containers: %w", err)
+	var created time.Time
+	if t, err := time.Parse(time.RFC3339Nano, inspect.Created); err == nil {
+		created = t
 	}
 
-	type statsResult struct {
-		stats *ContainerStats
-		err   error
+	health := ""
+	if inspect.State.Health != nil {
+		health = inspect.State.Health.Status
 	}
-
-	statsChan := make(chan statsResult, len(apiContainers))
-	var wg sync.WaitGroup
-
-	for _, apiContainer := range apiContainers {
-		// Получаем статистику только для запущенных контейнеров
-		if apiContainer.State != "running" {
-			continue
-		}
-
-		wg.Add(1)
-		go func(containerID string) {
-			defer wg.Done()
-			// Используем полный ID для получения статистики
-			stats, err := GetContainerStats(containerID)
-			if err != nil {
-				// Игнорируем ошибки для остановленных контейнеров
-				if strings.Contains(err.Error(), "No such container") ||
-					strings.Contains(err.Error(), "is not running") {
-					return
-				}
-				log.Printf("[docker-dashboard] Failed to get stats for container %s: %v", containerID, err)
-				return
-			}
-			// Используем короткий ID для совместимости с фронтендом
-			if len(containerID) > 12 {
-				stats.ID = containerID[:12]
-			} else {
-				stats.ID = containerID
-			}
-			statsChan <- statsResult{stats: stats, err: nil}
-		}(apiContainer.ID)
+	shortID := inspect.ID
+	if len(shortID) > 12 {
+		shortID = shortID[:12]
 	}
 
-	go func() {
-		wg.Wait()
-		close(statsChan)
-	}()
-
-	var result []ContainerStats
-	for res := range statsChan {
-		if res.stats != nil {
-			result = append(result, *res.stats)
+	// Извлекаем compose project до фильтрации меток
+	composeProject := ""
+	if labels != nil {
+		if project, ok := labels["com.docker.compose.project"]; ok {
+			composeProject = project
 		}
 	}
 
-	return result, nil
+	return &containerRecord{
+		fullID:    inspect.ID,
+		imageID:   inspect.Image,
+		created:   created,
+		startedAt: startedAt,
+		container: Container{
+			ID:              shortID,
+			FullID:          inspect.ID,
+			Name:            strings.TrimLeft(inspect.Name, "/"),
+			Image:           inspect.Config.Image,
+			TagCommit:       tagCommit,
+			ImageCreatedAt:  imageCreatedAt,
+			CreatedAt:       inspect.Created,
+			State:           inspect.State.Status,
+			Health:          health,
+			Run:             inspect.State.Running,
+			Restart:         inspect.State.RestartCount > 0,
+			RestartCount:    inspect.State.RestartCount,
+			StartedAt:       startedAtValue,
+			ExitCode:        inspect.State.ExitCode,
+			Labels:          filterLabels(labels),
+			AllLabels:       labels,
+			Tty:             inspect.Config.Tty,
+			ComposeProject:  composeProject,
+			DeployResources: parseResources(inspect),
+		},
+	}, nil
 }
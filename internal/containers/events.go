@@ -0,0 +1,479 @@
+package containers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errContainerNotFound = errors.New("no such container")
+
+// dockerEvent — сообщение из потока Docker /events
+type dockerEvent struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	TimeNano int64 `json:"timeNano"`
+}
+
+// События контейнеров, после которых нужно перечитать состояние контейнера.
+// health_status приходит в виде "health_status: healthy", поэтому сравниваем по префиксу
+var refreshActions = []string{
+	"create", "start", "restart", "die", "stop", "kill", "oom",
+	"pause", "unpause", "rename", "update", "health_status",
+}
+
+// containerRecord — запись модели контейнеров
+type containerRecord struct {
+	fullID    string
+	imageID   string
+	created   time.Time
+	startedAt time.Time
+	container Container
+}
+
+// Сколько хранится отметка об удалении контейнера: дольше любого inspect,
+// начатого до удаления (у клиента Docker таймаут 5s на запрос)
+const removedTTL = time.Minute
+
+// itemVersion — номер операции, последней изменившей запись контейнера
+type itemVersion struct {
+	seq       uint64
+	removedAt time.Time // не нулевое — контейнер удален
+}
+
+// containerStore — модель контейнеров в памяти.
+// Полностью заполняется при старте (и после разрыва потока событий),
+// далее обновляются только контейнеры, которых касаются события Docker.
+//
+// Обновления одного контейнера могут выполняться одновременно (события Docker и Refresh
+// после действий), поэтому каждой операции выдается номер seq: результат inspect,
+// начатого раньше последнего примененного обновления или удаления, отбрасывается
+type containerStore struct {
+	mu       sync.RWMutex
+	items    map[string]*containerRecord // по полному ID
+	synced   bool
+	seq      uint64
+	floor    uint64                 // номер последней полной синхронизации
+	versions map[string]itemVersion // по полному ID, только изменения после floor
+
+	// resyncMu не дает выполнять несколько полных перечитываний одновременно
+	resyncMu sync.Mutex
+
+	imagesMu sync.Mutex
+	images   map[string]dockerImageInspect // по ID образа
+
+	watcherOnce sync.Once
+}
+
+var (
+	containerStoreInstance *containerStore
+	containerStoreOnce     sync.Once
+)
+
+func getContainerStore() *containerStore {
+	containerStoreOnce.Do(func() {
+		containerStoreInstance = &containerStore{
+			items:    make(map[string]*containerRecord),
+			versions: make(map[string]itemVersion),
+			images:   make(map[string]dockerImageInspect),
+		}
+	})
+	return containerStoreInstance
+}
+
+func (s *containerStore) isSynced() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.synced
+}
+
+func (s *containerStore) markUnsynced() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.synced = false
+}
+
+// snapshot возвращает копию модели в порядке Docker API (новые контейнеры первыми)
+func (s *containerStore) snapshot() []Container {
+	s.mu.RLock()
+	records := make([]*containerRecord, 0, len(s.items))
+	for _, rec := range s.items {
+		records = append(records, rec)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].created.Equal(records[j].created) {
+			return records[i].fullID < records[j].fullID
+		}
+		return records[i].created.After(records[j].created)
+	})
+
+	now := time.Now()
+	result := make([]Container, 0, len(records))
+	for _, rec := range records {
+		c := rec.container
+		// uptime — человекочитаемый (29h26m2s), считается в момент чтения
+		if !rec.startedAt.IsZero() {
+			c.Uptime = now.Sub(rec.startedAt).Truncate(time.Second).String()
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// imageInfo возвращает информацию об образе, запрашивая ее у Docker только один раз
+func (s *containerStore) imageInfo(imageID string) (dockerImageInspect, bool) {
+	s.imagesMu.Lock()
+	info, ok := s.images[imageID]
+	s.imagesMu.Unlock()
+	if ok {
+		return info, true
+	}
+
+	imageURL, err := DockerURL(fmt.Sprintf("/images/%s/json", imageID))
+	if err != nil {
+		return info, false
+	}
+	resp, err := getDockerClient().Get(imageURL)
+	if err != nil {
+		return info, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return info, false
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return info, false
+	}
+
+	s.imagesMu.Lock()
+	s.images[imageID] = info
+	s.imagesMu.Unlock()
+	return info, true
+}
+
+// resync полностью перечитывает список контейнеров
+func (s *containerStore) resync() error {
+	s.resyncMu.Lock()
+	defer s.resyncMu.Unlock()
+
+	log.Println("[docker-dashboard] containers resync: start")
+	start := s.nextSeq()
+	listURL, err := DockerURL("/containers/json?all=1")
+	if err != nil {
+		log.Printf("[docker-dashboard] containers resync: %v", err)
+		return err
+	}
+	resp, err := getDockerClient().Get(listURL)
+	if err != nil {
+		log.Printf("[docker-dashboard] http.Get error: %v", err)
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Printf("[docker-dashboard] read body error: %v", err)
+		return err
+	}
+	if os.Getenv("DEBUG") == "true" {
+		log.Printf("[docker-dashboard] raw body: %s", string(body))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker API status %d", resp.StatusCode)
+	}
+	var apiContainers []dockerAPIContainer
+	if err := json.Unmarshal(body, &apiContainers); err != nil {
+		log.Printf("[docker-dashboard] json.Unmarshal error: %v", err)
+		return err
+	}
+	log.Printf("[docker-dashboard] containers found: %d", len(apiContainers))
+
+	items := make(map[string]*containerRecord, len(apiContainers))
+	var itemsMu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range apiContainers {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			rec, err := s.inspectContainer(id)
+			if err != nil {
+				if !errors.Is(err, errContainerNotFound) {
+					log.Printf("[docker-dashboard] %v", err)
+				}
+				return
+			}
+			itemsMu.Lock()
+			items[id] = rec
+			itemsMu.Unlock()
+		}(c.ID)
+	}
+	wg.Wait()
+
+	// Если не удалось получить ни одного контейнера, считаем синхронизацию неуспешной
+	if len(items) == 0 && len(apiContainers) > 0 {
+		return fmt.Errorf("failed to inspect %d containers", len(apiContainers))
+	}
+
+	s.mu.Lock()
+	// Контейнеры, обновленные или удаленные во время синхронизации, берем из текущей модели
+	for id, v := range s.versions {
+		if v.seq <= start {
+			delete(s.versions, id)
+			continue
+		}
+		if rec, ok := s.items[id]; ok {
+			items[id] = rec
+		} else {
+			delete(items, id)
+		}
+	}
+	s.items = items
+	s.floor = start
+	s.synced = true
+	s.mu.Unlock()
+
+	s.pruneImages()
+	return nil
+}
+
+// pruneImages удаляет из кэша образы, которые больше не используются контейнерами
+func (s *containerStore) pruneImages() {
+	s.imagesMu.Lock()
+	defer s.imagesMu.Unlock()
+	if len(s.images) == 0 {
+		return
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	used := make(map[string]bool, len(s.items))
+	for _, rec := range s.items {
+		used[rec.imageID] = true
+	}
+	for id := range s.images {
+		if !used[id] {
+			delete(s.images, id)
+		}
+	}
+}
+
+// nextSeq выдает номер очередной операции над моделью
+func (s *containerStore) nextSeq() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	return s.seq
+}
+
+// stale сообщает, что операция seq устарела: после ее начала контейнер
+// уже обновлен, удален или модель полностью перечитана (вызывается под s.mu)
+func (s *containerStore) stale(id string, seq uint64) bool {
+	return seq <= s.floor || s.versions[id].seq > seq
+}
+
+// refresh перечитывает состояние одного контейнера
+func (s *containerStore) refresh(id string) {
+	seq := s.nextSeq()
+	rec, err := s.inspectContainer(id)
+	if errors.Is(err, errContainerNotFound) {
+		s.removeAt(id, seq)
+		return
+	}
+	if err != nil {
+		log.Printf("[docker-dashboard] refresh container %s: %v", id, err)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stale(id, seq) {
+		return
+	}
+	s.items[id] = rec
+	s.versions[id] = itemVersion{seq: seq}
+}
+
+// Refresh сразу перечитывает состояние контейнера по полному ID, не дожидаясь события Docker.
+// Вызывается после действий над контейнером, чтобы список обновился немедленно
+func Refresh(fullID string) {
+	getContainerStore().refresh(fullID)
+}
+
+// remove удаляет контейнер из модели (событие destroy)
+func (s *containerStore) remove(id string) {
+	s.removeAt(id, s.nextSeq())
+}
+
+// removeAt удаляет контейнер и запоминает удаление, чтобы начатый раньше inspect не вернул его в модель
+func (s *containerStore) removeAt(id string, seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stale(id, seq) {
+		return
+	}
+	now := time.Now()
+	delete(s.items, id)
+	s.versions[id] = itemVersion{seq: seq, removedAt: now}
+	for otherID, v := range s.versions {
+		if !v.removedAt.IsZero() && now.Sub(v.removedAt) > removedTTL {
+			delete(s.versions, otherID)
+		}
+	}
+}
+
+// handleEvent применяет событие Docker к модели и уведомляет подписчиков
+func (s *containerStore) handleEvent(ev dockerEvent) {
+	if ev.Type != "container" || ev.Actor.ID == "" {
+		return
+	}
+	if ev.Action == "destroy" {
+		s.remove(ev.Actor.ID)
+		notifyEventListeners(newContainerEvent(ev))
+		return
+	}
+	for _, action := range refreshActions {
+		if strings.HasPrefix(ev.Action, action) {
+			s.refresh(ev.Actor.ID)
+			notifyEventListeners(newContainerEvent(ev))
+			return
+		}
+	}
+}
+
+// ContainerEvent — событие жизненного цикла контейнера
+type ContainerEvent struct {
+	Time           time.Time `json:"time"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Image          string    `json:"image,omitempty"`
+	ComposeProject string    `json:"compose_project,omitempty"`
+	Action         string    `json:"action"`
+	ExitCode       string    `json:"exit_code,omitempty"`
+}
+
+func newContainerEvent(ev dockerEvent) ContainerEvent {
+	attrs := ev.Actor.Attributes
+	t := time.Now()
+	if ev.TimeNano > 0 {
+		t = time.Unix(0, ev.TimeNano)
+	}
+	return ContainerEvent{
+		Time:           t,
+		ID:             shortContainerID(ev.Actor.ID),
+		Name:           attrs["name"],
+		Image:          attrs["image"],
+		ComposeProject: attrs["com.docker.compose.project"],
+		Action:         ev.Action,
+		ExitCode:       attrs["exitCode"],
+	}
+}
+
+// Подписчики на события жизненного цикла контейнеров
+var eventListeners struct {
+	mu  sync.RWMutex
+	fns []func(ContainerEvent)
+}
+
+// AddEventListener регистрирует обработчик событий жизненного цикла контейнеров.
+// Обработчик вызывается синхронно из потока событий, поэтому должен быть быстрым
+func AddEventListener(fn func(ContainerEvent)) {
+	getContainerStore().startWatcher()
+	eventListeners.mu.Lock()
+	defer eventListeners.mu.Unlock()
+	eventListeners.fns = append(eventListeners.fns, fn)
+}
+
+func notifyEventListeners(ev ContainerEvent) {
+	eventListeners.mu.RLock()
+	defer eventListeners.mu.RUnlock()
+	for _, fn := range eventListeners.fns {
+		fn(ev)
+	}
+}
+
+// startWatcher запускает фоновую подписку на события Docker (один раз)
+func (s *containerStore) startWatcher() {
+	s.watcherOnce.Do(func() {
+		go s.watch()
+	})
+}
+
+func (s *containerStore) watch() {
+	backoff := time.Second
+	for {
+		// Подписываемся на события с момента перед полной синхронизацией,
+		// чтобы не пропустить изменения, произошедшие во время нее
+		since := time.Now().Unix()
+		if err := s.resync(); err != nil {
+			log.Printf("[docker-dashboard] containers resync error: %v", err)
+			time.Sleep(backoff)
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+		backoff = time.Second
+
+		err := s.followEvents(since)
+		log.Printf("[docker-dashboard] Docker events stream closed: %v", err)
+		s.markUnsynced()
+		time.Sleep(time.Second)
+	}
+}
+
+// followEvents читает поток Docker /events до его закрытия
+func (s *containerStore) followEvents(since int64) error {
+	filters := url.QueryEscape(`{"type":["container"]}`)
+	eventsURL, err := DockerURL(fmt.Sprintf("/events?since=%d&filters=%s", since, filters))
+	if err != nil {
+		return err
+	}
+	log.Printf("[docker-dashboard] GET %s", eventsURL)
+
+	resp, err := getDockerStreamClient().Get(eventsURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker API status %d", resp.StatusCode)
+	}
+
+	debug := os.Getenv("DEBUG") == "true"
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var ev dockerEvent
+		if err := decoder.Decode(&ev); err != nil {
+			return err
+		}
+		if debug {
+			log.Printf("[docker-dashboard] event: %s %s %s", ev.Type, ev.Action, ev.Actor.ID)
+		}
+		s.handleEvent(ev)
+	}
+}
+
+// runningIDs возвращает полные ID запущенных контейнеров
+func (s *containerStore) runningIDs() map[string]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	running := make(map[string]bool)
+	for id, rec := range s.items {
+		if rec.container.Run {
+			running[id] = true
+		}
+	}
+	return running
+}
@@ -0,0 +1,218 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ExecConfig — параметры создания exec в контейнере
+type ExecConfig struct {
+	Cmd        []string
+	User       string
+	WorkingDir string
+}
+
+// ExecSession — подключенный к процессу exec поток терминала (TTY, без мультиплексирования)
+type ExecSession struct {
+	ID     string
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Read читает вывод терминала
+func (s *ExecSession) Read(p []byte) (int, error) {
+	return s.reader.Read(p)
+}
+
+// Write передает ввод в терминал
+func (s *ExecSession) Write(p []byte) (int, error) {
+	return s.conn.Write(p)
+}
+
+// Close закрывает соединение; процесс получает EOF на stdin
+func (s *ExecSession) Close() error {
+	return s.conn.Close()
+}
+
+// CreateExec создает exec с TTY и подключенными stdin/stdout/stderr и возвращает его ID
+func CreateExec(ctx context.Context, containerID string, cfg ExecConfig) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"AttachStdin":  true,
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Tty":          true,
+		"Cmd":          cfg.Cmd,
+		"User":         cfg.User,
+		"WorkingDir":   cfg.WorkingDir,
+		"Env":          []string{"TERM=xterm-256color"},
+	})
+	if err != nil {
+		return "", err
+	}
+	execURL, err := DockerURL("/containers/" + containerID + "/exec")
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, execURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := getDockerClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return "", fmt.Errorf("docker API status %d: %s", resp.StatusCode, readDockerError(resp.Body))
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("failed to decode exec response: %w", err)
+	}
+	return created.ID, nil
+}
+
+// StartExec запускает exec и перехватывает соединение с Docker (Upgrade: tcp):
+// дальше по нему идет сырой поток терминала в обе стороны
+func StartExec(ctx context.Context, execID string) (*ExecSession, error) {
+	conn, err := dialDocker(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	body := []byte(`{"Detach":false,"Tty":true}`)
+	startURL, err := DockerURL("/exec/" + execID + "/start")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, startURL, bytes.NewReader(body))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "tcp")
+
+	// Дедлайн только на рукопожатие: сам сеанс может длиться сколько угодно
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send exec start request: %w", err)
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read exec start response: %w", err)
+	}
+	// Старые версии Docker отвечают 200 без смены протокола, поток идет так же
+	if resp.StatusCode != http.StatusSwitchingProtocols && resp.StatusCode != http.StatusOK {
+		message := readDockerError(resp.Body)
+		conn.Close()
+		return nil, fmt.Errorf("docker API status %d: %s", resp.StatusCode, message)
+	}
+	conn.SetDeadline(time.Time{})
+	return &ExecSession{ID: execID, conn: conn, reader: reader}, nil
+}
+
+// ResizeExec меняет размер терминала exec
+func ResizeExec(ctx context.Context, execID string, cols, rows int) error {
+	path := "/exec/" + execID + "/resize?h=" + strconv.Itoa(rows) + "&w=" + strconv.Itoa(cols)
+	resizeURL, err := DockerURL(path)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, resizeURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := getDockerClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("docker API status %d: %s", resp.StatusCode, readDockerError(resp.Body))
+	}
+	return nil
+}
+
+// ExecExitCode возвращает код завершения процесса exec; ok=false, если процесс еще работает
+func ExecExitCode(ctx context.Context, execID string) (code int, ok bool, err error) {
+	inspectURL, err := DockerURL("/exec/" + execID + "/json")
+	if err != nil {
+		return 0, false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, inspectURL, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	resp, err := getDockerClient().Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("docker API status %d: %s", resp.StatusCode, readDockerError(resp.Body))
+	}
+	var inspect struct {
+		Running  bool `json:"Running"`
+		ExitCode int  `json:"ExitCode"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return 0, false, err
+	}
+	return inspect.ExitCode, !inspect.Running, nil
+}
+
+// dialDocker открывает отдельное соединение с Docker (с TLS, если он настроен)
+func dialDocker(ctx context.Context) (net.Conn, error) {
+	getDockerClient()
+	if dockerEndpointErr != nil {
+		return nil, dockerEndpointErr
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+	conn, err := dialer.DialContext(ctx, dockerEndpointConf.network, dockerEndpointConf.address)
+	if err != nil {
+		return nil, err
+	}
+	if dockerEndpointConf.tls != nil {
+		tlsConn := tls.Client(conn, dockerEndpointConf.tls)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("TLS handshake with Docker failed: %w", err)
+		}
+		return tlsConn, nil
+	}
+	return conn, nil
+}
+
+// readDockerError извлекает текст ошибки из тела ответа Docker ({"message": "..."})
+func readDockerError(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, 64*1024))
+	var dockerErr struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &dockerErr) == nil && dockerErr.Message != "" {
+		return dockerErr.Message
+	}
+	return string(bytes.TrimSpace(data))
+}
@@ -0,0 +1,295 @@
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Интервал сверки потоков статистики с моделью контейнеров
+	statsReconcileInterval = 1 * time.Second
+	// Пауза перед повторным открытием потока после ошибки
+	statsRetryInterval = 5 * time.Second
+)
+
+// statsCollector держит по одному потоку stats?stream=true на каждый запущенный контейнер
+// и хранит последние вычисленные значения
+type statsCollector struct {
+	mu       sync.RWMutex
+	streams  map[string]context.CancelFunc // по полному ID
+	latest   map[string]ContainerStats     // по полному ID
+	failedAt map[string]time.Time          // время последней ошибки потока
+
+	startOnce sync.Once
+}
+
+var (
+	statsCollectorInstance *statsCollector
+	statsCollectorOnce     sync.Once
+)
+
+func getStatsCollector() *statsCollector {
+	statsCollectorOnce.Do(func() {
+		statsCollectorInstance = &statsCollector{
+			streams:  make(map[string]context.CancelFunc),
+			latest:   make(map[string]ContainerStats),
+			failedAt: make(map[string]time.Time),
+		}
+	})
+	return statsCollectorInstance
+}
+
+func (sc *statsCollector) start() {
+	sc.startOnce.Do(func() {
+		getContainerStore().startWatcher()
+		go sc.run()
+	})
+}
+
+func (sc *statsCollector) run() {
+	ticker := time.NewTicker(statsReconcileInterval)
+	defer ticker.Stop()
+	for {
+		sc.reconcile()
+		<-ticker.C
+	}
+}
+
+// reconcile открывает потоки для запущенных контейнеров и закрывает для остановленных
+func (sc *statsCollector) reconcile() {
+	running := getContainerStore().runningIDs()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	for id := range running {
+		if _, ok := sc.streams[id]; ok {
+			continue
+		}
+		if failedAt, ok := sc.failedAt[id]; ok && time.Since(failedAt) < statsRetryInterval {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		sc.streams[id] = cancel
+		go sc.follow(ctx, id)
+	}
+	for id, cancel := range sc.streams {
+		if !running[id] {
+			cancel()
+			delete(sc.streams, id)
+			delete(sc.latest, id)
+		}
+	}
+	for id := range sc.failedAt {
+		if !running[id] {
+			delete(sc.failedAt, id)
+		}
+	}
+}
+
+// follow читает поток статистики контейнера до его закрытия
+func (sc *statsCollector) follow(ctx context.Context, id string) {
+	err := sc.readStream(ctx, id)
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if ctx.Err() != nil {
+		// Поток закрыт сверкой, контейнер уже удален из streams и latest
+		return
+	}
+	delete(sc.streams, id)
+	delete(sc.latest, id)
+	sc.failedAt[id] = time.Now()
+	// EOF — штатное закрытие потока при остановке контейнера
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("[docker-dashboard] Stats stream for container %s closed: %v", shortContainerID(id), err)
+	}
+}
+
+func (sc *statsCollector) readStream(ctx context.Context, id string) error {
+	statsURL, err := DockerURL(fmt.Sprintf("/containers/%s/stats?stream=true", id))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", statsURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := getDockerStreamClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("docker stats API returned status %d", resp.StatusCode)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	var prev *dockerStats
+	for {
+		var cur dockerStats
+		if err := decoder.Decode(&cur); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		// Первый кадр не содержит дельты CPU, поэтому значения публикуем начиная со второго
+		if prev != nil {
+			stats := computeStats(id, prev, &cur)
+			sc.mu.Lock()
+			// Сверка отменяет поток под sc.mu и удаляет latest[id]; кадр, прочитанный
+			// до отмены, не должен вернуть статистику остановленного контейнера
+			if ctx.Err() != nil {
+				sc.mu.Unlock()
+				return nil
+			}
+			sc.latest[id] = stats
+			sc.mu.Unlock()
+		}
+		prev = &cur
+	}
+}
+
+// computeStats вычисляет статистику контейнера по двум последовательным кадрам
+func computeStats(id string, prev, cur *dockerStats) ContainerStats {
+	stats := ContainerStats{
+		ID:             shortContainerID(id),
+		CPUUsage:       cpuCores(prev, cur),
+		MemoryUsage:    int64(memoryWorkingSet(cur)),
+		MemoryRawUsage: int64(cur.MemoryStats.Usage),
+		MemoryLimit:    int64(cur.MemoryStats.Limit),
+		PIDs:           cur.PidsStats.Current,
+	}
+	if stats.MemoryLimit > 0 {
+		stats.MemoryPercent = float64(stats.MemoryUsage) / float64(stats.MemoryLimit) * 100.0
+	}
+
+	// Скорость считаем по времени между кадрами
+	elapsed := cur.Read.Sub(prev.Read).Seconds()
+	rate := func(curValue, prevValue uint64) float64 {
+		if elapsed <= 0 || curValue < prevValue {
+			return 0
+		}
+		return float64(curValue-prevValue) / elapsed
+	}
+
+	for name, n := range cur.Networks {
+		iface := NetworkStats{
+			Interface: name,
+			RxBytes:   n.RxBytes,
+			TxBytes:   n.TxBytes,
+		}
+		if p, ok := prev.Networks[name]; ok {
+			iface.RxRate = rate(n.RxBytes, p.RxBytes)
+			iface.TxRate = rate(n.TxBytes, p.TxBytes)
+		}
+		stats.Networks = append(stats.Networks, iface)
+		stats.NetworkRxBytes += iface.RxBytes
+		stats.NetworkTxBytes += iface.TxBytes
+		stats.NetworkRxRate += iface.RxRate
+		stats.NetworkTxRate += iface.TxRate
+	}
+	sort.Slice(stats.Networks, func(i, j int) bool {
+		return stats.Networks[i].Interface < stats.Networks[j].Interface
+	})
+
+	// cgroup v1 пишет операции с заглавной буквы ("Read"), cgroup v2 — строчными ("read")
+	for _, entry := range cur.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockRead += entry.Value
+		case "write":
+			stats.BlockWrite += entry.Value
+		}
+	}
+
+	return stats
+}
+
+// memoryWorkingSet возвращает память контейнера без страничного кэша:
+// usage - inactive_file для cgroup v2 и usage - cache для cgroup v1
+func memoryWorkingSet(s *dockerStats) uint64 {
+	usage := s.MemoryStats.Usage
+	var cache uint64
+	if v, ok := s.MemoryStats.Stats["cache"]; ok {
+		// cgroup v1
+		cache = v
+	} else if v, ok := s.MemoryStats.Stats["inactive_file"]; ok {
+		// cgroup v2
+		cache = v
+	}
+	if cache > usage {
+		return 0
+	}
+	return usage - cache
+}
+
+// cpuCores вычисляет использование CPU в ядрах по дельте между двумя кадрами
+func cpuCores(prev, cur *dockerStats) float64 {
+	onlineCPUs := cur.CPUStats.OnlineCPUs
+	if onlineCPUs == 0 {
+		onlineCPUs = uint32(len(cur.CPUStats.CPUUsage.Percpu))
+	}
+	if prev.CPUStats.SystemCPUUsage == 0 || cur.CPUStats.SystemCPUUsage <= prev.CPUStats.SystemCPUUsage ||
+		cur.CPUStats.CPUUsage.TotalUsage < prev.CPUStats.CPUUsage.TotalUsage || onlineCPUs == 0 {
+		return 0
+	}
+
+	cpuDelta := float64(cur.CPUStats.CPUUsage.TotalUsage - prev.CPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(cur.CPUStats.SystemCPUUsage - prev.CPUStats.SystemCPUUsage)
+
+	// Доля от всех ядер, умноженная на количество ядер, дает количество ядер
+	cores := (cpuDelta / systemDelta) * float64(onlineCPUs)
+	// Ограничиваем количеством доступных ядер
+	if cores > float64(onlineCPUs) {
+		cores = float64(onlineCPUs)
+	}
+	return cores
+}
+
+func shortContainerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// GetContainerStats возвращает последнюю статистику использования CPU и RAM для контейнера
+// (по полному или короткому ID)
+func GetContainerStats(containerID string) (*ContainerStats, error) {
+	sc := getStatsCollector()
+	sc.start()
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	for id, stats := range sc.latest {
+		if id == containerID || shortContainerID(id) == containerID {
+			result := stats
+			return &result, nil
+		}
+	}
+	return nil, fmt.Errorf("no stats for container %s", containerID)
+}
+
+// GetContainersStats возвращает последнюю статистику для всех запущенных контейнеров
+func GetContainersStats() ([]ContainerStats, error) {
+	sc := getStatsCollector()
+	sc.start()
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	result := make([]ContainerStats, 0, len(sc.latest))
+	for _, stats := range sc.latest {
+		result = append(result, stats)
+	}
+	return result, nil
+}
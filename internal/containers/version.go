@@ -0,0 +1,228 @@
+package containers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Максимальная версия Docker API, с которой умеет работать дашборд
+	clientMaxAPIVersion = "1.47"
+	// Версия API, которую используем, если демон не сообщил свою (Docker 1.12)
+	fallbackAPIVersion = "1.24"
+	// Минимальный интервал между повторными попытками согласования версии
+	negotiateRetryInterval = 5 * time.Second
+)
+
+// apiVersionState — согласованная версия Docker API
+var apiVersionState struct {
+	mu          sync.Mutex
+	version     string
+	lastAttempt time.Time
+	lastErr     error         // ошибка последней попытки согласования
+	inflight    chan struct{} // закрывается по завершении текущей попытки
+}
+
+// DockerInfo — информация о демоне Docker и согласованной версии API
+type DockerInfo struct {
+	DockerHost           string `json:"docker_host"`
+	DaemonVersion        string `json:"daemon_version"`
+	DaemonAPIVersion     string `json:"daemon_api_version"`
+	DaemonMinAPIVersion  string `json:"daemon_min_api_version,omitempty"`
+	ClientMaxAPIVersion  string `json:"client_max_api_version"`
+	NegotiatedAPIVersion string `json:"negotiated_api_version"`
+	Os                   string `json:"os"`
+	Arch                 string `json:"arch"`
+	KernelVersion        string `json:"kernel_version,omitempty"`
+}
+
+type dockerVersion struct {
+	Version       string `json:"Version"`
+	APIVersion    string `json:"ApiVersion"`
+	MinAPIVersion string `json:"MinAPIVersion"`
+	Os            string `json:"Os"`
+	Arch          string `json:"Arch"`
+	KernelVersion string `json:"KernelVersion"`
+}
+
+// parseAPIVersion разбирает версию вида "1.43" в пару (major, minor)
+func parseAPIVersion(v string) (int, int, bool) {
+	major, minor, ok := strings.Cut(strings.TrimPrefix(v, "v"), ".")
+	if !ok {
+		return 0, 0, false
+	}
+	maj, err := strconv.Atoi(major)
+	if err != nil {
+		return 0, 0, false
+	}
+	minr, err := strconv.Atoi(minor)
+	if err != nil {
+		return 0, 0, false
+	}
+	return maj, minr, true
+}
+
+// apiVersionLess сообщает, что версия a меньше версии b
+func apiVersionLess(a, b string) bool {
+	aMaj, aMin, _ := parseAPIVersion(a)
+	bMaj, bMin, _ := parseAPIVersion(b)
+	if aMaj != bMaj {
+		return aMaj < bMaj
+	}
+	return aMin < bMin
+}
+
+// negotiate выбирает наибольшую версию API, поддерживаемую и дашбордом, и демоном
+func negotiate(daemonVersion string) string {
+	if _, _, ok := parseAPIVersion(daemonVersion); !ok {
+		return fallbackAPIVersion
+	}
+	if apiVersionLess(daemonVersion, clientMaxAPIVersion) {
+		return daemonVersion
+	}
+	return clientMaxAPIVersion
+}
+
+// fetchDockerVersion запрашивает /version по неверсионированному пути
+func fetchDockerVersion() (*dockerVersion, error) {
+	resp, err := getDockerClient().Get(dockerBaseURL + "/version")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("docker API status %d", resp.StatusCode)
+	}
+	var version dockerVersion
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// pingAPIVersion возвращает версию API демона из заголовка ответа /_ping
+func pingAPIVersion() (string, error) {
+	resp, err := getDockerClient().Get(dockerBaseURL + "/_ping")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("docker API status %d", resp.StatusCode)
+	}
+	if v := resp.Header.Get("API-Version"); v != "" {
+		return v, nil
+	}
+	// Старые демоны не отдают заголовок, берем версию из /version
+	version, err := fetchDockerVersion()
+	if err != nil {
+		return "", err
+	}
+	return version.APIVersion, nil
+}
+
+// NegotiateAPIVersion согласовывает версию Docker API с демоном.
+// DOCKER_API_VERSION позволяет зафиксировать версию вручную.
+// При недоступности демона повторная попытка выполняется при следующем запросе
+func NegotiateAPIVersion() (string, error) {
+	getDockerClient()
+	return negotiateVersion(true)
+}
+
+// negotiateVersion возвращает согласованную версию. Запрос к демону выполняется без блокировки
+// apiVersionState.mu и только одной горутиной: остальные ждут его результата, а запросы
+// с уже согласованной версией не ждут вовсе. Без retryNow после неудачи новая попытка
+// делается не чаще negotiateRetryInterval, а до нее возвращается прошлая ошибка
+func negotiateVersion(retryNow bool) (string, error) {
+	s := &apiVersionState
+	s.mu.Lock()
+	if s.version != "" {
+		defer s.mu.Unlock()
+		return s.version, nil
+	}
+	if wait := s.inflight; wait != nil {
+		s.mu.Unlock()
+		<-wait
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.version, s.lastErr
+	}
+	if !retryNow && time.Since(s.lastAttempt) < negotiateRetryInterval {
+		defer s.mu.Unlock()
+		return "", s.lastErr
+	}
+	done := make(chan struct{})
+	s.inflight = done
+	s.lastAttempt = time.Now()
+	s.mu.Unlock()
+
+	version, err := resolveAPIVersion()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version, s.lastErr = version, err
+	s.inflight = nil
+	close(done)
+	return version, err
+}
+
+// resolveAPIVersion определяет версию API: зафиксированную в DOCKER_API_VERSION или по ответу демона
+func resolveAPIVersion() (string, error) {
+	if pinned := os.Getenv("DOCKER_API_VERSION"); pinned != "" {
+		if _, _, ok := parseAPIVersion(pinned); !ok {
+			return "", fmt.Errorf("invalid DOCKER_API_VERSION %q", pinned)
+		}
+		version := strings.TrimPrefix(pinned, "v")
+		log.Printf("[docker-dashboard] Docker API version pinned: %s", version)
+		return version, nil
+	}
+
+	daemonVersion, err := pingAPIVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to negotiate Docker API version: %w", err)
+	}
+	version := negotiate(daemonVersion)
+	log.Printf("[docker-dashboard] Docker API version negotiated: %s (daemon: %s, client max: %s)",
+		version, daemonVersion, clientMaxAPIVersion)
+	return version, nil
+}
+
+// apiVersionPrefix возвращает префикс пути "/vX.Y" для запросов к Docker API.
+// Пока версия не согласована, возвращается ошибка: неверсионированные пути не используются
+func apiVersionPrefix() (string, error) {
+	version, err := negotiateVersion(false)
+	if err != nil {
+		return "", err
+	}
+	return "/v" + version, nil
+}
+
+// GetDockerInfo возвращает версию демона Docker и согласованную версию API
+func GetDockerInfo() (*DockerInfo, error) {
+	negotiated, err := NegotiateAPIVersion()
+	if err != nil {
+		return nil, err
+	}
+	version, err := fetchDockerVersion()
+	if err != nil {
+		return nil, err
+	}
+	return &DockerInfo{
+		DockerHost:           dockerHostDescription,
+		DaemonVersion:        version.Version,
+		DaemonAPIVersion:     version.APIVersion,
+		DaemonMinAPIVersion:  version.MinAPIVersion,
+		ClientMaxAPIVersion:  clientMaxAPIVersion,
+		NegotiatedAPIVersion: negotiated,
+		Os:                   version.Os,
+		Arch:                 version.Arch,
+		KernelVersion:        version.KernelVersion,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package containers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	var pings atomic.Int32
+	var failing atomic.Bool
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_ping" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+			return
+		}
+		pings.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		<-release
+		w.Header().Set("API-Version", "1.45")
+	}))
+	defer server.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+server.Listener.Addr().String())
+	t.Setenv("DOCKER_API_VERSION", "")
+	// Общий клиент Docker создается один раз на процесс
+	if getDockerClient(); dockerBaseURL != "http://"+server.Listener.Addr().String() {
+		t.Skip("Docker client is already configured for another endpoint")
+	}
+	resetAPIVersion()
+	t.Cleanup(resetAPIVersion)
+
+	// Демон недоступен: запрос не уходит по неверсионированному пути, а получает ошибку
+	failing.Store(true)
+	for i := 0; i < 2; i++ {
+		if u, err := DockerURL("/containers/json"); err == nil || u != "" {
+			t.Fatalf("DockerURL = %q, %v; want negotiation error", u, err)
+		}
+	}
+	if got := pings.Load(); got != 1 {
+		t.Fatalf("%d pings, want 1 (retry is delayed by negotiateRetryInterval)", got)
+	}
+
+	// Повторная попытка: одна горутина согласует версию, остальные ждут ее результата
+	apiVersionState.mu.Lock()
+	apiVersionState.lastAttempt = time.Time{}
+	apiVersionState.mu.Unlock()
+	failing.Store(false)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	urls := make([]string, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			urls[i], errs[i] = DockerURL("/containers/json")
+		}(i)
+	}
+	for pings.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	// Пока идет /_ping, блокировка состояния свободна
+	if !apiVersionState.mu.TryLock() {
+		t.Fatal("apiVersionState.mu is held during negotiation")
+	}
+	apiVersionState.mu.Unlock()
+	close(release)
+	wg.Wait()
+
+	for i := range urls {
+		if errs[i] != nil || !strings.HasSuffix(urls[i], "/v1.45/containers/json") {
+			t.Errorf("caller %d: DockerURL = %q, %v", i, urls[i], errs[i])
+		}
+	}
+	if _, err := DockerURL("/info"); err != nil {
+		t.Fatal(err)
+	}
+	if got := pings.Load(); got != 2 {
+		t.Errorf("%d pings, want 2", got)
+	}
+}
+
+func resetAPIVersion() {
+	apiVersionState.mu.Lock()
+	defer apiVersionState.mu.Unlock()
+	apiVersionState.version = ""
+	apiVersionState.lastAttempt = time.Time{}
+	apiVersionState.lastErr = nil
+}
@@ -0,0 +1,319 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/hostinfo"
+)
+
+// Экспорт метрик в текстовом формате Prometheus (text/plain; version=0.0.4)
+
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// labelPair — пара имя/значение метки Prometheus
+type labelPair struct {
+	name  string
+	value string
+}
+
+// family — семейство метрик: HELP, TYPE и все его строки
+type family struct {
+	name    string
+	typ     string
+	help    string
+	samples bytes.Buffer
+}
+
+// writer собирает строки по семействам: формат Prometheus требует, чтобы все строки
+// семейства шли подряд сразу после его HELP и TYPE, а контейнеры выводятся по очереди
+type writer struct {
+	families map[string]*family
+	order    []*family
+	current  *family
+}
+
+func newWriter() *writer {
+	return &writer{families: make(map[string]*family)}
+}
+
+// family выбирает семейство, в которое попадут следующие строки sample
+func (w *writer) family(name, typ, help string) {
+	f, ok := w.families[name]
+	if !ok {
+		f = &family{name: name, typ: typ, help: help}
+		w.families[name] = f
+		w.order = append(w.order, f)
+	}
+	w.current = f
+}
+
+func (w *writer) sample(name string, labels []labelPair, value float64) {
+	b := &w.current.samples
+	b.WriteString(name)
+	if len(labels) > 0 {
+		b.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(l.name)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(l.value))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatValue(value))
+	b.WriteByte('\n')
+}
+
+// flush выводит семейства в порядке их первого появления
+func (w *writer) flush(out io.Writer) error {
+	bw := bufio.NewWriter(out)
+	for _, f := range w.order {
+		fmt.Fprintf(bw, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+		bw.Write(f.samples.Bytes())
+	}
+	return bw.Flush()
+}
+
+func (w *writer) gauge(name, help string, labels []labelPair, value float64) {
+	w.family(name, "gauge", help)
+	w.sample(name, labels, value)
+}
+
+func (w *writer) counter(name, help string, labels []labelPair, value float64) {
+	w.family(name, "counter", help)
+	w.sample(name, labels, value)
+}
+
+func escapeLabelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, "\n", `\n`)
+	return strings.ReplaceAll(v, `"`, `\"`)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func withLabels(base []labelPair, extra ...labelPair) []labelPair {
+	result := make([]labelPair, 0, len(base)+len(extra))
+	result = append(result, base...)
+	return append(result, extra...)
+}
+
+// labelName превращает имя Docker label в допустимое имя метки Prometheus
+func labelName(key string) string {
+	return "label_" + invalidLabelChars.ReplaceAllString(key, "_")
+}
+
+// containerLabels возвращает метки контейнера: имя, compose project и,
+// если задан LABEL_PREFIX, Docker labels с этим префиксом
+func containerLabels(c containers.Container) []labelPair {
+	labels := []labelPair{
+		{name: "name", value: c.Name},
+		{name: "id", value: c.ID},
+		{name: "compose_project", value: c.ComposeProject},
+	}
+	if os.Getenv("LABEL_PREFIX") == "" {
+		return labels
+	}
+	keys := make([]string, 0, len(c.Labels))
+	for key := range c.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		name := labelName(key)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		labels = append(labels, labelPair{name: name, value: c.Labels[key]})
+	}
+	return labels
+}
+
+var containerStates = []string{"created", "running", "paused", "restarting", "removing", "exited", "dead"}
+var healthStates = []string{"starting", "healthy", "unhealthy"}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Write выводит все метрики в формате Prometheus.
+// include (если задан) отбирает контейнеры, метрики которых попадут в вывод
+func Write(out io.Writer, include func(*containers.Container) bool) error {
+	w := newWriter()
+	writeContainers(w, include)
+	writeHost(w)
+	writeDockerAPI(w)
+	return w.flush(out)
+}
+
+func writeContainers(w *writer, include func(*containers.Container) bool) {
+	list, err := containers.GetContainers()
+	if err != nil {
+		log.Printf("[docker-dashboard] exporter: failed to get containers: %v", err)
+		w.gauge("docker_dashboard_containers_up", "Whether container data could be collected from Docker", nil, 0)
+		return
+	}
+	w.gauge("docker_dashboard_containers_up", "Whether container data could be collected from Docker", nil, 1)
+
+	stats, err := containers.GetContainersStats()
+	if err != nil {
+		log.Printf("[docker-dashboard] exporter: failed to get container stats: %v", err)
+	}
+	statsByID := make(map[string]containers.ContainerStats, len(stats))
+	for _, s := range stats {
+		statsByID[s.ID] = s
+	}
+	writeContainerList(w, list, statsByID, include, time.Now())
+}
+
+// writeContainerList выводит метрики контейнеров по уже полученным списку и статистике
+func writeContainerList(w *writer, list []containers.Container, statsByID map[string]containers.ContainerStats,
+	include func(*containers.Container) bool, now time.Time) {
+	for _, c := range list {
+		if include != nil && !include(&c) {
+			continue
+		}
+		labels := containerLabels(c)
+
+		for _, state := range containerStates {
+			w.gauge("docker_container_state", "Container state (1 for the current state)",
+				withLabels(labels, labelPair{name: "state", value: state}), boolValue(c.State == state))
+		}
+		if c.Health != "" {
+			for _, health := range healthStates {
+				w.gauge("docker_container_health_status", "Container health check status (1 for the current status)",
+					withLabels(labels, labelPair{name: "health", value: health}), boolValue(c.Health == health))
+			}
+		}
+		w.counter("docker_container_restarts_total", "Number of times the container was restarted by Docker", labels, float64(c.RestartCount))
+		if c.StartedAt != "" {
+			if started, err := time.Parse(time.RFC3339Nano, c.StartedAt); err == nil {
+				w.gauge("docker_container_uptime_seconds", "Seconds since the container was started", labels, now.Sub(started).Seconds())
+			}
+		}
+		if c.State == "exited" {
+			w.gauge("docker_container_exit_code", "Exit code of the stopped container", labels, float64(c.ExitCode))
+		}
+		if r := c.DeployResources; r != nil {
+			if r.CPULimitCores > 0 {
+				w.gauge("docker_container_cpu_limit_cores", "Container CPU limit in cores", labels, r.CPULimitCores)
+			}
+			if r.MemoryLimitBytes > 0 {
+				w.gauge("docker_container_memory_limit_bytes", "Container memory limit in bytes", labels, float64(r.MemoryLimitBytes))
+			}
+			if r.MemoryReservationBytes > 0 {
+				w.gauge("docker_container_memory_reservation_bytes", "Container memory reservation in bytes", labels, float64(r.MemoryReservationBytes))
+			}
+		}
+
+		s, ok := statsByID[c.ID]
+		if !ok {
+			continue
+		}
+		w.gauge("docker_container_cpu_usage_cores", "Container CPU usage in cores", labels, s.CPUUsage)
+		w.gauge("docker_container_memory_working_set_bytes", "Container memory usage without page cache", labels, float64(s.MemoryUsage))
+		w.gauge("docker_container_memory_usage_bytes", "Container memory usage as reported by Docker (with page cache)", labels, float64(s.MemoryRawUsage))
+		w.gauge("docker_container_memory_percent", "Container memory working set as percent of the limit", labels, s.MemoryPercent)
+		w.gauge("docker_container_pids", "Number of processes in the container", labels, float64(s.PIDs))
+		w.counter("docker_container_block_read_bytes_total", "Bytes read from block devices", labels, float64(s.BlockRead))
+		w.counter("docker_container_block_write_bytes_total", "Bytes written to block devices", labels, float64(s.BlockWrite))
+		for _, n := range s.Networks {
+			netLabels := withLabels(labels, labelPair{name: "interface", value: n.Interface})
+			w.counter("docker_container_network_receive_bytes_total", "Bytes received by the container", netLabels, float64(n.RxBytes))
+			w.counter("docker_container_network_transmit_bytes_total", "Bytes sent by the container", netLabels, float64(n.TxBytes))
+		}
+	}
+}
+
+func writeHost(w *writer) {
+	metrics, err := hostinfo.GetSystemMetrics()
+	if err != nil {
+		log.Printf("[docker-dashboard] exporter: failed to get host metrics: %v", err)
+		return
+	}
+	if len(metrics.CPU) > 0 {
+		w.gauge("host_cpu_usage_percent", "Host CPU usage in percent", nil, metrics.CPU[0])
+	}
+	w.gauge("host_cpu_count", "Number of logical CPUs", nil, float64(metrics.CPUCount))
+	if m := metrics.Memory; m != nil {
+		w.gauge("host_memory_total_bytes", "Host total memory", nil, float64(m.Total))
+		w.gauge("host_memory_used_bytes", "Host used memory", nil, float64(m.Used))
+		w.gauge("host_memory_available_bytes", "Host available memory", nil, float64(m.Available))
+		w.gauge("host_swap_total_bytes", "Host total swap", nil, float64(m.SwapTotal))
+		w.gauge("host_swap_free_bytes", "Host free swap", nil, float64(m.SwapFree))
+	}
+	if l := metrics.Load; l != nil {
+		w.gauge("host_load1", "Host load average over 1 minute", nil, l.Load1)
+		w.gauge("host_load5", "Host load average over 5 minutes", nil, l.Load5)
+		w.gauge("host_load15", "Host load average over 15 minutes", nil, l.Load15)
+	}
+	mountpoints := make([]string, 0, len(metrics.DiskUsage))
+	for mountpoint := range metrics.DiskUsage {
+		mountpoints = append(mountpoints, mountpoint)
+	}
+	sort.Strings(mountpoints)
+	for _, mountpoint := range mountpoints {
+		u := metrics.DiskUsage[mountpoint]
+		labels := []labelPair{{name: "mountpoint", value: mountpoint}, {name: "fstype", value: u.Fstype}}
+		w.gauge("host_disk_total_bytes", "Host filesystem size", labels, float64(u.Total))
+		w.gauge("host_disk_used_bytes", "Host filesystem used bytes", labels, float64(u.Used))
+		w.gauge("host_disk_used_percent", "Host filesystem used percent", labels, u.UsedPercent)
+	}
+	for _, n := range metrics.Net {
+		labels := []labelPair{{name: "interface", value: n.Name}}
+		w.counter("host_network_receive_bytes_total", "Host bytes received", labels, float64(n.BytesRecv))
+		w.counter("host_network_transmit_bytes_total", "Host bytes sent", labels, float64(n.BytesSent))
+	}
+	if h := metrics.Host; h != nil {
+		w.gauge("host_uptime_seconds", "Host uptime", nil, float64(h.Uptime))
+	}
+}
+
+func writeDockerAPI(w *writer) {
+	for _, m := range containers.GetDockerAPIMetrics() {
+		labels := []labelPair{{name: "method", value: m.Method}, {name: "endpoint", value: m.Endpoint}}
+		w.counter("docker_dashboard_docker_api_errors_total", "Failed Docker API requests (transport errors and HTTP status >= 400)", labels, float64(m.Errors))
+
+		name := "docker_dashboard_docker_api_request_duration_seconds"
+		w.family(name, "histogram", "Docker API request latency (until response headers)")
+		for i, bound := range containers.DockerAPILatencyBuckets {
+			w.sample(name+"_bucket", withLabels(labels, labelPair{name: "le", value: formatValue(bound)}), float64(m.BucketCounts[i]))
+		}
+		w.sample(name+"_bucket", withLabels(labels, labelPair{name: "le", value: "+Inf"}), float64(m.Requests))
+		w.sample(name+"_sum", labels, m.DurationSum)
+		w.sample(name+"_count", labels, float64(m.Requests))
+	}
+}
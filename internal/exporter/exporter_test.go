@@ -0,0 +1,223 @@
+package exporter
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"docker-dashboard/internal/containers"
+)
+
+func TestContainerFamiliesAreContiguous(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	list := []containers.Container{
+		{
+			ID: "aaaaaaaaaaaa", Name: "web", State: "running", Health: "healthy", RestartCount: 2,
+			StartedAt: now.Add(-time.Hour).Format(time.RFC3339Nano), ComposeProject: "shop",
+			DeployResources: &containers.DeployResources{CPULimitCores: 1, MemoryLimitBytes: 512 << 20},
+		},
+		{ID: "bbbbbbbbbbbb", Name: "worker", State: "exited", ExitCode: 1, ComposeProject: "shop"},
+		{ID: "cccccccccccc", Name: "db", State: "running", Health: "unhealthy"},
+	}
+	stats := map[string]containers.ContainerStats{
+		"aaaaaaaaaaaa": {ID: "aaaaaaaaaaaa", CPUUsage: 0.5, MemoryUsage: 100 << 20, Networks: []containers.NetworkStats{
+			{Interface: "eth0", RxBytes: 10, TxBytes: 20},
+			{Interface: "eth1", RxBytes: 30, TxBytes: 40},
+		}},
+		"cccccccccccc": {ID: "cccccccccccc", CPUUsage: 0.1, MemoryUsage: 50 << 20, Networks: []containers.NetworkStats{
+			{Interface: "eth0", RxBytes: 1, TxBytes: 2},
+		}},
+	}
+
+	w := newWriter()
+	w.gauge("docker_dashboard_containers_up", "Whether container data could be collected from Docker", nil, 1)
+	writeContainerList(w, list, stats, nil, now)
+	writeDockerAPI(w)
+	var out bytes.Buffer
+	if err := w.flush(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	families := parseExposition(t, out.String())
+	counts := map[string]int{
+		"docker_container_state":                        3 * len(containerStates),
+		"docker_container_health_status":                2 * len(healthStates),
+		"docker_container_restarts_total":               3,
+		"docker_container_uptime_seconds":               1,
+		"docker_container_exit_code":                    1,
+		"docker_container_cpu_limit_cores":              1,
+		"docker_container_cpu_usage_cores":              2,
+		"docker_container_network_receive_bytes_total":  3,
+		"docker_container_network_transmit_bytes_total": 3,
+	}
+	for name, want := range counts {
+		family, ok := families[name]
+		if !ok {
+			t.Errorf("family %s is missing", name)
+			continue
+		}
+		if got := len(family.samples); got != want {
+			t.Errorf("family %s has %d samples, want %d", name, got, want)
+		}
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	t.Setenv("LABEL_PREFIX", "com.example.")
+	name := "web \"blue\"\\green\nline"
+	list := []containers.Container{{
+		ID: "aaaaaaaaaaaa", Name: name, State: "running",
+		Labels: map[string]string{"com.example.team": `a\b`, "com.example/team": "duplicate label name"},
+	}}
+	w := newWriter()
+	writeContainerList(w, list, nil, nil, time.Unix(1_700_000_000, 0))
+	var out bytes.Buffer
+	if err := w.flush(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	families := parseExposition(t, out.String())
+	restarts := families["docker_container_restarts_total"]
+	if restarts == nil || len(restarts.samples) != 1 {
+		t.Fatalf("restarts family = %+v", restarts)
+	}
+	labels := restarts.samples[0].labels
+	if labels["name"] != name || labels["label_com_example_team"] != `a\b` {
+		t.Errorf("labels = %q", labels)
+	}
+}
+
+// parsedFamily — семейство, прочитанное из текстового формата Prometheus
+type parsedFamily struct {
+	typ     string
+	samples []parsedSample
+}
+
+type parsedSample struct {
+	labels map[string]string
+	value  float64
+}
+
+var metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*`)
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*`)
+
+// parseExposition разбирает вывод экспортера и проверяет формат: каждое семейство встречается
+// один раз, его строки идут подряд сразу после HELP и TYPE, значения меток правильно
+// экранированы, а одинаковых рядов (имя и набор меток) нет
+func parseExposition(t *testing.T, text string) map[string]*parsedFamily {
+	t.Helper()
+	families := make(map[string]*parsedFamily)
+	series := make(map[string]bool)
+	var current string
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if rest, ok := strings.CutPrefix(line, "# HELP "); ok {
+			name, _, _ := strings.Cut(rest, " ")
+			if families[name] != nil {
+				t.Fatalf("line %d: family %s appears more than once", i+1, name)
+			}
+			i++
+			if i >= len(lines) {
+				t.Fatalf("family %s has no TYPE", name)
+			}
+			fields := strings.Fields(lines[i])
+			if len(fields) != 4 || fields[0] != "#" || fields[1] != "TYPE" || fields[2] != name ||
+				(fields[3] != "gauge" && fields[3] != "counter") {
+				t.Fatalf("line %d: expected TYPE of %s, got %q", i+1, name, lines[i])
+			}
+			families[name] = &parsedFamily{typ: fields[3]}
+			current = name
+			continue
+		}
+		if strings.HasPrefix(line, "#") || line == "" {
+			t.Fatalf("line %d: unexpected line %q", i+1, line)
+		}
+
+		name := metricNameRe.FindString(line)
+		if name == "" || name != current {
+			t.Fatalf("line %d: sample %q is outside its family (current family %s)", i+1, line, current)
+		}
+		labels, rest := parseLabels(t, i+1, line[len(name):])
+		valueText, ok := strings.CutPrefix(rest, " ")
+		if !ok {
+			t.Fatalf("line %d: no value in %q", i+1, line)
+		}
+		value, err := strconv.ParseFloat(valueText, 64)
+		if err != nil {
+			t.Fatalf("line %d: invalid value %q", i+1, valueText)
+		}
+
+		keys := make([]string, 0, len(labels))
+		for key, v := range labels {
+			keys = append(keys, key+"="+strconv.Quote(v))
+		}
+		sort.Strings(keys)
+		id := name + "{" + strings.Join(keys, ",") + "}"
+		if series[id] {
+			t.Errorf("line %d: duplicate series %s", i+1, id)
+		}
+		series[id] = true
+		families[name].samples = append(families[name].samples, parsedSample{labels: labels, value: value})
+	}
+	return families
+}
+
+// parseLabels разбирает {name="value",...} в начале s и возвращает метки и остаток строки.
+// В значениях допустимы только экранирования \\, \" и \n
+func parseLabels(t *testing.T, lineNo int, s string) (map[string]string, string) {
+	t.Helper()
+	labels := make(map[string]string)
+	if !strings.HasPrefix(s, "{") {
+		return labels, s
+	}
+	s = s[1:]
+	for !strings.HasPrefix(s, "}") {
+		name := labelNameRe.FindString(s)
+		if name == "" || !strings.HasPrefix(s[len(name):], `="`) {
+			t.Fatalf("line %d: invalid label at %q", lineNo, s)
+		}
+		if _, ok := labels[name]; ok {
+			t.Fatalf("line %d: duplicate label %s", lineNo, name)
+		}
+		s = s[len(name)+2:]
+		var value strings.Builder
+		for {
+			if s == "" || s[0] == '\n' {
+				t.Fatalf("line %d: unterminated value of label %s", lineNo, name)
+			}
+			c := s[0]
+			s = s[1:]
+			if c == '"' {
+				break
+			}
+			if c != '\\' {
+				value.WriteByte(c)
+				continue
+			}
+			if s == "" {
+				t.Fatalf("line %d: unterminated escape in label %s", lineNo, name)
+			}
+			switch s[0] {
+			case '\\', '"':
+				value.WriteByte(s[0])
+			case 'n':
+				value.WriteByte('\n')
+			default:
+				t.Fatalf("line %d: invalid escape \\%c in label %s", lineNo, s[0], name)
+			}
+			s = s[1:]
+		}
+		labels[name] = value.String()
+		if strings.HasPrefix(s, ",") {
+			s = s[1:]
+		} else if !strings.HasPrefix(s, "}") {
+			t.Fatalf("line %d: expected , or } after label %s", lineNo, name)
+		}
+	}
+	return labels, s[1:]
+}
@@ -0,0 +1,290 @@
+package history
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HostScope — область хранения метрик хоста; остальные области — имена контейнеров
+const HostScope = ""
+
+// Point — значение метрики в момент времени (unix seconds)
+type Point struct {
+	T int64
+	V float64
+}
+
+// MarshalJSON кодирует точку компактно: [t, v]
+func (p Point) MarshalJSON() ([]byte, error) {
+	return []byte("[" + formatInt(p.T) + "," + formatFloat(p.V) + "]"), nil
+}
+
+// ring — кольцевой буфер значений с фиксированным шагом.
+// Хранятся только значения (float32), время вычисляется по номеру слота.
+// Незаписанные слоты содержат NaN
+type ring struct {
+	res       int64 // шаг в секундах
+	values    []float32
+	firstSlot int64 // номер самого раннего записанного слота
+	lastSlot  int64 // номер последнего записанного слота, -1 если пусто
+}
+
+func newRing(res, retention time.Duration) *ring {
+	resSec := int64(res / time.Second)
+	if resSec < 1 {
+		resSec = 1
+	}
+	size := int(int64(retention/time.Second) / resSec)
+	if size < 1 {
+		size = 1
+	}
+	values := make([]float32, size)
+	for i := range values {
+		values[i] = float32(math.NaN())
+	}
+	return &ring{res: resSec, values: values, lastSlot: -1}
+}
+
+func (r *ring) size() int64 {
+	return int64(len(r.values))
+}
+
+func (r *ring) index(slot int64) int {
+	return int(slot % r.size())
+}
+
+// add записывает значение в слот, соответствующий времени t
+func (r *ring) add(t int64, v float64) {
+	slot := t / r.res
+	switch {
+	case r.lastSlot < 0:
+		r.firstSlot, r.lastSlot = slot, slot
+	case slot <= r.lastSlot-r.size():
+		// Слишком старое значение, уже вне окна хранения
+		return
+	case slot < r.firstSlot:
+		r.firstSlot = slot
+	case slot > r.lastSlot:
+		// Пропущенные слоты помечаем как отсутствующие
+		gap := slot - r.lastSlot - 1
+		if gap > r.size() {
+			gap = r.size()
+		}
+		for i := int64(1); i <= gap; i++ {
+			r.values[r.index(slot-i)] = float32(math.NaN())
+		}
+		r.lastSlot = slot
+	}
+	r.values[r.index(slot)] = float32(v)
+}
+
+// points возвращает значения в интервале [from, to]
+func (r *ring) points(from, to int64) []Point {
+	if r.lastSlot < 0 {
+		return nil
+	}
+	first := r.firstStored()
+	if s := from / r.res; s > first {
+		first = s
+	}
+	last := r.lastSlot
+	if s := to / r.res; s < last {
+		last = s
+	}
+	var result []Point
+	for slot := first; slot <= last; slot++ {
+		v := r.values[r.index(slot)]
+		if math.IsNaN(float64(v)) {
+			continue
+		}
+		result = append(result, Point{T: slot * r.res, V: float64(v)})
+	}
+	return result
+}
+
+// firstStored возвращает номер самого старого записанного слота, который еще хранится в буфере
+func (r *ring) firstStored() int64 {
+	return max(r.firstSlot, r.lastSlot-r.size()+1)
+}
+
+// oldest возвращает время самого старого записанного слота, который еще хранится в буфере
+func (r *ring) oldest() int64 {
+	if r.lastSlot < 0 {
+		return 0
+	}
+	return r.firstStored() * r.res
+}
+
+// newest возвращает время последнего записанного слота
+func (r *ring) newest() int64 {
+	return r.lastSlot * r.res
+}
+
+// series — история одной метрики: подробная и прореженная (средние значения)
+type series struct {
+	raw    *ring
+	coarse *ring
+
+	// Накопитель для текущего интервала прореживания
+	bucketSlot int64
+	bucketSum  float64
+	bucketN    int
+}
+
+func (s *series) add(t int64, v float64) {
+	s.raw.add(t, v)
+	if s.coarse == nil {
+		return
+	}
+	slot := t / s.coarse.res
+	if slot != s.bucketSlot && s.bucketN > 0 {
+		s.coarse.add(s.bucketSlot*s.coarse.res, s.bucketSum/float64(s.bucketN))
+		s.bucketSum, s.bucketN = 0, 0
+	}
+	s.bucketSlot = slot
+	s.bucketSum += v
+	s.bucketN++
+}
+
+// Config — параметры хранения истории
+type Config struct {
+	Resolution           time.Duration // шаг подробной истории
+	Retention            time.Duration // глубина подробной истории
+	DownsampleResolution time.Duration // шаг прореженной истории (0 — не хранить)
+	DownsampleRetention  time.Duration // глубина прореженной истории
+}
+
+type seriesKey struct {
+	scope  string
+	metric string
+}
+
+// Store — хранилище истории метрик в памяти
+type Store struct {
+	cfg    Config
+	mu     sync.RWMutex
+	series map[seriesKey]*series
+}
+
+func NewStore(cfg Config) *Store {
+	return &Store{cfg: cfg, series: make(map[seriesKey]*series)}
+}
+
+// Config возвращает параметры хранилища
+func (st *Store) Config() Config {
+	return st.cfg
+}
+
+// Add записывает значение метрики для области (HostScope или имя контейнера)
+func (st *Store) Add(scope, metric string, t time.Time, v float64) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return
+	}
+	key := seriesKey{scope: scope, metric: metric}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	s, ok := st.series[key]
+	if !ok {
+		s = &series{raw: newRing(st.cfg.Resolution, st.cfg.Retention)}
+		if st.cfg.DownsampleResolution > 0 && st.cfg.DownsampleRetention > st.cfg.Retention {
+			s.coarse = newRing(st.cfg.DownsampleResolution, st.cfg.DownsampleRetention)
+		}
+		st.series[key] = s
+	}
+	s.add(t.Unix(), v)
+}
+
+// Query возвращает значения метрики в интервале [from, to], усредненные по шагу step.
+// Если from выходит за глубину подробной истории, используется прореженная история
+func (st *Store) Query(scope, metric string, from, to time.Time, step time.Duration) ([]Point, time.Duration, bool) {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	s, ok := st.series[seriesKey{scope: scope, metric: metric}]
+	if !ok {
+		return nil, 0, false
+	}
+
+	// Прореженная история используется, только если в ней есть данные старше подробной
+	source := s.raw
+	if s.coarse != nil && s.coarse.lastSlot >= 0 && from.Unix() < s.raw.oldest() && s.coarse.oldest() < s.raw.oldest() {
+		source = s.coarse
+	}
+	points := source.points(from.Unix(), to.Unix())
+
+	res := time.Duration(source.res) * time.Second
+	if step <= res {
+		return points, res, true
+	}
+	return downsample(points, int64(step/time.Second)), step, true
+}
+
+// downsample усредняет точки по интервалам длиной step секунд
+func downsample(points []Point, step int64) []Point {
+	var result []Point
+	var bucket int64 = -1
+	var sum float64
+	var n int
+	for _, p := range points {
+		b := p.T / step
+		if b != bucket && n > 0 {
+			result = append(result, Point{T: bucket * step, V: sum / float64(n)})
+			sum, n = 0, 0
+		}
+		bucket = b
+		sum += p.V
+		n++
+	}
+	if n > 0 {
+		result = append(result, Point{T: bucket * step, V: sum / float64(n)})
+	}
+	return result
+}
+
+// Scopes возвращает список областей, для которых есть история
+func (st *Store) Scopes() []string {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	seen := make(map[string]bool)
+	for key := range st.series {
+		seen[key.scope] = true
+	}
+	scopes := make([]string, 0, len(seen))
+	for scope := range seen {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// Prune удаляет серии, в которые давно ничего не записывалось
+// (например, метрики удаленных контейнеров)
+func (st *Store) Prune(now time.Time) {
+	retention := st.cfg.Retention
+	if st.cfg.DownsampleRetention > retention {
+		retention = st.cfg.DownsampleRetention
+	}
+	cutoff := now.Add(-retention).Unix()
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for key, s := range st.series {
+		if s.raw.newest() < cutoff {
+			delete(st.series, key)
+		}
+	}
+}
+
+func formatInt(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
+
+func formatFloat(v float64) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "null"
+	}
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
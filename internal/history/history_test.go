@@ -0,0 +1,96 @@
+package history
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryReturnsOnlyRecordedPoints(t *testing.T) {
+	st := NewStore(Config{Resolution: 10 * time.Second, Retention: time.Hour})
+	now := time.Unix(1_700_000_000, 0)
+	st.Add(HostScope, "cpu", now, 42)
+
+	points, res, ok := st.Query(HostScope, "cpu", now.Add(-time.Hour), now, 0)
+	if !ok {
+		t.Fatal("series not found")
+	}
+	if res != 10*time.Second {
+		t.Errorf("resolution = %s, want 10s", res)
+	}
+	if len(points) != 1 || points[0].T != now.Unix() || points[0].V != 42 {
+		t.Fatalf("points = %v, want one point [%d, 42]", points, now.Unix())
+	}
+}
+
+func TestQuerySkipsGaps(t *testing.T) {
+	st := NewStore(Config{Resolution: 10 * time.Second, Retention: time.Hour})
+	start := time.Unix(1_700_000_000, 0)
+	st.Add(HostScope, "cpu", start, 1)
+	st.Add(HostScope, "cpu", start.Add(10*time.Second), 2)
+	st.Add(HostScope, "cpu", start.Add(5*time.Minute), 3)
+
+	points, _, _ := st.Query(HostScope, "cpu", start.Add(-time.Hour), start.Add(time.Hour), 0)
+	want := []Point{{start.Unix(), 1}, {start.Unix() + 10, 2}, {start.Unix() + 300, 3}}
+	if len(points) != len(want) {
+		t.Fatalf("points = %v, want %v", points, want)
+	}
+	for i := range want {
+		if points[i] != want[i] {
+			t.Errorf("point %d = %v, want %v", i, points[i], want[i])
+		}
+	}
+}
+
+func TestQueryAfterWrapAround(t *testing.T) {
+	st := NewStore(Config{Resolution: time.Second, Retention: 10 * time.Second})
+	start := time.Unix(1_700_000_000, 0)
+	for i := 0; i < 25; i++ {
+		st.Add(HostScope, "cpu", start.Add(time.Duration(i)*time.Second), float64(i))
+	}
+
+	points, _, _ := st.Query(HostScope, "cpu", start, start.Add(time.Minute), 0)
+	if len(points) != 10 {
+		t.Fatalf("got %d points, want 10: %v", len(points), points)
+	}
+	if points[0].V != 15 || points[9].V != 24 {
+		t.Errorf("points = %v, want values 15..24", points)
+	}
+}
+
+func TestQueryFallsBackToCoarseOnlyWithOlderData(t *testing.T) {
+	cfg := Config{
+		Resolution:           10 * time.Second,
+		Retention:            time.Minute,
+		DownsampleResolution: time.Minute,
+		DownsampleRetention:  time.Hour,
+	}
+
+	// Молодая серия: прореженная история пуста, запрос за час отвечает подробной
+	st := NewStore(cfg)
+	now := time.Unix(1_700_000_000, 0)
+	st.Add(HostScope, "cpu", now, 5)
+	points, res, _ := st.Query(HostScope, "cpu", now.Add(-time.Hour), now, 0)
+	if res != 10*time.Second || len(points) != 1 {
+		t.Fatalf("young series: res = %s, points = %v; want raw resolution and one point", res, points)
+	}
+
+	// Старая серия: подробная история уже не покрывает начало интервала
+	st = NewStore(cfg)
+	start := time.Unix(1_700_000_000, 0)
+	for i := 0; i <= 60; i++ {
+		st.Add(HostScope, "cpu", start.Add(time.Duration(i)*10*time.Second), 1)
+	}
+	end := start.Add(10 * time.Minute)
+	points, res, _ = st.Query(HostScope, "cpu", start, end, 0)
+	if res != time.Minute {
+		t.Fatalf("old series: res = %s, want 1m", res)
+	}
+	if len(points) != 10 {
+		t.Errorf("old series: got %d points, want 10: %v", len(points), points)
+	}
+	for _, p := range points {
+		if p.V != 1 {
+			t.Errorf("old series: unexpected point %v", p)
+		}
+	}
+}
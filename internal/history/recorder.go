@@ -0,0 +1,224 @@
+package history
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"docker-dashboard/internal/containers"
+	"docker-dashboard/internal/hostinfo"
+	"docker-dashboard/internal/storage"
+)
+
+// Метрики хоста
+var HostMetrics = []string{
+	"cpu", "memory", "memory_percent", "load1", "load5", "load15",
+	"disk_percent", "net_rx_rate", "net_tx_rate",
+}
+
+// Метрики контейнеров
+var ContainerMetrics = []string{
+	"cpu", "memory", "memory_percent", "net_rx_rate", "net_tx_rate",
+	"block_read", "block_write", "pids",
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreOnce sync.Once
+	recorderOnce     sync.Once
+)
+
+// LoadConfig читает параметры хранения истории из переменных окружения
+func LoadConfig() Config {
+	return Config{
+		Resolution:           envDuration("HISTORY_RESOLUTION", 10*time.Second),
+		Retention:            envDuration("HISTORY_RETENTION", 24*time.Hour),
+		DownsampleResolution: envDuration("HISTORY_DOWNSAMPLE_RESOLUTION", 5*time.Minute),
+		DownsampleRetention:  envDuration("HISTORY_DOWNSAMPLE_RETENTION", 7*24*time.Hour),
+	}
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		log.Printf("[docker-dashboard] Invalid %s=%q, using %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+// Default возвращает общее хранилище истории
+func Default() *Store {
+	defaultStoreOnce.Do(func() {
+		defaultStore = NewStore(LoadConfig())
+	})
+	return defaultStore
+}
+
+// StartRecorder запускает фоновую запись метрик хоста и контейнеров в историю (один раз)
+func StartRecorder() {
+	recorderOnce.Do(func() {
+		store := Default()
+		cfg := store.Config()
+		log.Printf("[docker-dashboard] Metrics history: %s resolution for %s, %s resolution for %s",
+			cfg.Resolution, cfg.Retention, cfg.DownsampleResolution, cfg.DownsampleRetention)
+		r := &recorder{store: store, disk: storage.Default()}
+		if r.disk != nil {
+			r.replay()
+		}
+		go r.run(cfg.Resolution)
+	})
+}
+
+type recorder struct {
+	store *Store
+	// Хранилище на диске (nil, если DATA_DIR не задан)
+	disk *storage.Store
+
+	// Предыдущие счетчики сети хоста для вычисления скорости
+	prevNetAt time.Time
+	prevRx    uint64
+	prevTx    uint64
+}
+
+// replay загружает историю с диска после перезапуска
+func (r *recorder) replay() {
+	cfg := r.store.Config()
+	depth := cfg.Retention
+	if cfg.DownsampleRetention > depth {
+		depth = cfg.DownsampleRetention
+	}
+	count := 0
+	err := r.disk.ReplayMetrics(time.Now().Add(-depth), func(rec storage.MetricsRecord) {
+		t := time.Unix(rec.T, 0)
+		for metric, value := range rec.Values {
+			r.store.Add(rec.Scope, metric, t, value)
+		}
+		count++
+	})
+	if err != nil {
+		log.Printf("[docker-dashboard] history: failed to replay metrics: %v", err)
+		return
+	}
+	log.Printf("[docker-dashboard] history: replayed %d records from disk", count)
+}
+
+func (r *recorder) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		var records []storage.MetricsRecord
+		if rec, ok := r.recordHost(now); ok {
+			records = append(records, rec)
+		}
+		records = append(records, r.recordContainers(now)...)
+
+		for _, rec := range records {
+			for metric, value := range rec.Values {
+				r.store.Add(rec.Scope, metric, now, value)
+			}
+		}
+		if r.disk != nil {
+			if err := r.disk.AppendMetrics(records); err != nil {
+				log.Printf("[docker-dashboard] history: failed to persist metrics: %v", err)
+			}
+		}
+		r.store.Prune(now)
+	}
+}
+
+func (r *recorder) recordHost(now time.Time) (storage.MetricsRecord, bool) {
+	metrics, err := hostinfo.GetSystemMetrics()
+	if err != nil {
+		log.Printf("[docker-dashboard] history: failed to get host metrics: %v", err)
+		return storage.MetricsRecord{}, false
+	}
+	values := HostValues(metrics)
+
+	if len(metrics.Net) > 0 {
+		rx, tx := metrics.Net[0].BytesRecv, metrics.Net[0].BytesSent
+		if !r.prevNetAt.IsZero() && rx >= r.prevRx && tx >= r.prevTx {
+			elapsed := now.Sub(r.prevNetAt).Seconds()
+			if elapsed > 0 {
+				values["net_rx_rate"] = float64(rx-r.prevRx) / elapsed
+				values["net_tx_rate"] = float64(tx-r.prevTx) / elapsed
+			}
+		}
+		r.prevNetAt, r.prevRx, r.prevTx = now, rx, tx
+	}
+	return storage.MetricsRecord{T: now.Unix(), Scope: HostScope, Values: values}, true
+}
+
+// HostValues возвращает мгновенные значения метрик хоста (кроме скоростей сети)
+func HostValues(metrics *hostinfo.SystemMetrics) map[string]float64 {
+	values := make(map[string]float64)
+	if len(metrics.CPU) > 0 {
+		values["cpu"] = metrics.CPU[0]
+	}
+	if metrics.Memory != nil {
+		values["memory"] = float64(metrics.Memory.Used)
+		values["memory_percent"] = metrics.Memory.UsedPercent
+	}
+	if metrics.Load != nil {
+		values["load1"] = metrics.Load.Load1
+		values["load5"] = metrics.Load.Load5
+		values["load15"] = metrics.Load.Load15
+	}
+	// Заполненность самого загруженного раздела
+	var diskPercent float64
+	for _, usage := range metrics.DiskUsage {
+		if usage.UsedPercent > diskPercent {
+			diskPercent = usage.UsedPercent
+		}
+	}
+	if len(metrics.DiskUsage) > 0 {
+		values["disk_percent"] = diskPercent
+	}
+	return values
+}
+
+func (r *recorder) recordContainers(now time.Time) []storage.MetricsRecord {
+	stats, err := containers.GetContainersStats()
+	if err != nil {
+		log.Printf("[docker-dashboard] history: failed to get container stats: %v", err)
+		return nil
+	}
+	list, err := containers.GetContainers()
+	if err != nil {
+		log.Printf("[docker-dashboard] history: failed to get containers: %v", err)
+		return nil
+	}
+	// История хранится по имени контейнера, чтобы переживать пересоздание контейнера
+	names := make(map[string]string, len(list))
+	for _, c := range list {
+		names[c.ID] = c.Name
+	}
+	records := make([]storage.MetricsRecord, 0, len(stats))
+	for _, s := range stats {
+		name, ok := names[s.ID]
+		if !ok {
+			continue
+		}
+		records = append(records, storage.MetricsRecord{T: now.Unix(), Scope: name, Values: ContainerValues(s)})
+	}
+	return records
+}
+
+// ContainerValues возвращает значения метрик контейнера
+func ContainerValues(s containers.ContainerStats) map[string]float64 {
+	return map[string]float64{
+		"cpu":            s.CPUUsage,
+		"memory":         float64(s.MemoryUsage),
+		"memory_percent": s.MemoryPercent,
+		"net_rx_rate":    s.NetworkRxRate,
+		"net_tx_rate":    s.NetworkTxRate,
+		"block_read":     float64(s.BlockRead),
+		"block_write":    float64(s.BlockWrite),
+		"pids":           float64(s.PIDs),
+	}
+}
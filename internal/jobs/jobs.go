@@ -0,0 +1,297 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status — состояние задачи
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+const (
+	// Сколько хранятся завершенные задачи и ключи идемпотентности
+	retention = 24 * time.Hour
+	// Максимальное число хранимых завершенных задач
+	maxFinished = 1000
+)
+
+// ErrIdempotencyMismatch — ключ идемпотентности уже использован для другого запроса
+var ErrIdempotencyMismatch = errors.New("idempotency key was already used for a different request")
+
+// Job — фоновая задача (действие над контейнером или проектом)
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`   // например restart
+	Target     string      `json:"target"` // контейнер или проект
+	User       string      `json:"user,omitempty"`
+	Status     Status      `json:"status"`
+	CreatedAt  time.Time   `json:"created_at"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+	Progress   interface{} `json:"progress,omitempty"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Done сообщает, что задача завершена
+func (j Job) Done() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// Func выполняет задачу. update публикует промежуточный прогресс.
+// Результат сохраняется и при ошибке (например, структурированная ошибка Docker)
+type Func func(ctx context.Context, update func(progress interface{})) (interface{}, error)
+
+type entry struct {
+	job      Job
+	done     chan struct{}
+	watchers map[chan Job]struct{}
+}
+
+type idempotencyEntry struct {
+	fingerprint string
+	jobID       string
+	created     time.Time
+	// Итог задачи, удаленной из jobs раньше ключа (при превышении maxFinished):
+	// повтор запроса получает его, а не запускает действие заново
+	job *Job
+}
+
+// Manager хранит задачи в памяти и рассылает их изменения подписчикам
+type Manager struct {
+	mu          sync.Mutex
+	jobs        map[string]*entry
+	idempotency map[string]idempotencyEntry
+}
+
+var (
+	defaultManager     *Manager
+	defaultManagerOnce sync.Once
+)
+
+// Default возвращает общий менеджер задач
+func Default() *Manager {
+	defaultManagerOnce.Do(func() {
+		defaultManager = NewManager()
+	})
+	return defaultManager
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		jobs:        make(map[string]*entry),
+		idempotency: make(map[string]idempotencyEntry),
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("failed to generate job id: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Start запускает задачу в фоне с ограничением по времени.
+// Если задан idempotencyKey и он уже использовался с тем же fingerprint,
+// новая задача не запускается и возвращается существующая
+func (m *Manager) Start(idempotencyKey, fingerprint, kind, target, user string, timeout time.Duration, fn Func) (Job, error) {
+	m.mu.Lock()
+	m.pruneLocked()
+	if idempotencyKey != "" {
+		if prev, ok := m.idempotency[idempotencyKey]; ok {
+			if prev.fingerprint != fingerprint {
+				m.mu.Unlock()
+				return Job{}, ErrIdempotencyMismatch
+			}
+			job := prev.job
+			if e, ok := m.jobs[prev.jobID]; ok {
+				job = &e.job
+			}
+			// Выполняющиеся задачи не удаляются, а для удаленных завершенных сохранен итог
+			if job != nil {
+				result := *job
+				m.mu.Unlock()
+				return result, nil
+			}
+		}
+	}
+
+	e := &entry{
+		job: Job{
+			ID:        newJobID(),
+			Kind:      kind,
+			Target:    target,
+			User:      user,
+			Status:    StatusRunning,
+			CreatedAt: time.Now(),
+		},
+		done:     make(chan struct{}),
+		watchers: make(map[chan Job]struct{}),
+	}
+	m.jobs[e.job.ID] = e
+	if idempotencyKey != "" {
+		m.idempotency[idempotencyKey] = idempotencyEntry{fingerprint: fingerprint, jobID: e.job.ID, created: e.job.CreatedAt}
+	}
+	job := e.job
+	m.mu.Unlock()
+
+	go m.run(e, timeout, fn)
+	return job, nil
+}
+
+type jobIDKey struct{}
+
+// IDFromContext возвращает ID задачи, внутри которой выполняется Func
+func IDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(jobIDKey{}).(string)
+	return id
+}
+
+func (m *Manager) run(e *entry, timeout time.Duration, fn Func) {
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), jobIDKey{}, e.job.ID), timeout)
+	defer cancel()
+
+	update := func(progress interface{}) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		e.job.Progress = progress
+		m.notifyLocked(e)
+	}
+	result, err := fn(ctx, update)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	e.job.FinishedAt = &now
+	e.job.Result = result
+	e.job.Status = StatusSucceeded
+	if err != nil {
+		e.job.Status = StatusFailed
+		e.job.Error = err.Error()
+	}
+	m.notifyLocked(e)
+	for ch := range e.watchers {
+		close(ch)
+	}
+	e.watchers = nil
+	close(e.done)
+}
+
+// notifyLocked отправляет текущее состояние задачи подписчикам.
+// Подписчик получает только последнее состояние, промежуточные могут быть пропущены
+func (m *Manager) notifyLocked(e *entry) {
+	for ch := range e.watchers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- e.job
+	}
+}
+
+// Get возвращает задачу по ID
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// Wait ждет завершения задачи или отмены ctx и возвращает ее текущее состояние
+func (m *Manager) Wait(ctx context.Context, id string) (Job, bool) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, false
+	}
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+	return m.Get(id)
+}
+
+// Watch подписывается на изменения задачи. Канал сразу получает текущее состояние
+// и закрывается после завершения задачи; cancel отменяет подписку
+func (m *Manager) Watch(id string) (<-chan Job, func(), bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return nil, nil, false
+	}
+	ch := make(chan Job, 1)
+	ch <- e.job
+	if e.job.Done() {
+		close(ch)
+		return ch, func() {}, true
+	}
+	e.watchers[ch] = struct{}{}
+	cancel := func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := e.watchers[ch]; ok {
+			delete(e.watchers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel, true
+}
+
+// pruneLocked удаляет старые завершенные задачи и ключи идемпотентности.
+// Ключ может пережить свою задачу: тогда в нем остается итог задачи без промежуточного прогресса
+func (m *Manager) pruneLocked() {
+	cutoff := time.Now().Add(-retention)
+	for key, idem := range m.idempotency {
+		if idem.created.Before(cutoff) {
+			delete(m.idempotency, key)
+		}
+	}
+	var finished []*entry
+	for id, e := range m.jobs {
+		if !e.job.Done() {
+			continue
+		}
+		if e.job.FinishedAt.Before(cutoff) {
+			delete(m.jobs, id)
+			continue
+		}
+		finished = append(finished, e)
+	}
+	if len(finished) <= maxFinished {
+		return
+	}
+	// Слишком много задач: удаляем самые старые
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].job.FinishedAt.Before(*finished[j].job.FinishedAt)
+	})
+	removed := make(map[string]Job)
+	for _, e := range finished[:len(finished)-maxFinished] {
+		delete(m.jobs, e.job.ID)
+		job := e.job
+		job.Progress = nil
+		removed[job.ID] = job
+	}
+	for key, idem := range m.idempotency {
+		if job, ok := removed[idem.jobID]; ok {
+			idem.job = &job
+			m.idempotency[key] = idem
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func finish(t *testing.T, m *Manager, job Job) Job {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, ok := m.Wait(ctx, job.ID)
+	if !ok || !job.Done() {
+		t.Fatalf("job %s did not finish: %+v", job.ID, job)
+	}
+	return job
+}
+
+func TestIdempotencyKeySurvivesPrunedJob(t *testing.T) {
+	m := NewManager()
+	var runs atomic.Int32
+	action := func(ctx context.Context, update func(interface{})) (interface{}, error) {
+		runs.Add(1)
+		update("halfway")
+		return "restarted", nil
+	}
+	first, err := m.Start("key", "restart api", "restart", "api", "alice", time.Minute, action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	finish(t, m, first)
+
+	// Завершенных задач больше maxFinished: первая удаляется из менеджера
+	for i := 0; i < maxFinished+1; i++ {
+		job, err := m.Start("", "", "restart", "web", "alice", time.Minute, func(context.Context, func(interface{})) (interface{}, error) {
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		finish(t, m, job)
+	}
+	m.mu.Lock()
+	m.pruneLocked()
+	m.mu.Unlock()
+	if _, ok := m.Get(first.ID); ok {
+		t.Fatal("first job was not pruned")
+	}
+
+	replay, err := m.Start("key", "restart api", "restart", "api", "alice", time.Minute, action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs.Load() != 1 {
+		t.Fatalf("action ran %d times, want 1", runs.Load())
+	}
+	if replay.ID != first.ID || replay.Status != StatusSucceeded || replay.Result != "restarted" || replay.Progress != nil {
+		t.Errorf("replayed job = %+v", replay)
+	}
+
+	if _, err := m.Start("key", "stop api", "stop", "api", "alice", time.Minute, action); !errors.Is(err, ErrIdempotencyMismatch) {
+		t.Errorf("err = %v, want ErrIdempotencyMismatch", err)
+	}
+}
+
+func TestIdempotencyKeyReturnsRunningJob(t *testing.T) {
+	m := NewManager()
+	release := make(chan struct{})
+	var runs atomic.Int32
+	action := func(ctx context.Context, _ func(interface{})) (interface{}, error) {
+		runs.Add(1)
+		<-release
+		return nil, errors.New("failed")
+	}
+	first, err := m.Start("key", "kill api", "kill", "api", "", time.Minute, action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := m.Start("key", "kill api", "kill", "api", "", time.Minute, action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.ID != first.ID || second.Done() {
+		t.Errorf("second = %+v, want the running job %s", second, first.ID)
+	}
+	close(release)
+	if job := finish(t, m, first); job.Status != StatusFailed || job.Error != "failed" {
+		t.Errorf("job = %+v", job)
+	}
+	if runs.Load() != 1 {
+		t.Errorf("action ran %d times, want 1", runs.Load())
+	}
+}
@@ -0,0 +1,103 @@
+package logstream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Операторы условий, более длинные проверяются раньше
+var conditionOperators = []string{">=", "<=", "!=", "=", ">", "<"}
+
+// Condition — условие на поле структурированной строки: level>=warn, request_id=abc, status>=500
+type Condition struct {
+	Key   string
+	Op    string
+	Value string
+}
+
+// ParseCondition разбирает условие вида key<op>value, где op — =, !=, >, >=, <, <=.
+// Для level значения сравниваются по важности уровня, для чисел — как числа
+func ParseCondition(expr string) (Condition, error) {
+	i := strings.IndexAny(expr, "=!<>")
+	if i <= 0 {
+		return Condition{}, fmt.Errorf("invalid condition %q: expected key=value, key!=value, key>=value, ...", expr)
+	}
+	cond := Condition{Key: strings.TrimSpace(expr[:i])}
+	rest := expr[i:]
+	for _, op := range conditionOperators {
+		if strings.HasPrefix(rest, op) {
+			cond.Op = op
+			cond.Value = strings.Trim(strings.TrimSpace(rest[len(op):]), `"`)
+			break
+		}
+	}
+	if cond.Op == "" || cond.Key == "" {
+		return Condition{}, fmt.Errorf("invalid condition %q", expr)
+	}
+	if cond.Key == "level" {
+		cond.Value = NormalizeLevel(cond.Value)
+		if cond.Op != "=" && cond.Op != "!=" && LevelRank(cond.Value) < 0 {
+			return Condition{}, fmt.Errorf("unknown level %q, expected one of %s", cond.Value, strings.Join(Levels, ", "))
+		}
+	}
+	return cond, nil
+}
+
+// Match проверяет условие. Строки без поля проходят только условие !=
+func (c Condition) Match(s *Structured) bool {
+	if s == nil {
+		return c.Op == "!="
+	}
+	value, ok := s.Value(c.Key)
+	if !ok {
+		return c.Op == "!="
+	}
+	if c.Key == "level" {
+		value = NormalizeLevel(value)
+	}
+	switch c.Op {
+	case "=":
+		return value == c.Value
+	case "!=":
+		return value != c.Value
+	}
+	return compareOrdered(c.Key, value, c.Value, c.Op)
+}
+
+// compareOrdered сравнивает значения для >, >=, <, <=: уровни по важности,
+// числа как числа, остальное как строки
+func compareOrdered(key, a, b, op string) bool {
+	var cmp int
+	if key == "level" {
+		ra, rb := LevelRank(a), LevelRank(b)
+		if ra < 0 {
+			return false
+		}
+		cmp = ra - rb
+	} else if fa, err := strconv.ParseFloat(a, 64); err == nil {
+		fb, err := strconv.ParseFloat(b, 64)
+		if err != nil {
+			return false
+		}
+		switch {
+		case fa < fb:
+			cmp = -1
+		case fa > fb:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(a, b)
+	}
+	switch op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
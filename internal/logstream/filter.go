@@ -0,0 +1,65 @@
+package logstream
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// maxPatternLength — ограничение длины шаблона фильтра
+const maxPatternLength = 1024
+
+// Matcher проверяет строки лога на соответствие подстроке или регулярному выражению.
+// nil Matcher пропускает все строки
+type Matcher struct {
+	substring  string
+	re         *regexp.Regexp
+	ignoreCase bool
+	invert     bool
+}
+
+// NewMatcher создает фильтр строк. Пустой pattern — без фильтрации (возвращается nil).
+// regex — pattern является регулярным выражением (синтаксис RE2), ignoreCase — без учета регистра,
+// invert — пропускать строки, которые не совпадают
+func NewMatcher(pattern string, regex, ignoreCase, invert bool) (*Matcher, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if len(pattern) > maxPatternLength {
+		return nil, fmt.Errorf("pattern is longer than %d characters", maxPatternLength)
+	}
+	m := &Matcher{ignoreCase: ignoreCase, invert: invert}
+	if regex {
+		if ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		m.re = re
+		return m, nil
+	}
+	m.substring = pattern
+	if ignoreCase {
+		m.substring = strings.ToLower(pattern)
+	}
+	return m, nil
+}
+
+// Match сообщает, проходит ли строка фильтр
+func (m *Matcher) Match(text string) bool {
+	if m == nil {
+		return true
+	}
+	var matched bool
+	switch {
+	case m.re != nil:
+		matched = m.re.MatchString(text)
+	case m.ignoreCase:
+		matched = strings.Contains(strings.ToLower(text), m.substring)
+	default:
+		matched = strings.Contains(text, m.substring)
+	}
+	return matched != m.invert
+}
@@ -0,0 +1,199 @@
+// Package logstream разбирает поток логов Docker API (/containers/{id}/logs) на строки.
+//
+// Для контейнеров без TTY Docker мультиплексирует stdout и stderr в кадры:
+// [stream (1 байт)][3 байта нулей][размер данных (4 байта, big-endian)][данные].
+// Для контейнеров с TTY (Config.Tty) поток идет как есть, без кадров.
+package logstream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Потоки в заголовке кадра
+const (
+	Stdin     = 0
+	Stdout    = 1
+	Stderr    = 2
+	Systemerr = 3 // ошибка Docker при чтении логов, данные — текст ошибки
+)
+
+const (
+	// MaxLineSize — строка длиннее разбивается на несколько
+	MaxLineSize = 1024 * 1024
+	// maxFrameSize — кадр больше считается поврежденным потоком
+	maxFrameSize = 4 * 1024 * 1024
+	// Размер порции чтения потока TTY
+	ttyChunkSize = 32 * 1024
+)
+
+// ErrCorruptStream — поток не похож на мультиплексированные кадры Docker
+var ErrCorruptStream = errors.New("logstream: corrupt multiplexed stream")
+
+// timestampFormat — формат меток времени Docker (RFC3339 с наносекундами фиксированной длины)
+const timestampFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// Line — строка лога без завершающего перевода строки
+type Line struct {
+	Stream    int       // Stdout или Stderr; для TTY всегда Stdout
+	Timestamp time.Time // нулевое, если метки времени не запрошены
+	Text      string
+}
+
+// partialLine — незавершенная строка потока
+type partialLine struct {
+	buf  []byte
+	open bool
+}
+
+// Reader читает строки из потока логов
+type Reader struct {
+	src        *bufio.Reader
+	tty        bool
+	timestamps bool
+
+	header  [8]byte
+	chunk   []byte
+	pending [3]partialLine // по потокам stdin, stdout, stderr
+	queue   []Line
+	err     error
+}
+
+// NewReader создает Reader для потока логов.
+// tty — контейнер запущен с TTY (Config.Tty), поток без кадров.
+// timestamps — логи запрошены с timestamps=true: метка времени отделяется от текста в Line.Timestamp
+func NewReader(r io.Reader, tty, timestamps bool) *Reader {
+	return &Reader{src: bufio.NewReaderSize(r, 32*1024), tty: tty, timestamps: timestamps}
+}
+
+// Next возвращает следующую строку. В конце потока незавершенные строки возвращаются
+// как есть, затем io.EOF
+func (r *Reader) Next() (Line, error) {
+	for len(r.queue) == 0 {
+		if r.err != nil {
+			return Line{}, r.err
+		}
+		if err := r.fill(); err != nil {
+			r.err = err
+			r.flush()
+		}
+	}
+	line := r.queue[0]
+	r.queue = r.queue[1:]
+	return line, nil
+}
+
+// fill читает следующий кадр (или порцию потока TTY)
+func (r *Reader) fill() error {
+	if r.tty {
+		if r.chunk == nil {
+			r.chunk = make([]byte, ttyChunkSize)
+		}
+		n, err := r.src.Read(r.chunk)
+		r.push(Stdout, r.chunk[:n], false)
+		return err
+	}
+
+	if _, err := io.ReadFull(r.src, r.header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w: truncated frame header", ErrCorruptStream)
+		}
+		return err
+	}
+	stream := int(r.header[0])
+	size := binary.BigEndian.Uint32(r.header[4:])
+	if stream > Systemerr || r.header[1] != 0 || r.header[2] != 0 || r.header[3] != 0 {
+		return fmt.Errorf("%w: invalid frame header %x", ErrCorruptStream, r.header)
+	}
+	if size > maxFrameSize {
+		return fmt.Errorf("%w: frame of %d bytes", ErrCorruptStream, size)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r.src, payload); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w: truncated frame", ErrCorruptStream)
+		}
+		return err
+	}
+	if stream == Systemerr {
+		return fmt.Errorf("docker: %s", bytes.TrimSpace(payload))
+	}
+	r.push(stream, payload, true)
+	return nil
+}
+
+// push добавляет данные потока и выделяет завершенные строки.
+// Docker пишет каждое сообщение лога отдельным кадром и разбивает длинные строки
+// на сообщения по 16KB; при timestamps=true каждая часть получает свою метку времени,
+// поэтому у продолжения строки (entryStart при незавершенной строке) метка отбрасывается
+func (r *Reader) push(stream int, data []byte, entryStart bool) {
+	p := &r.pending[stream]
+	if r.timestamps && entryStart && p.open {
+		if _, rest, ok := cutTimestamp(data); ok {
+			data = rest
+		}
+	}
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			p.buf = append(p.buf, data...)
+			p.open = true
+			if len(p.buf) >= MaxLineSize {
+				r.emit(stream, p)
+			}
+			return
+		}
+		p.buf = append(p.buf, data[:i]...)
+		r.emit(stream, p)
+		data = data[i+1:]
+	}
+}
+
+// emit переносит накопленную строку потока в очередь
+func (r *Reader) emit(stream int, p *partialLine) {
+	text := p.buf
+	line := Line{Stream: stream}
+	if r.timestamps {
+		if ts, rest, ok := cutTimestamp(text); ok {
+			line.Timestamp, text = ts, rest
+		}
+	}
+	// Вывод TTY завершает строки \r\n
+	text = bytes.TrimSuffix(text, []byte{'\r'})
+	line.Text = string(text)
+	r.queue = append(r.queue, line)
+	p.buf = p.buf[:0]
+	p.open = false
+}
+
+// flush отдает незавершенные строки в конце потока
+func (r *Reader) flush() {
+	for stream := range r.pending {
+		if p := &r.pending[stream]; p.open {
+			r.emit(stream, p)
+		}
+	}
+}
+
+// cutTimestamp отделяет метку времени Docker "2024-01-02T15:04:05.123456789Z " от начала данных
+func cutTimestamp(data []byte) (time.Time, []byte, bool) {
+	i := bytes.IndexByte(data, ' ')
+	if i < len("2006-01-02T15:04:05Z") || i > len(timestampFormat) {
+		return time.Time{}, data, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, string(data[:i]))
+	if err != nil {
+		return time.Time{}, data, false
+	}
+	return ts, data[i+1:], true
+}
+
+// FormatTimestamp форматирует метку времени так же, как Docker в логах с timestamps=true
+func FormatTimestamp(t time.Time) string {
+	return t.UTC().Format(timestampFormat)
+}
@@ -0,0 +1,530 @@
+package logstream
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// frame кодирует кадр мультиплексированного потока так же, как Docker (stdcopy)
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func frames(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+func readAll(t *testing.T, r *Reader) ([]Line, error) {
+	t.Helper()
+	var lines []Line
+	for {
+		line, err := r.Next()
+		if err != nil {
+			if err == io.EOF {
+				return lines, nil
+			}
+			return lines, err
+		}
+		lines = append(lines, line)
+	}
+}
+
+func assertLines(t *testing.T, got []Line, want []Line) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Stream != want[i].Stream || got[i].Text != want[i].Text || !got[i].Timestamp.Equal(want[i].Timestamp) {
+			t.Errorf("line %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	ts, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ts
+}
+
+func TestMultiplexed(t *testing.T) {
+	stream := frames(
+		frame(Stdout, "starting server\n"),
+		frame(Stderr, "warning: config not found\n"),
+		frame(Stdout, "listening on :8080\nready\n"),
+	)
+	lines, err := readAll(t, NewReader(bytes.NewReader(stream), false, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, lines, []Line{
+		{Stream: Stdout, Text: "starting server"},
+		{Stream: Stderr, Text: "warning: config not found"},
+		{Stream: Stdout, Text: "listening on :8080"},
+		{Stream: Stdout, Text: "ready"},
+	})
+}
+
+func TestMultiplexedShortReads(t *testing.T) {
+	stream := frames(
+		frame(Stdout, "2024-01-02T15:04:05.123456789Z first line\n"),
+		frame(Stderr, "2024-01-02T15:04:06.000000001Z second line\n"),
+	)
+	// Каждое чтение возвращает по одному байту: заголовки и данные приходят по частям
+	lines, err := readAll(t, NewReader(iotest.OneByteReader(bytes.NewReader(stream)), false, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, lines, []Line{
+		{Stream: Stdout, Text: "first line", Timestamp: mustTime(t, "2024-01-02T15:04:05.123456789Z")},
+		{Stream: Stderr, Text: "second line", Timestamp: mustTime(t, "2024-01-02T15:04:06.000000001Z")},
+	})
+}
+
+func TestLongLineSplitAt16KB(t *testing.T) {
+	// Docker разбивает строку на сообщения по 16KB, каждое со своей меткой времени
+	long := strings.Repeat("a", 16*1024) + strings.Repeat("b", 16*1024) + "tail"
+	stream := frames(
+		frame(Stdout, "2024-01-02T15:04:05.000000001Z "+long[:16*1024]),
+		frame(Stderr, "2024-01-02T15:04:05.000000002Z interleaved\n"),
+		frame(Stdout, "2024-01-02T15:04:05.000000003Z "+long[16*1024:32*1024]),
+		frame(Stdout, "2024-01-02T15:04:05.000000004Z "+long[32*1024:]+"\n"),
+	)
+	lines, err := readAll(t, NewReader(bytes.NewReader(stream), false, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, lines, []Line{
+		{Stream: Stderr, Text: "interleaved", Timestamp: mustTime(t, "2024-01-02T15:04:05.000000002Z")},
+		{Stream: Stdout, Text: long, Timestamp: mustTime(t, "2024-01-02T15:04:05.000000001Z")},
+	})
+}
+
+func TestLongLineWithoutTimestamps(t *testing.T) {
+	long := strings.Repeat("x", 40*1024)
+	stream := frames(
+		frame(Stdout, long[:16*1024]),
+		frame(Stdout, long[16*1024:32*1024]),
+		frame(Stdout, long[32*1024:]+"\n"),
+	)
+	lines, err := readAll(t, NewReader(bytes.NewReader(stream), false, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, lines, []Line{{Stream: Stdout, Text: long}})
+}
+
+func TestUnterminatedLastLine(t *testing.T) {
+	stream := frames(frame(Stdout, "done\nno newline"))
+	lines, err := readAll(t, NewReader(bytes.NewReader(stream), false, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, lines, []Line{
+		{Stream: Stdout, Text: "done"},
+		{Stream: Stdout, Text: "no newline"},
+	})
+}
+
+func TestTTY(t *testing.T) {
+	// Контейнер с TTY: сырой текст с \r\n и управляющими последовательностями, без кадров
+	stream := "\x1b[32mgreen\x1b[0m\r\nprompt$ ls\r\nbin  etc\r\n"
+	lines, err := readAll(t, NewReader(iotest.HalfReader(strings.NewReader(stream)), true, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, lines, []Line{
+		{Stream: Stdout, Text: "\x1b[32mgreen\x1b[0m"},
+		{Stream: Stdout, Text: "prompt$ ls"},
+		{Stream: Stdout, Text: "bin  etc"},
+	})
+}
+
+func TestTTYDoesNotTreatTextAsFrames(t *testing.T) {
+	// Первые байты текста TTY не должны читаться как заголовок кадра
+	stream := "\x01\x00\x00\x00hello\n"
+	lines, err := readAll(t, NewReader(strings.NewReader(stream), true, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, lines, []Line{{Stream: Stdout, Text: "\x01\x00\x00\x00hello"}})
+}
+
+func TestTTYWithTimestamps(t *testing.T) {
+	stream := "2024-01-02T15:04:05.123456789Z one\r\n2024-01-02T15:04:06Z two\r\n"
+	// Метка времени разрезана между чтениями
+	lines, err := readAll(t, NewReader(iotest.OneByteReader(strings.NewReader(stream)), true, true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLines(t, lines, []Line{
+		{Stream: Stdout, Text: "one", Timestamp: mustTime(t, "2024-01-02T15:04:05.123456789Z")},
+		{Stream: Stdout, Text: "two", Timestamp: mustTime(t, "2024-01-02T15:04:06Z")},
+	})
+}
+
+func TestTruncatedStream(t *testing.T) {
+	full := frames(frame(Stdout, "complete\n"), frame(Stdout, "cut off here\n"))
+	for _, size := range []int{len(full) - 3, len(frame(Stdout, "complete\n")) + 4} {
+		r := NewReader(bytes.NewReader(full[:size]), false, false)
+		lines, err := readAll(t, r)
+		if !errors.Is(err, ErrCorruptStream) {
+			t.Errorf("size %d: got error %v, want ErrCorruptStream", size, err)
+		}
+		assertLines(t, lines, []Line{{Stream: Stdout, Text: "complete"}})
+	}
+}
+
+func TestInvalidHeader(t *testing.T) {
+	// Поток TTY, ошибочно прочитанный как мультиплексированный
+	_, err := readAll(t, NewReader(strings.NewReader("plain text log line\n"), false, false))
+	if !errors.Is(err, ErrCorruptStream) {
+		t.Fatalf("got error %v, want ErrCorruptStream", err)
+	}
+}
+
+func TestSystemError(t *testing.T) {
+	stream := frames(frame(Stdout, "before\n"), frame(Systemerr, "error from daemon in stream: log file rotated\n"))
+	lines, err := readAll(t, NewReader(bytes.NewReader(stream), false, false))
+	if err == nil || !strings.Contains(err.Error(), "log file rotated") {
+		t.Fatalf("got error %v, want daemon error", err)
+	}
+	assertLines(t, lines, []Line{{Stream: Stdout, Text: "before"}})
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	ts := mustTime(t, "2024-01-02T15:04:06.000000001Z")
+	if got := FormatTimestamp(ts); got != "2024-01-02T15:04:06.000000001Z" {
+		t.Errorf("got %s", got)
+	}
+	if got := FormatTimestamp(mustTime(t, "2024-01-02T15:04:06Z")); got != "2024-01-02T15:04:06.000000000Z" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		regex      bool
+		ignoreCase bool
+		invert     bool
+		text       string
+		want       bool
+	}{
+		{"substring", "timeout", false, false, false, "request timeout after 5s", true},
+		{"substring miss", "timeout", false, false, false, "request done", false},
+		{"substring is case sensitive", "ERROR", false, false, false, "error: disk full", false},
+		{"substring ignore case", "ERROR", false, true, false, "Error: disk full", true},
+		{"substring is not a regex", "a.c", false, false, false, "abc", false},
+		{"substring with regex characters", "a.c", false, false, false, "file a.c", true},
+		{"regex", `status=5\d\d`, true, false, false, "GET / status=503", true},
+		{"regex miss", `status=5\d\d`, true, false, false, "GET / status=200", false},
+		{"regex is case sensitive", "^warn", true, false, false, "WARN low memory", false},
+		{"regex ignore case", "^warn", true, true, false, "WARN low memory", true},
+		{"invert substring", "health", false, false, true, "GET /health 200", false},
+		{"invert substring miss", "health", false, false, true, "GET /api 200", true},
+		{"invert regex ignore case", "^debug", true, true, true, "DEBUG cache hit", false},
+		{"invert regex ignore case miss", "^debug", true, true, true, "INFO started", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMatcher(tt.pattern, tt.regex, tt.ignoreCase, tt.invert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := m.Match(tt.text); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherEmptyPattern(t *testing.T) {
+	m, err := NewMatcher("", true, true, true)
+	if err != nil || m != nil {
+		t.Fatalf("NewMatcher(\"\") = %v, %v; want nil, nil", m, err)
+	}
+	if !m.Match("anything") {
+		t.Error("nil Matcher must pass all lines")
+	}
+}
+
+func TestMatcherInvalid(t *testing.T) {
+	if _, err := NewMatcher("a(b", true, false, false); err == nil {
+		t.Error("invalid regex: expected error")
+	}
+	if _, err := NewMatcher("a(b", false, false, false); err != nil {
+		t.Errorf("substring with parenthesis: %v", err)
+	}
+	if _, err := NewMatcher(strings.Repeat("x", maxPatternLength+1), false, false, false); err == nil {
+		t.Error("too long pattern: expected error")
+	}
+}
+
+func TestNormalizeLevel(t *testing.T) {
+	tests := map[string]string{
+		"WARN": "warn", "warning": "warn", "wrn": "warn",
+		"err": "error", "ERROR": "error",
+		"notice": "info", "information": "info",
+		"dbg": "debug", "verbose": "trace",
+		"panic": "fatal", "CRITICAL": "fatal", "emerg": "fatal",
+		// числовые уровни pino и bunyan
+		"10": "trace", "20": "debug", "30": "info", "40": "warn", "50": "error", "60": "fatal",
+		" Info ": "info",
+		"custom": "custom",
+	}
+	for in, want := range tests {
+		if got := NormalizeLevel(in); got != want {
+			t.Errorf("NormalizeLevel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		expr string
+		want Condition
+	}{
+		{"level>=warn", Condition{Key: "level", Op: ">=", Value: "warn"}},
+		{"level>=warning", Condition{Key: "level", Op: ">=", Value: "warn"}},
+		{"level=ERR", Condition{Key: "level", Op: "=", Value: "error"}},
+		{"level!=debug", Condition{Key: "level", Op: "!=", Value: "debug"}},
+		{"level<info", Condition{Key: "level", Op: "<", Value: "info"}},
+		{"level<=info", Condition{Key: "level", Op: "<=", Value: "info"}},
+		{"level>error", Condition{Key: "level", Op: ">", Value: "error"}},
+		{"status>=500", Condition{Key: "status", Op: ">=", Value: "500"}},
+		{"request_id=abc", Condition{Key: "request_id", Op: "=", Value: "abc"}},
+		{`user = "john doe"`, Condition{Key: "user", Op: "=", Value: "john doe"}},
+		{"http.method!=GET", Condition{Key: "http.method", Op: "!=", Value: "GET"}},
+		{"path=", Condition{Key: "path", Op: "=", Value: ""}},
+		// для = и != допускается любой уровень
+		{"level=custom", Condition{Key: "level", Op: "=", Value: "custom"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseCondition(tt.expr)
+		if err != nil {
+			t.Errorf("ParseCondition(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseCondition(%q) = %+v, want %+v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "level", "=warn", ">=500", "key!value", " >= 1", "level>=loud"} {
+		if cond, err := ParseCondition(expr); err == nil {
+			t.Errorf("ParseCondition(%q) = %+v, want error", expr, cond)
+		}
+	}
+}
+
+func TestConditionMatch(t *testing.T) {
+	line := &Structured{
+		Format:  FormatJSON,
+		Level:   "warn",
+		Message: "slow request",
+		Fields:  map[string]string{"status": "503", "duration": "9.5", "method": "GET"},
+	}
+	tests := []struct {
+		expr string
+		s    *Structured
+		want bool
+	}{
+		{"level>=warn", line, true},
+		{"level>=warning", line, true},
+		{"level>warn", line, false},
+		{"level>=error", line, false},
+		{"level<error", line, true},
+		{"level<=info", line, false},
+		{"level=warn", line, true},
+		{"level!=warn", line, false},
+		{"level>=warn", &Structured{Format: FormatText, Level: "custom"}, false},
+		{"msg=slow request", line, true},
+		{"message!=slow request", line, false},
+		// числа сравниваются как числа, а не как строки
+		{"status>=500", line, true},
+		{"status<60", line, false},
+		{"duration>10", line, false},
+		{"duration>9.25", line, true},
+		{"status>abc", line, false},
+		{"method>=GET", line, true},
+		{"method<HEAD", line, true},
+		// строки без поля проходят только !=
+		{"request_id=abc", line, false},
+		{"request_id!=abc", line, true},
+		{"level>=info", nil, false},
+		{"level!=debug", nil, true},
+	}
+	for _, tt := range tests {
+		cond, err := ParseCondition(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCondition(%q): %v", tt.expr, err)
+		}
+		if got := cond.Match(tt.s); got != tt.want {
+			t.Errorf("%s on %+v = %v, want %v", tt.expr, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	s := Parse(`{"level":"WARNING","msg":"slow request","time":"2024-01-02T15:04:05Z","status":503,` +
+		`"ok":false,"user":null,"http":{"method":"GET","req":{"headers":{"x":{"y":1}}}},"tags":["a","b"]}`)
+	if s == nil {
+		t.Fatal("JSON line is not recognized")
+	}
+	if s.Format != FormatJSON || s.Level != "warn" || s.Message != "slow request" {
+		t.Errorf("got format %q, level %q, message %q", s.Format, s.Level, s.Message)
+	}
+	if s.Time == nil || !s.Time.Equal(mustTime(t, "2024-01-02T15:04:05Z")) {
+		t.Errorf("time = %v", s.Time)
+	}
+	want := map[string]string{
+		"status":             "503",
+		"ok":                 "false",
+		"user":               "null",
+		"http.method":        "GET",
+		"http.req.headers.x": `{"y":1}`,
+		"tags":               `["a","b"]`,
+	}
+	if len(s.Fields) != len(want) {
+		t.Errorf("fields = %v, want %v", s.Fields, want)
+	}
+	for key, value := range want {
+		if s.Fields[key] != value {
+			t.Errorf("field %s = %q, want %q", key, s.Fields[key], value)
+		}
+	}
+}
+
+func TestParseJSONLevelKeys(t *testing.T) {
+	tests := map[string]string{
+		`{"lvl":"err","message":"x"}`:         "error",
+		`{"severity":"CRITICAL"}`:             "fatal",
+		`{"level":50,"msg":"pino"}`:           "error",
+		`{"log.level":"debug"}`:               "debug",
+		`{"@l":"Information","@m":"serilog"}`: "info",
+	}
+	for line, want := range tests {
+		s := Parse(line)
+		if s == nil || s.Format != FormatJSON || s.Level != want {
+			t.Errorf("Parse(%s) = %+v, want JSON with level %s", line, s, want)
+		}
+	}
+}
+
+func TestParseLogfmt(t *testing.T) {
+	s := Parse(`ts=1704207845 level=info msg="request done" path=/api duration=12ms quote="a \"b\""`)
+	if s == nil || s.Format != FormatLogfmt {
+		t.Fatalf("logfmt line is not recognized: %+v", s)
+	}
+	if s.Level != "info" || s.Message != "request done" {
+		t.Errorf("level %q, message %q", s.Level, s.Message)
+	}
+	if s.Time == nil || s.Time.Unix() != 1704207845 {
+		t.Errorf("time = %v", s.Time)
+	}
+	want := map[string]string{"path": "/api", "duration": "12ms", "quote": `a "b"`}
+	if len(s.Fields) != len(want) {
+		t.Errorf("fields = %v, want %v", s.Fields, want)
+	}
+	for key, value := range want {
+		if s.Fields[key] != value {
+			t.Errorf("field %s = %q, want %q", key, s.Fields[key], value)
+		}
+	}
+}
+
+func TestParseTextAndUnrecognized(t *testing.T) {
+	tests := []struct {
+		line   string
+		format string
+		level  string
+	}{
+		{"2024-01-02 15:04:05 ERROR connection refused", FormatText, "error"},
+		{"[WARN] disk almost full", FormatText, "warn"},
+		{"WARNING: deprecated option", FormatText, "warn"},
+		{"main.go:12: CRIT out of memory", FormatText, "fatal"},
+		{"NOTICE server ready", FormatText, "info"},
+		// уровень ищется только в начале строки и только заглавными буквами
+		{"no errors found", "", ""},
+		{strings.Repeat("x", 80) + " ERROR late", "", ""},
+		{"INFORMATION about the server", "", ""},
+		// не logfmt: одна пара, нет уровня и сообщения, текст между парами
+		{"key=value", "", ""},
+		{"a=1 b=2", "", ""},
+		{"level=info done in 5ms", "", ""},
+		{`level=info msg="unterminated`, "", ""},
+		// не JSON: массив и несколько объектов
+		{`["a"]`, "", ""},
+		{`{"level":"info"} {"level":"warn"}`, "", ""},
+		{"plain text", "", ""},
+	}
+	for _, tt := range tests {
+		s := Parse(tt.line)
+		if tt.format == "" {
+			if s != nil {
+				t.Errorf("Parse(%q) = %+v, want nil", tt.line, s)
+			}
+			continue
+		}
+		if s == nil || s.Format != tt.format || s.Level != tt.level {
+			t.Errorf("Parse(%q) = %+v, want format %s, level %s", tt.line, s, tt.format, tt.level)
+		}
+	}
+}
+
+func TestParseRecordTime(t *testing.T) {
+	want := time.Unix(1704207845, 0)
+	for _, value := range []string{"1704207845", "1704207845000", "1704207845000000000", "1704207845.0", "2024-01-02T15:04:05Z"} {
+		got, ok := parseRecordTime(value)
+		if !ok || !got.Equal(want) {
+			t.Errorf("parseRecordTime(%q) = %v, %v; want %v", value, got, ok, want)
+		}
+	}
+	if _, ok := parseRecordTime("yesterday"); ok {
+		t.Error("parseRecordTime(yesterday): expected failure")
+	}
+}
+
+func TestStructuredSelect(t *testing.T) {
+	s := &Structured{Format: FormatJSON, Level: "info", Fields: map[string]string{"a": "1", "b": "2"}}
+	selected := s.Select([]string{"b", "missing"})
+	if selected.Level != "info" || len(selected.Fields) != 1 || selected.Fields["b"] != "2" {
+		t.Errorf("Select = %+v", selected)
+	}
+	if len(s.Fields) != 2 {
+		t.Error("Select must not modify the original")
+	}
+	if s.Select([]string{"missing"}).Fields != nil {
+		t.Error("Select without matching fields must return nil fields")
+	}
+
+	many := &Structured{Fields: make(map[string]string)}
+	for i := 0; i < maxFields+10; i++ {
+		many.Fields[fmt.Sprintf("k%03d", i)] = "v"
+	}
+	fields := many.Select(nil).Fields
+	if len(fields) != maxFields {
+		t.Errorf("Select(nil) returned %d fields, want %d", len(fields), maxFields)
+	}
+	// Остаются первые по имени поля, а не случайные
+	for i := 0; i < maxFields; i++ {
+		if _, ok := fields[fmt.Sprintf("k%03d", i)]; !ok {
+			t.Fatalf("Select(nil) dropped k%03d", i)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package logstream
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Форматы строк лога
+const (
+	FormatJSON   = "json"
+	FormatLogfmt = "logfmt"
+	FormatText   = "text" // обычный текст, у которого удалось определить только уровень
+)
+
+// Уровни логирования после нормализации, по возрастанию важности
+var Levels = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+const (
+	// Максимальное число полей, передаваемых клиенту без явного списка полей.
+	// Из записи с большим числом полей остаются первые maxFields по имени
+	maxFields = 50
+	// Максимальная глубина вложенных JSON объектов, которые разворачиваются в поля a.b.c
+	maxJSONDepth = 3
+)
+
+// Ключи, из которых берутся уровень, сообщение и время (в порядке приоритета)
+var (
+	levelKeys   = []string{"level", "lvl", "severity", "log.level", "levelname", "loglevel", "@l"}
+	messageKeys = []string{"msg", "message", "@m", "@message", "log.message"}
+	timeKeys    = []string{"time", "ts", "timestamp", "@timestamp", "@t", "t"}
+)
+
+// Уровень в начале обычной текстовой строки: "2024-01-02 15:04:05 ERROR ...", "[WARN] ..."
+var textLevelPattern = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|NOTICE|WARN|WARNING|ERROR|ERR|FATAL|PANIC|CRITICAL|CRIT)\b`)
+
+// Structured — разобранная структурированная строка лога
+type Structured struct {
+	Format  string            `json:"format"`
+	Level   string            `json:"level,omitempty"` // нормализованный уровень из Levels
+	Message string            `json:"message,omitempty"`
+	Time    *time.Time        `json:"time,omitempty"` // время из самой записи
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+// Parse распознает строку в формате JSON или logfmt. Для обычного текста
+// возвращает только уровень, если он есть в начале строки; иначе nil
+func Parse(text string) *Structured {
+	trimmed := strings.TrimSpace(text)
+	if strings.HasPrefix(trimmed, "{") {
+		if s := parseJSON(trimmed); s != nil {
+			return s
+		}
+	}
+	if s := parseLogfmt(trimmed); s != nil {
+		return s
+	}
+	head := trimmed
+	if len(head) > 64 {
+		head = head[:64]
+	}
+	if m := textLevelPattern.FindString(head); m != "" {
+		return &Structured{Format: FormatText, Level: NormalizeLevel(m)}
+	}
+	return nil
+}
+
+// NormalizeLevel приводит название или числовой уровень (pino, bunyan) к одному из Levels.
+// Неизвестный уровень возвращается в нижнем регистре как есть
+func NormalizeLevel(level string) string {
+	value := strings.ToLower(strings.TrimSpace(level))
+	switch value {
+	case "trace", "trc", "verbose", "vrb":
+		return "trace"
+	case "debug", "dbg", "dbug":
+		return "debug"
+	case "info", "inf", "information", "informational", "notice":
+		return "info"
+	case "warn", "wrn", "warning":
+		return "warn"
+	case "error", "err", "eror":
+		return "error"
+	case "fatal", "ftl", "panic", "dpanic", "critical", "crit", "alert", "emerg", "emergency":
+		return "fatal"
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		switch {
+		case n >= 60:
+			return "fatal"
+		case n >= 50:
+			return "error"
+		case n >= 40:
+			return "warn"
+		case n >= 30:
+			return "info"
+		case n >= 20:
+			return "debug"
+		default:
+			return "trace"
+		}
+	}
+	return value
+}
+
+// LevelRank возвращает порядковый номер уровня в Levels или -1
+func LevelRank(level string) int {
+	for i, l := range Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseJSON разбирает JSON объект; вложенные объекты разворачиваются в поля с ключами через точку
+func parseJSON(text string) *Structured {
+	decoder := json.NewDecoder(strings.NewReader(text))
+	decoder.UseNumber()
+	var object map[string]interface{}
+	if err := decoder.Decode(&object); err != nil || decoder.More() {
+		return nil
+	}
+	fields := make(map[string]string, len(object))
+	flattenJSON("", object, fields, 0)
+	return newStructured(FormatJSON, fields)
+}
+
+func flattenJSON(prefix string, object map[string]interface{}, fields map[string]string, depth int) {
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if depth < maxJSONDepth {
+				flattenJSON(key, v, fields, depth+1)
+				continue
+			}
+			fields[key] = compactJSON(v)
+		case string:
+			fields[key] = v
+		case json.Number:
+			fields[key] = v.String()
+		case bool:
+			fields[key] = strconv.FormatBool(v)
+		case nil:
+			fields[key] = "null"
+		default:
+			fields[key] = compactJSON(v)
+		}
+	}
+}
+
+func compactJSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// parseLogfmt разбирает строку вида level=info msg="request done" duration=12ms.
+// Строка считается logfmt, если она целиком состоит из пар key=value (не меньше двух)
+// и среди ключей есть уровень или сообщение
+func parseLogfmt(text string) *Structured {
+	fields := make(map[string]string)
+	rest := text
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return nil
+		}
+		key := rest[:eq]
+		if strings.ContainsAny(key, " \t\"") {
+			return nil
+		}
+		rest = rest[eq+1:]
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := closingQuote(rest)
+			if end < 0 {
+				return nil
+			}
+			unquoted, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return nil
+			}
+			value, rest = unquoted, rest[end+1:]
+		} else if sp := strings.IndexAny(rest, " \t"); sp >= 0 {
+			value, rest = rest[:sp], rest[sp:]
+		} else {
+			value, rest = rest, ""
+		}
+		fields[key] = value
+	}
+	if len(fields) < 2 || (findKey(fields, levelKeys) == "" && findKey(fields, messageKeys) == "") {
+		return nil
+	}
+	return newStructured(FormatLogfmt, fields)
+}
+
+// closingQuote возвращает индекс закрывающей кавычки строки, начинающейся с кавычки
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func findKey(fields map[string]string, keys []string) string {
+	for _, key := range keys {
+		if _, ok := fields[key]; ok {
+			return key
+		}
+	}
+	return ""
+}
+
+// newStructured выделяет уровень, сообщение и время из полей записи
+func newStructured(format string, fields map[string]string) *Structured {
+	s := &Structured{Format: format}
+	if key := findKey(fields, levelKeys); key != "" {
+		s.Level = NormalizeLevel(fields[key])
+		delete(fields, key)
+	}
+	if key := findKey(fields, messageKeys); key != "" {
+		s.Message = fields[key]
+		delete(fields, key)
+	}
+	if key := findKey(fields, timeKeys); key != "" {
+		if t, ok := parseRecordTime(fields[key]); ok {
+			s.Time = &t
+			delete(fields, key)
+		}
+	}
+	if len(fields) > 0 {
+		s.Fields = fields
+	}
+	return s
+}
+
+// parseRecordTime разбирает время записи: RFC3339, "2006-01-02 15:04:05"
+// или unix время в секундах, миллисекундах или наносекундах
+func parseRecordTime(value string) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, true
+		}
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil && n > 0 {
+		switch {
+		case n < 1e11:
+			return time.Unix(n, 0), true
+		case n < 1e14:
+			return time.UnixMilli(n), true
+		default:
+			return time.Unix(0, n), true
+		}
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n <= 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case n < 1e11:
+		return time.Unix(0, int64(n*float64(time.Second))), true
+	case n < 1e14:
+		return time.Unix(0, int64(n*float64(time.Millisecond))), true
+	default:
+		return time.Unix(0, int64(n)), true
+	}
+}
+
+// Select оставляет только перечисленные поля (пустой список — все поля, но не больше maxFields).
+// Лишние поля отбрасываются по порядку имен, чтобы у одинаковых записей набор полей совпадал
+func (s *Structured) Select(keys []string) *Structured {
+	if s == nil {
+		return nil
+	}
+	result := *s
+	if len(keys) > 0 {
+		result.Fields = nil
+		for _, key := range keys {
+			if value, ok := s.Fields[key]; ok {
+				if result.Fields == nil {
+					result.Fields = make(map[string]string, len(keys))
+				}
+				result.Fields[key] = value
+			}
+		}
+		return &result
+	}
+	if len(s.Fields) > maxFields {
+		names := make([]string, 0, len(s.Fields))
+		for key := range s.Fields {
+			names = append(names, key)
+		}
+		sort.Strings(names)
+		result.Fields = make(map[string]string, maxFields)
+		for _, key := range names[:maxFields] {
+			result.Fields[key] = s.Fields[key]
+		}
+	}
+	return &result
+}
+
+// Value возвращает значение поля для условий фильтра: level, msg (message) или поле записи
+func (s *Structured) Value(key string) (string, bool) {
+	switch key {
+	case "level":
+		return s.Level, s.Level != ""
+	case "msg", "message":
+		return s.Message, s.Message != ""
+	}
+	value, ok := s.Fields[key]
+	return value, ok
+}
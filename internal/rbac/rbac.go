@@ -0,0 +1,229 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+
+	"docker-dashboard/internal/auth"
+	"docker-dashboard/internal/containers"
+)
+
+// Role — роль пользователя
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"
+	RoleOperator Role = "operator"
+	RoleAdmin    Role = "admin"
+)
+
+// Action — действие над контейнером
+type Action string
+
+const (
+	ActionView    Action = "view"
+	ActionLogs    Action = "logs"
+	ActionRestart Action = "restart"
+	ActionStart   Action = "start"
+	ActionStop    Action = "stop"
+	ActionKill    Action = "kill"
+	ActionPause   Action = "pause"
+	ActionUnpause Action = "unpause"
+	ActionRemove  Action = "remove"
+	ActionExec    Action = "exec"
+
+	// ActionAudit — чтение журнала аудита. Проверяется не для контейнера,
+	// а глобально (см. CanAccess)
+	ActionAudit Action = "audit"
+	// ActionAlerts — тестовая отправка уведомлений алертов, тоже глобально
+	ActionAlerts Action = "alerts"
+)
+
+// Actions — все действия, для которых вычисляются права (кроме просмотра)
+var Actions = []Action{
+	ActionLogs, ActionRestart, ActionStart, ActionStop,
+	ActionKill, ActionPause, ActionUnpause, ActionRemove, ActionExec,
+}
+
+// rolePermissions — действия, разрешенные каждой роли.
+// Необратимые действия (kill, remove) и терминал (exec) доступны только администраторам
+var rolePermissions = map[Role][]Action{
+	RoleViewer: {ActionView},
+	RoleOperator: {
+		ActionView, ActionLogs, ActionRestart, ActionStart, ActionStop,
+		ActionPause, ActionUnpause,
+	},
+	RoleAdmin: {
+		ActionView, ActionLogs, ActionRestart, ActionStart, ActionStop,
+		ActionPause, ActionUnpause, ActionKill, ActionRemove, ActionExec, ActionAudit, ActionAlerts,
+	},
+}
+
+// featureFlags — переменные окружения, которыми действие включается глобально.
+// Действия без флага всегда включены
+var featureFlags = map[Action]string{
+	ActionLogs:    "LOGS_SHOW",
+	ActionRestart: "CONTAINER_RESTART",
+	ActionStart:   "CONTAINER_START",
+	ActionStop:    "CONTAINER_STOP",
+	ActionKill:    "CONTAINER_KILL",
+	ActionPause:   "CONTAINER_PAUSE",
+	ActionUnpause: "CONTAINER_PAUSE",
+	ActionRemove:  "CONTAINER_REMOVE",
+	ActionExec:    "CONTAINER_EXEC",
+}
+
+// FeatureEnabled сообщает, включено ли действие глобально через переменную окружения
+func FeatureEnabled(action Action) bool {
+	name, ok := featureFlags[action]
+	if !ok {
+		return true
+	}
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return false
+	}
+	return value
+}
+
+// Binding назначает роль пользователям и группам, опционально только
+// для контейнеров определенных compose проектов или с определенными метками
+type Binding struct {
+	Users    []string          `json:"users,omitempty"`  // "*" — все пользователи
+	Groups   []string          `json:"groups,omitempty"` // группы из OIDC
+	Role     Role              `json:"role"`
+	Projects []string          `json:"projects,omitempty"` // com.docker.compose.project
+	Selector map[string]string `json:"selector,omitempty"` // все метки должны совпасть
+}
+
+// Policy — политика доступа.
+// Роль default_role действует для всех пользователей и всех контейнеров,
+// права из подходящих bindings добавляются к ней
+type Policy struct {
+	DefaultRole Role      `json:"default_role,omitempty"`
+	Bindings    []Binding `json:"bindings"`
+}
+
+var (
+	defaultPolicy     *Policy
+	defaultPolicyOnce sync.Once
+)
+
+// permissivePolicy используется без RBAC_POLICY_FILE: все пользователи — администраторы,
+// доступ к действиям определяется только флагами из featureFlags
+var permissivePolicy = &Policy{DefaultRole: RoleAdmin}
+
+// Default возвращает политику из RBAC_POLICY_FILE (JSON)
+func Default() *Policy {
+	defaultPolicyOnce.Do(func() {
+		path := os.Getenv("RBAC_POLICY_FILE")
+		if path == "" {
+			defaultPolicy = permissivePolicy
+			return
+		}
+		policy, err := LoadPolicy(path)
+		if err != nil {
+			// Ошибка в политике не должна открывать доступ: оставляем только просмотр
+			log.Printf("[docker-dashboard] RBAC: %v; falling back to read-only access", err)
+			defaultPolicy = &Policy{DefaultRole: RoleViewer}
+			return
+		}
+		log.Printf("[docker-dashboard] RBAC: loaded %d bindings from %s", len(policy.Bindings), path)
+		defaultPolicy = policy
+	})
+	return defaultPolicy
+}
+
+// LoadPolicy читает и проверяет политику из JSON файла
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy: %w", err)
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse policy: %w", err)
+	}
+	if policy.DefaultRole != "" {
+		if _, ok := rolePermissions[policy.DefaultRole]; !ok {
+			return nil, fmt.Errorf("unknown default_role %q", policy.DefaultRole)
+		}
+	}
+	for i, b := range policy.Bindings {
+		if _, ok := rolePermissions[b.Role]; !ok {
+			return nil, fmt.Errorf("binding %d: unknown role %q", i, b.Role)
+		}
+		if len(b.Users) == 0 && len(b.Groups) == 0 {
+			return nil, fmt.Errorf("binding %d: users or groups required", i)
+		}
+	}
+	return &policy, nil
+}
+
+func (b Binding) matchesUser(user *auth.User) bool {
+	if user == nil {
+		return false
+	}
+	for _, u := range b.Users {
+		if u == "*" || u == user.Name {
+			return true
+		}
+	}
+	for _, g := range b.Groups {
+		if slices.Contains(user.Groups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b Binding) matchesContainer(c *containers.Container) bool {
+	if len(b.Projects) > 0 && !slices.Contains(b.Projects, c.ComposeProject) {
+		return false
+	}
+	for key, value := range b.Selector {
+		if c.AllLabels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Allowed проверяет право пользователя на действие с контейнером по политике
+// (без учета глобальных флагов)
+func (p *Policy) Allowed(user *auth.User, c *containers.Container, action Action) bool {
+	if slices.Contains(rolePermissions[p.DefaultRole], action) {
+		return true
+	}
+	for _, b := range p.Bindings {
+		if slices.Contains(rolePermissions[b.Role], action) && b.matchesUser(user) && b.matchesContainer(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Can проверяет, что действие включено глобально и разрешено пользователю политикой
+func Can(user *auth.User, c *containers.Container, action Action) bool {
+	return FeatureEnabled(action) && Default().Allowed(user, c, action)
+}
+
+// Permissions возвращает права пользователя на действия с контейнером
+func Permissions(user *auth.User, c *containers.Container) map[Action]bool {
+	result := make(map[Action]bool, len(Actions))
+	for _, action := range Actions {
+		result[action] = Can(user, c, action)
+	}
+	return result
+}
+
+// CanAccess проверяет право на действие, не относящееся к конкретному контейнеру.
+// Учитываются только default_role и bindings без projects и selector
+func CanAccess(user *auth.User, action Action) bool {
+	return Can(user, &containers.Container{}, action)
+}
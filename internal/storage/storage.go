@@ -0,0 +1,475 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"docker-dashboard/internal/containers"
+)
+
+// Хранилище на диске состоит из append-only сегментов в формате JSON lines:
+//
+//	DATA_DIR/metrics/metrics-YYYYMMDDHH.jsonl          — метрики за час
+//	DATA_DIR/metrics/metrics-YYYYMMDDHH.compact.jsonl  — те же метрики после прореживания
+//	DATA_DIR/events/events-YYYYMMDD.jsonl              — события контейнеров за сутки
+//
+// Сегменты старше срока хранения удаляются целиком
+
+const (
+	metricsDir           = "metrics"
+	eventsDir            = "events"
+	metricsSegmentLayout = "2006010215"
+	eventsSegmentLayout  = "20060102"
+	compactSuffix        = ".compact.jsonl"
+	segmentSuffix        = ".jsonl"
+
+	maintenanceInterval = 10 * time.Minute
+)
+
+// Config — параметры хранилища
+type Config struct {
+	Dir       string
+	Retention time.Duration // сколько хранить данные
+	// Сегменты метрик старше CompactAfter прореживаются до шага CompactResolution
+	CompactAfter      time.Duration
+	CompactResolution time.Duration
+}
+
+// MetricsRecord — значения метрик одной области (хост или контейнер) в момент времени
+type MetricsRecord struct {
+	T      int64              `json:"t"`
+	Scope  string             `json:"s,omitempty"`
+	Values map[string]float64 `json:"m"`
+}
+
+// Store — хранилище метрик и событий на диске
+type Store struct {
+	cfg Config
+
+	mu             sync.Mutex
+	metricsFile    *os.File
+	metricsSegment string
+	eventsFile     *os.File
+	eventsSegment  string
+}
+
+var (
+	defaultStore     *Store
+	defaultStoreOnce sync.Once
+)
+
+// Default возвращает хранилище, настроенное через DATA_DIR, или nil, если DATA_DIR не задан
+func Default() *Store {
+	defaultStoreOnce.Do(func() {
+		dir := os.Getenv("DATA_DIR")
+		if dir == "" {
+			return
+		}
+		cfg := Config{
+			Dir:               dir,
+			Retention:         envDuration("DATA_RETENTION", 7*24*time.Hour),
+			CompactAfter:      envDuration("HISTORY_RETENTION", 24*time.Hour),
+			CompactResolution: envDuration("HISTORY_DOWNSAMPLE_RESOLUTION", 5*time.Minute),
+		}
+		store, err := Open(cfg)
+		if err != nil {
+			log.Printf("[docker-dashboard] Failed to open data dir %s: %v", dir, err)
+			return
+		}
+		log.Printf("[docker-dashboard] Persistent storage: %s (retention %s)", dir, cfg.Retention)
+		defaultStore = store
+	})
+	return defaultStore
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("[docker-dashboard] Invalid %s=%q, using %s", name, value, def)
+		return def
+	}
+	return d
+}
+
+// Open создает каталоги хранилища и запускает фоновое обслуживание (удаление и прореживание)
+func Open(cfg Config) (*Store, error) {
+	for _, dir := range []string{metricsDir, eventsDir} {
+		if err := os.MkdirAll(filepath.Join(cfg.Dir, dir), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	s := &Store{cfg: cfg}
+	go s.maintain()
+	return s, nil
+}
+
+// appendLine дописывает JSON строку в сегмент, при смене сегмента открывает новый файл
+func (s *Store) appendLine(file **os.File, current *string, dir, segment string, v interface{}) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if *file == nil || *current != segment {
+		if *file != nil {
+			(*file).Close()
+		}
+		f, err := os.OpenFile(filepath.Join(s.cfg.Dir, dir, segment), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			*file = nil
+			return err
+		}
+		*file, *current = f, segment
+	}
+	_, err = (*file).Write(append(line, '\n'))
+	return err
+}
+
+// AppendMetrics записывает значения метрик
+func (s *Store) AppendMetrics(records []MetricsRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rec := range records {
+		segment := "metrics-" + time.Unix(rec.T, 0).UTC().Format(metricsSegmentLayout) + segmentSuffix
+		if err := s.appendLine(&s.metricsFile, &s.metricsSegment, metricsDir, segment, rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendEvent записывает событие контейнера
+func (s *Store) AppendEvent(ev containers.ContainerEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	segment := "events-" + ev.Time.UTC().Format(eventsSegmentLayout) + segmentSuffix
+	return s.appendLine(&s.eventsFile, &s.eventsSegment, eventsDir, segment, ev)
+}
+
+// RecordContainerEvents подписывает хранилище на события жизненного цикла контейнеров
+func (s *Store) RecordContainerEvents() {
+	containers.AddEventListener(func(ev containers.ContainerEvent) {
+		if err := s.AppendEvent(ev); err != nil {
+			log.Printf("[docker-dashboard] storage: failed to write event: %v", err)
+		}
+	})
+}
+
+// segmentFile — файл сегмента и начало периода, который он покрывает
+type segmentFile struct {
+	path      string
+	start     time.Time
+	compacted bool
+}
+
+// listSegments возвращает сегменты каталога, отсортированные по времени.
+// Исходный сегмент, у которого уже есть прореженная версия, пропускается: он остается,
+// если процесс упал между записью прореженного файла и удалением исходного, а его данные
+// уже учтены в прореженном
+func (s *Store) listSegments(dir, prefix, layout string) ([]segmentFile, error) {
+	entries, err := os.ReadDir(filepath.Join(s.cfg.Dir, dir))
+	if err != nil {
+		return nil, err
+	}
+	var segments []segmentFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		compacted := strings.HasSuffix(name, compactSuffix)
+		stamp := strings.TrimPrefix(name, prefix)
+		stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, compactSuffix), segmentSuffix)
+		start, err := time.ParseInLocation(layout, stamp, time.UTC)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segmentFile{
+			path:      filepath.Join(s.cfg.Dir, dir, name),
+			start:     start,
+			compacted: compacted,
+		})
+	}
+	compactedStarts := make(map[time.Time]bool)
+	for _, seg := range segments {
+		if seg.compacted {
+			compactedStarts[seg.start] = true
+		}
+	}
+	current := segments[:0]
+	for _, seg := range segments {
+		if seg.compacted || !compactedStarts[seg.start] {
+			current = append(current, seg)
+		}
+	}
+	segments = current
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].start.Before(segments[j].start)
+	})
+	return segments, nil
+}
+
+// readLines вызывает fn для каждой строки сегмента; поврежденные строки пропускаются
+func readLines(path string, fn func(line []byte) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if !fn(scanner.Bytes()) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// ReplayMetrics читает все записи метрик начиная с from в хронологическом порядке
+func (s *Store) ReplayMetrics(from time.Time, fn func(MetricsRecord)) error {
+	segments, err := s.listSegments(metricsDir, "metrics-", metricsSegmentLayout)
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		if seg.start.Add(time.Hour).Before(from) {
+			continue
+		}
+		err := readLines(seg.path, func(line []byte) bool {
+			var rec MetricsRecord
+			if json.Unmarshal(line, &rec) == nil && rec.T >= from.Unix() {
+				fn(rec)
+			}
+			return true
+		})
+		if err != nil {
+			log.Printf("[docker-dashboard] storage: failed to read %s: %v", seg.path, err)
+		}
+	}
+	return nil
+}
+
+// EventFilter — условия выборки событий
+type EventFilter struct {
+	From      time.Time
+	To        time.Time
+	Container string // имя или короткий ID
+	Project   string
+	Action    string // префикс действия, например "die" или "health_status"
+	// Visible — дополнительная проверка события (например, прав пользователя).
+	// Применяется до Limit, чтобы недоступные события не занимали место в выборке
+	Visible func(containers.ContainerEvent) bool
+	Limit   int
+}
+
+func (f EventFilter) match(ev containers.ContainerEvent) bool {
+	if ev.Time.Before(f.From) || (!f.To.IsZero() && ev.Time.After(f.To)) {
+		return false
+	}
+	if f.Container != "" && ev.Name != f.Container && ev.ID != f.Container {
+		return false
+	}
+	if f.Project != "" && ev.ComposeProject != f.Project {
+		return false
+	}
+	if f.Action != "" && !strings.HasPrefix(ev.Action, f.Action) {
+		return false
+	}
+	return f.Visible == nil || f.Visible(ev)
+}
+
+// QueryEvents возвращает события, подходящие под фильтр, в хронологическом порядке.
+// При превышении Limit возвращаются самые свежие события
+func (s *Store) QueryEvents(filter EventFilter) ([]containers.ContainerEvent, error) {
+	segments, err := s.listSegments(eventsDir, "events-", eventsSegmentLayout)
+	if err != nil {
+		return nil, err
+	}
+	var result []containers.ContainerEvent
+	for _, seg := range segments {
+		if seg.start.Add(24*time.Hour).Before(filter.From) || (!filter.To.IsZero() && seg.start.After(filter.To)) {
+			continue
+		}
+		err := readLines(seg.path, func(line []byte) bool {
+			var ev containers.ContainerEvent
+			if json.Unmarshal(line, &ev) == nil && filter.match(ev) {
+				result = append(result, ev)
+			}
+			return true
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", seg.path, err)
+		}
+	}
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[len(result)-filter.Limit:]
+	}
+	return result, nil
+}
+
+func (s *Store) maintain() {
+	for {
+		s.runMaintenance(time.Now())
+		time.Sleep(maintenanceInterval)
+	}
+}
+
+// runMaintenance удаляет устаревшие сегменты и прореживает старые сегменты метрик
+func (s *Store) runMaintenance(now time.Time) {
+	cutoff := now.Add(-s.cfg.Retention)
+
+	events, err := s.listSegments(eventsDir, "events-", eventsSegmentLayout)
+	if err == nil {
+		for _, seg := range events {
+			if seg.start.Add(24 * time.Hour).Before(cutoff) {
+				s.removeSegment(seg.path)
+			}
+		}
+	}
+
+	metrics, err := s.listSegments(metricsDir, "metrics-", metricsSegmentLayout)
+	if err != nil {
+		return
+	}
+	compactBefore := now.Add(-s.cfg.CompactAfter)
+	for _, seg := range metrics {
+		end := seg.start.Add(time.Hour)
+		switch {
+		case end.Before(cutoff):
+			s.removeSegment(seg.path)
+		case seg.compacted:
+			s.removeCompactedSource(seg)
+		case !seg.compacted && end.Before(compactBefore) && s.cfg.CompactResolution > 0:
+			if err := s.compact(seg); err != nil {
+				log.Printf("[docker-dashboard] storage: failed to compact %s: %v", seg.path, err)
+			}
+		}
+	}
+}
+
+func (s *Store) removeSegment(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(path); err != nil {
+		log.Printf("[docker-dashboard] storage: failed to remove %s: %v", path, err)
+	}
+}
+
+// removeCompactedSource удаляет исходный сегмент, оставшийся после прерванного прореживания
+func (s *Store) removeCompactedSource(seg segmentFile) {
+	source := strings.TrimSuffix(seg.path, compactSuffix) + segmentSuffix
+	if _, err := os.Stat(source); err == nil {
+		log.Printf("[docker-dashboard] storage: removing %s, already compacted", source)
+		s.removeSegment(source)
+	}
+}
+
+// compact заменяет сегмент метрик его прореженной версией (средние по CompactResolution)
+func (s *Store) compact(seg segmentFile) error {
+	step := int64(s.cfg.CompactResolution / time.Second)
+	if step < 1 {
+		step = 1
+	}
+
+	type bucketKey struct {
+		t      int64
+		scope  string
+		metric string
+	}
+	type bucket struct {
+		sum float64
+		n   int
+	}
+	buckets := make(map[bucketKey]*bucket)
+	err := readLines(seg.path, func(line []byte) bool {
+		var rec MetricsRecord
+		if json.Unmarshal(line, &rec) != nil {
+			return true
+		}
+		for metric, v := range rec.Values {
+			key := bucketKey{t: rec.T / step * step, scope: rec.Scope, metric: metric}
+			b, ok := buckets[key]
+			if !ok {
+				b = &bucket{}
+				buckets[key] = b
+			}
+			b.sum += v
+			b.n++
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	// Собираем записи обратно по (время, область)
+	type recordKey struct {
+		t     int64
+		scope string
+	}
+	records := make(map[recordKey]map[string]float64)
+	for key, b := range buckets {
+		rk := recordKey{t: key.t, scope: key.scope}
+		if records[rk] == nil {
+			records[rk] = make(map[string]float64)
+		}
+		records[rk][key.metric] = b.sum / float64(b.n)
+	}
+	keys := make([]recordKey, 0, len(records))
+	for rk := range records {
+		keys = append(keys, rk)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].t != keys[j].t {
+			return keys[i].t < keys[j].t
+		}
+		return keys[i].scope < keys[j].scope
+	})
+
+	target := strings.TrimSuffix(seg.path, segmentSuffix) + compactSuffix
+	tmp := target + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, rk := range keys {
+		line, err := json.Marshal(MetricsRecord{T: rk.t, Scope: rk.scope, Values: records[rk]})
+		if err != nil {
+			continue
+		}
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(seg.path)
+}
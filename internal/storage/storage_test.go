@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"docker-dashboard/internal/containers"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	cfg := Config{
+		Dir:               t.TempDir(),
+		Retention:         7 * 24 * time.Hour,
+		CompactAfter:      24 * time.Hour,
+		CompactResolution: 5 * time.Minute,
+	}
+	for _, dir := range []string{metricsDir, eventsDir} {
+		if err := os.MkdirAll(filepath.Join(cfg.Dir, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Без фонового обслуживания из Open: тест сам вызывает runMaintenance
+	return &Store{cfg: cfg}
+}
+
+func TestQueryEventsLimitAppliesToVisibleEvents(t *testing.T) {
+	s := newTestStore(t)
+	start := time.Date(2024, 1, 2, 15, 0, 0, 0, time.UTC)
+	for i := 0; i < 6; i++ {
+		id := "aaaaaaaaaaaa"
+		if i%2 == 1 {
+			id = "bbbbbbbbbbbb"
+		}
+		ev := containers.ContainerEvent{Time: start.Add(time.Duration(i) * time.Minute), ID: id, Action: "start"}
+		if err := s.AppendEvent(ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := s.QueryEvents(EventFilter{
+		From:    start.Add(-time.Hour),
+		Visible: func(ev containers.ContainerEvent) bool { return ev.ID == "aaaaaaaaaaaa" },
+		Limit:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Самые свежие события b не занимают место в выборке
+	if len(events) != 2 || !events[0].Time.Equal(start.Add(2*time.Minute)) || !events[1].Time.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("events = %+v, want the two latest events of a", events)
+	}
+}
+
+func replayed(t *testing.T, s *Store, from time.Time) []MetricsRecord {
+	t.Helper()
+	var records []MetricsRecord
+	if err := s.ReplayMetrics(from, func(rec MetricsRecord) { records = append(records, rec) }); err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestCompactInterruptedBeforeSourceRemoved(t *testing.T) {
+	s := newTestStore(t)
+	now := time.Now().UTC().Truncate(time.Hour)
+	hour := now.Add(-48 * time.Hour)
+	var records []MetricsRecord
+	for i := 0; i < 20; i++ {
+		records = append(records, MetricsRecord{T: hour.Add(time.Duration(i) * time.Minute).Unix(), Values: map[string]float64{"cpu": float64(i)}})
+	}
+	if err := s.AppendMetrics(records); err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(s.cfg.Dir, metricsDir, "metrics-"+hour.Format(metricsSegmentLayout)+segmentSuffix)
+	raw, err := os.ReadFile(source)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.runMaintenance(now)
+	compacted := replayed(t, s, hour)
+	if len(compacted) != 4 || compacted[0].Values["cpu"] != 2 || compacted[3].Values["cpu"] != 17 {
+		t.Fatalf("compacted = %+v, want 4 averages over 5 minutes", compacted)
+	}
+
+	// Сбой между записью прореженного файла и удалением исходного
+	if err := os.WriteFile(source, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := replayed(t, s, hour); len(got) != len(compacted) {
+		t.Fatalf("replayed %d records after interrupted compaction, want %d", len(got), len(compacted))
+	}
+
+	s.runMaintenance(now)
+	if _, err := os.Stat(source); !os.IsNotExist(err) {
+		t.Errorf("source segment was not removed: %v", err)
+	}
+	if got := replayed(t, s, hour); len(got) != len(compacted) {
+		t.Errorf("replayed %d records after maintenance, want %d", len(got), len(compacted))
+	}
+}
@@ -0,0 +1,56 @@
+// Действия над контейнерами через REST API (POST /api/containers/:id/:action).
+// Для cookie сессии (вход через OIDC) сервер требует CSRF токен из /auth/me
+let csrfTokenPromise = null;
+
+function getCsrfToken() {
+	if (!csrfTokenPromise) {
+		csrfTokenPromise = fetch("auth/me", { credentials: "same-origin" })
+			.then((response) => (response.ok ? response.json() : {}))
+			.then((data) => data.csrf_token || "")
+			.catch(() => "");
+	}
+	return csrfTokenPromise;
+}
+
+// Выполняет действие и возвращает ответ сервера {status, message, error, job_id}
+export function runContainerAction(containerId, action, options = {}) {
+	return postAction(`api/containers/${containerId}/${action}`, options);
+}
+
+// Выполняет действие над compose проектом; ответ содержит шаги по каждому контейнеру
+export function runProjectAction(project, action, options = {}) {
+	return postAction(
+		`api/projects/${encodeURIComponent(project)}/${action}`,
+		options,
+	);
+}
+
+async function postAction(url, options) {
+	const csrfToken = await getCsrfToken();
+	const headers = {
+		"Content-Type": "application/json",
+		// Повторная отправка того же запроса (например, после обрыва сети) не выполнит действие дважды
+		"Idempotency-Key": crypto.randomUUID
+			? crypto.randomUUID()
+			: `${Date.now()}-${Math.random().toString(16).slice(2)}`,
+	};
+	if (csrfToken) {
+		headers["X-CSRF-Token"] = csrfToken;
+	}
+
+	const response = await fetch(url, {
+		method: "POST",
+		credentials: "same-origin",
+		headers,
+		body: JSON.stringify(options),
+	});
+	const data = await response.json().catch(() => ({}));
+	if (response.status === 403 && data.message?.includes("CSRF")) {
+		// Сессия обновилась — запрашиваем токен заново при следующей попытке
+		csrfTokenPromise = null;
+	}
+	if (!response.ok && !data.status) {
+		return { status: "error", message: data.message || `HTTP ${response.status}` };
+	}
+	return data;
+}
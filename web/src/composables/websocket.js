@@ -191,39 +191,6 @@ export function createWebSocketStore() {
 		return firstMessagePromise;
 	}
 
-	function connectRestartWebSocket(containerId, callbacks) {
-		const wsUrl = getWebSocketUrl(`ws/containers/${containerId}/restart`);
-
-		const wsRestart = new WebSocket(wsUrl);
-
-		wsRestart.onopen = () => {
-			console.log("Restart WebSocket connected");
-			if (callbacks.onOpen) callbacks.onOpen();
-		};
-
-		wsRestart.onmessage = (event) => {
-			try {
-				const data = JSON.parse(event.data);
-				if (callbacks.onMessage) callbacks.onMessage(data);
-			} catch (error) {
-				console.error("Error parsing restart response:", error);
-				if (callbacks.onError) callbacks.onError(error);
-			}
-			wsRestart.close();
-		};
-
-		wsRestart.onerror = (error) => {
-			console.error("WebSocket error for restart:", error);
-			if (callbacks.onError) callbacks.onError(error);
-			wsRestart.close();
-		};
-
-		wsRestart.onclose = () => {
-			console.log("Restart WebSocket disconnected");
-			if (callbacks.onClose) callbacks.onClose();
-		};
-	}
-
 	function disconnectAll() {
 		if (ws) {
 			ws.close();
@@ -243,7 +210,6 @@ export function createWebSocketStore() {
 		connectContainersWebSocket,
 		connectHostInfoWebSocket,
 		connectStatsWebSocket,
-		connectRestartWebSocket,
 		disconnectAll,
 	};
 }
@@ -0,0 +1,34 @@
+// Действия жизненного цикла контейнера (кроме restart, у которого своя кнопка).
+// Кнопка показывается, если действие разрешено сервером (permissions) и имеет смысл в текущем состоянии
+const lifecycleActions = [
+	{ action: "start", label: "start", when: (c) => c.State !== "running" && c.State !== "paused" },
+	{ action: "stop", label: "stop", when: (c) => c.State === "running" },
+	{ action: "pause", label: "pause", when: (c) => c.State === "running" },
+	{ action: "unpause", label: "unpause", when: (c) => c.State === "paused" },
+	{ action: "kill", label: "kill", danger: true, when: (c) => c.State === "running" },
+	{ action: "remove", label: "remove", danger: true, when: (c) => c.State !== "running" },
+];
+
+export function availableActions(container) {
+	const permissions = container.permissions || {};
+	return lifecycleActions.filter(
+		(item) => permissions[item.action] && item.when(container),
+	);
+}
+
+// Действия над всем compose проектом: кнопка показывается, если действие
+// разрешено для всех контейнеров группы (сервер проверяет то же самое)
+const projectActionList = ["start", "stop", "restart"];
+
+export function availableProjectActions(group) {
+	if (!group.project_name || !group.containers?.length) return [];
+	return projectActionList.filter((action) =>
+		group.containers.every((c) => c.permissions?.[action]),
+	);
+}
+
+// Общий поток логов проекта доступен, если можно читать логи хотя бы одного контейнера
+export function canReadProjectLogs(group) {
+	if (!group.project_name || !group.containers?.length) return false;
+	return group.containers.some((c) => c.permissions?.logs !== false);
+}